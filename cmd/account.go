@@ -0,0 +1,332 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+
+*/
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+	"github.com/patrikcze/go-anf/pkg/uri"
+	"github.com/patrikcze/go-anf/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// accountCmd represents the account command
+var accountCmd = &cobra.Command{
+	Use:   "account",
+	Short: "Manage Azure NetApp Files accounts",
+	Long: `Create, show, list, update and delete Azure NetApp Files accounts.
+
+An account is the top level container for capacity pools, volumes and
+snapshot policies. It also holds the Active Directory connection used
+by SMB and dual-protocol volumes.`,
+}
+
+// accountCreateCmd represents the account create command
+var accountCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create an account",
+	Example: `  go-anf account create myaccount -g myrg -l westeurope --tags env=dev
+  go-anf account create myaccount -g myrg -l westeurope --ad-domain contoso.com --ad-dns 10.0.0.4 --ad-username admin --ad-smb-server-name anfsmb`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, err := requiredString(cmd, "resource-group")
+		if err != nil {
+			return err
+		}
+		location, err := requiredString(cmd, "location")
+		if err != nil {
+			return err
+		}
+		activeDirectories, err := activeDirectoriesFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Creating account %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginCreateANFAccount(cmd.Context(), location, resourceGroupName, args[0], activeDirectories, tagsFromFlag(cmd)))
+		}
+		account, err := client.CreateANFAccount(cmd.Context(), location, resourceGroupName, args[0], activeDirectories, tagsFromFlag(cmd))
+		if err != nil {
+			return err
+		}
+
+		return printAccount(account)
+	},
+}
+
+// accountShowCmd represents the account show command
+var accountShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show an account",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, err := requiredString(cmd, "resource-group")
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		account, err := client.GetANFAccount(cmd.Context(), resourceGroupName, args[0])
+		if err != nil {
+			return err
+		}
+
+		return printAccount(account)
+	},
+}
+
+// accountListCmd represents the account list command
+var accountListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List accounts in a resource group or in the whole subscription",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, _ := cmd.Flags().GetString("resource-group")
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		var accounts []*armnetapp.Account
+		if resourceGroupName == "" {
+			accounts, err = client.ListANFAccountsBySubscription(cmd.Context())
+		} else {
+			accounts, err = client.ListANFAccounts(cmd.Context(), resourceGroupName)
+		}
+		if err != nil {
+			return err
+		}
+
+		if !humanOutput() {
+			return printOutput(accounts)
+		}
+
+		rows := make([][]string, 0, len(accounts))
+		for _, account := range accounts {
+			rows = append(rows, []string{
+				str(account.Name),
+				uri.GetResourceGroup(str(account.ID)),
+				str(account.Location),
+				accountProvisioningState(account),
+			})
+		}
+		printTable([]string{"NAME", "RESOURCE GROUP", "LOCATION", "STATE"}, rows)
+		return nil
+	},
+}
+
+// accountUpdateCmd represents the account update command
+var accountUpdateCmd = &cobra.Command{
+	Use:   "update <name>",
+	Short: "Update the tags or the Active Directory connection of an account",
+	Example: `  go-anf account update myaccount -g myrg --tags env=prod
+  go-anf account update myaccount -g myrg --ad-domain contoso.com --ad-dns 10.0.0.4 --ad-username admin --ad-smb-server-name anfsmb`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, err := requiredString(cmd, "resource-group")
+		if err != nil {
+			return err
+		}
+		activeDirectories, err := activeDirectoriesFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		tags := tagsFromFlag(cmd)
+		if activeDirectories == nil && tags == nil {
+			return invalidUsage("nothing to update, use --tags or the --ad-* flags")
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		current, err := client.GetANFAccount(cmd.Context(), resourceGroupName, args[0])
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Updating account %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginUpdateANFAccount(cmd.Context(), str(current.Location), resourceGroupName, args[0], activeDirectories, tags))
+		}
+		account, err := client.UpdateANFAccount(cmd.Context(), str(current.Location), resourceGroupName, args[0], activeDirectories, tags)
+		if err != nil {
+			return err
+		}
+
+		return printAccount(account)
+	},
+}
+
+// accountDeleteCmd represents the account delete command
+var accountDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete an account",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, err := requiredString(cmd, "resource-group")
+		if err != nil {
+			return err
+		}
+
+		if !confirm(cmd, fmt.Sprintf("Delete account %v in resource group %v?", args[0], resourceGroupName)) {
+			return nil
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Deleting account %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginDeleteANFAccount(cmd.Context(), resourceGroupName, args[0]))
+		}
+		return client.DeleteANFAccount(cmd.Context(), resourceGroupName, args[0])
+	},
+}
+
+// activeDirectoriesFromFlags builds the Active Directory connection from the --ad-* flags,
+// nil is returned when no connection was requested
+func activeDirectoriesFromFlags(cmd *cobra.Command) ([]*armnetapp.ActiveDirectory, error) {
+	domain, _ := cmd.Flags().GetString("ad-domain")
+	if domain == "" {
+		return nil, nil
+	}
+
+	dns, err := requiredString(cmd, "ad-dns")
+	if err != nil {
+		return nil, err
+	}
+	username, err := requiredString(cmd, "ad-username")
+	if err != nil {
+		return nil, err
+	}
+	smbServerName, err := requiredString(cmd, "ad-smb-server-name")
+	if err != nil {
+		return nil, err
+	}
+
+	password, _ := cmd.Flags().GetString("ad-password")
+	if password == "" {
+		password = utils.GetPassword(fmt.Sprintf("Password for %v@%v: ", username, domain))
+	}
+
+	activeDirectory := &armnetapp.ActiveDirectory{
+		Domain:        to.Ptr(domain),
+		DNS:           to.Ptr(dns),
+		Username:      to.Ptr(username),
+		Password:      to.Ptr(password),
+		SmbServerName: to.Ptr(smbServerName),
+	}
+
+	if site, _ := cmd.Flags().GetString("ad-site"); site != "" {
+		activeDirectory.Site = to.Ptr(site)
+	}
+	if organizationalUnit, _ := cmd.Flags().GetString("ad-ou"); organizationalUnit != "" {
+		activeDirectory.OrganizationalUnit = to.Ptr(organizationalUnit)
+	}
+	if cmd.Flags().Changed("ad-ldap-signing") {
+		ldapSigning, _ := cmd.Flags().GetBool("ad-ldap-signing")
+		activeDirectory.LdapSigning = to.Ptr(ldapSigning)
+	}
+	if cmd.Flags().Changed("ad-ldap-over-tls") {
+		ldapOverTLS, _ := cmd.Flags().GetBool("ad-ldap-over-tls")
+		activeDirectory.LdapOverTLS = to.Ptr(ldapOverTLS)
+	}
+	if path, _ := cmd.Flags().GetString("ad-server-root-ca"); path != "" {
+		certificate, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read server root CA certificate: %v", err)
+		}
+		// Azure expects the PEM file base64 encoded
+		activeDirectory.ServerRootCACertificate = to.Ptr(base64.StdEncoding.EncodeToString(certificate))
+	}
+
+	return []*armnetapp.ActiveDirectory{activeDirectory}, nil
+}
+
+func addActiveDirectoryFlags(cmd *cobra.Command) {
+	cmd.Flags().String("ad-domain", "", "Active Directory domain name, enables the Active Directory connection")
+	cmd.Flags().String("ad-dns", "", "Comma separated list of DNS server IP addresses for the Active Directory domain")
+	cmd.Flags().String("ad-username", "", "Username of an Active Directory account allowed to create machine accounts")
+	cmd.Flags().String("ad-password", "", "Password of the Active Directory account, prompted for when omitted")
+	cmd.Flags().String("ad-smb-server-name", "", "NetBIOS prefix of the SMB server machine account")
+	cmd.Flags().String("ad-site", "", "Active Directory site the domain controllers are discovered in")
+	cmd.Flags().String("ad-ou", "", "Organizational unit where the SMB server machine account is created")
+	cmd.Flags().Bool("ad-ldap-signing", false, "Sign the LDAP traffic of LDAP enabled volumes")
+	cmd.Flags().Bool("ad-ldap-over-tls", false, "Encrypt the LDAP traffic of LDAP enabled volumes with TLS, requires --ad-server-root-ca")
+	cmd.Flags().String("ad-server-root-ca", "", "PEM file of the root CA certificate of the Active Directory, used by LDAP over TLS")
+}
+
+func accountProvisioningState(account *armnetapp.Account) string {
+	if account.Properties == nil {
+		return ""
+	}
+	return str(account.Properties.ProvisioningState)
+}
+
+func printAccount(account *armnetapp.Account) error {
+	if !humanOutput() {
+		return printOutput(account)
+	}
+
+	utils.PrintHeader(fmt.Sprintf("Account %v", str(account.Name)))
+
+	fields := [][]string{
+		{"ID", str(account.ID)},
+		{"Location", str(account.Location)},
+		{"Provisioning state", accountProvisioningState(account)},
+		{"Tags", formatTags(account.Tags)},
+	}
+	if account.Properties != nil {
+		for _, activeDirectory := range account.Properties.ActiveDirectories {
+			fields = append(fields,
+				[]string{"AD domain", str(activeDirectory.Domain)},
+				[]string{"AD SMB server", str(activeDirectory.SmbServerName)},
+			)
+		}
+	}
+	printFields(fields)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(accountCmd)
+	accountCmd.AddCommand(accountCreateCmd, accountShowCmd, accountListCmd, accountUpdateCmd, accountDeleteCmd)
+
+	for _, cmd := range []*cobra.Command{accountCreateCmd, accountShowCmd, accountListCmd, accountUpdateCmd, accountDeleteCmd} {
+		addResourceGroupFlag(cmd)
+	}
+
+	accountCreateCmd.Flags().StringP("location", "l", "", "Azure region of the account, e.g. westeurope")
+	addTagsFlag(accountCreateCmd)
+	addActiveDirectoryFlags(accountCreateCmd)
+
+	addTagsFlag(accountUpdateCmd)
+	addActiveDirectoryFlags(accountUpdateCmd)
+
+	addYesFlag(accountDeleteCmd)
+
+	for _, cmd := range []*cobra.Command{accountCreateCmd, accountUpdateCmd, accountDeleteCmd} {
+		addNoWaitFlag(cmd)
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/patrikcze/go-anf/pkg/topology"
+	"github.com/patrikcze/go-anf/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// applyCmd represents the apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply [plan-file]",
+	Short: "Reconcile the resources of a resource group with a topology file",
+	Long: `Reconcile the resources of a resource group with a topology file.
+
+The topology is a YAML or JSON file describing accounts with their
+snapshot policies, capacity pools and volumes, including volume export
+rules. Missing resources are created and drifted ones are patched,
+parents before children (account, snapshot policy, pool, volume). With
+--prune the resources of the resource group that are not part of the
+topology are deleted as well, children first.
+
+Fields left out of the topology (e.g. tags) are not managed. Fields that
+cannot be changed in place, such as the service level of a pool or the
+subnet of a volume, are reported as conflicts and nothing is applied.
+
+Instead of a topology file, apply accepts a plan file written by
+"go-anf plan --out". The saved plan runs without a confirmation prompt,
+exactly as it was reviewed, and is refused when the live resources
+changed after it was created.
+
+Example topology:
+
+  resourceGroup: myrg
+  location: westeurope
+  accounts:
+    - name: myaccount
+      snapshotPolicies:
+        - name: daily
+          daily: {keep: 7, at: "02:30"}
+      pools:
+        - name: mypool
+          serviceLevel: Premium
+          size: 4TiB
+          volumes:
+            - name: myvol
+              quota: 100GiB
+              subnetId: <subnetID>
+              protocols: [NFSv4.1]
+              snapshotPolicy: daily
+              exportRules:
+                - allowedClients: 10.0.0.0/24
+                  nfsv41: true`,
+	Example: `  go-anf apply -f topology.yaml --dry-run
+  go-anf apply -f topology.yaml --prune --yes
+  go-anf plan -f topology.yaml --out plan.out && go-anf apply plan.out`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		var spec *topology.Topology
+		var plan *topology.Plan
+		if len(args) == 1 {
+			if cmd.Flags().Changed("file") || cmd.Flags().Changed("prune") {
+				return invalidUsage("--file and --prune cannot be combined with a saved plan, they were fixed when the plan was created")
+			}
+
+			saved, err := topology.LoadPlan(args[0])
+			if err != nil {
+				return err
+			}
+
+			utils.ConsoleOutput(fmt.Sprintf("Checking plan %v against resource group %v...", args[0], saved.Topology.ResourceGroup))
+			if err := saved.Verify(cmd.Context(), client); err != nil {
+				return err
+			}
+			spec, plan = saved.Topology, saved.Plan
+		} else {
+			spec, err = loadTopology(cmd)
+			if err != nil {
+				return err
+			}
+			prune, _ := cmd.Flags().GetBool("prune")
+
+			utils.ConsoleOutput(fmt.Sprintf("Comparing topology with resource group %v...", spec.ResourceGroup))
+			plan, err = topology.NewPlan(cmd.Context(), client, spec, prune)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := printPlan(plan); err != nil {
+			return err
+		}
+		if dryRun || len(plan.Actions) == 0 {
+			return nil
+		}
+
+		// A saved plan was already reviewed
+		if len(args) == 0 && !confirm(cmd, "Apply these changes?") {
+			return nil
+		}
+
+		if err := plan.Apply(cmd.Context(), client, spec); err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Apply complete: %v created, %v updated, %v deleted", plan.Count(topology.OperationCreate), plan.Count(topology.OperationUpdate), plan.Count(topology.OperationDelete)))
+		return nil
+	},
+}
+
+// loadTopology reads the --file topology, filling the resource group and location from the flags when it has none
+func loadTopology(cmd *cobra.Command) (*topology.Topology, error) {
+	path, err := requiredString(cmd, "file")
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := topology.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if spec.ResourceGroup == "" {
+		spec.ResourceGroup, _ = cmd.Flags().GetString("resource-group")
+	}
+	if spec.Location == "" {
+		spec.Location, _ = cmd.Flags().GetString("location")
+	}
+
+	return spec, spec.Validate()
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+
+	applyCmd.Flags().StringP("file", "f", "", "Topology file in YAML or JSON format")
+	addResourceGroupFlag(applyCmd)
+	applyCmd.Flags().StringP("location", "l", "", "Default location, used when the topology does not set one")
+	applyCmd.Flags().Bool("prune", false, "Delete resources of the resource group that are not part of the topology")
+	applyCmd.Flags().Bool("dry-run", false, "Only print the changes")
+	addYesFlag(applyCmd)
+}
@@ -0,0 +1,807 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+	"github.com/patrikcze/go-anf/pkg/models"
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+	"github.com/patrikcze/go-anf/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Manage volume backups, backup policies and backup vaults",
+	Long: `Enable backups of volumes, take on-demand backups, list them and
+restore them into new volumes, and manage the backup policies that take
+scheduled backups.
+
+Backups are stored in the backup vault of the account, which Azure
+creates and manages: the vaults can be listed but not created, updated
+or deleted with API version 2022-05-01.`,
+}
+
+// backupCreateCmd represents the backup create command
+var backupCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Take an on-demand backup of a volume",
+	Long: `Take an on-demand backup of a volume. Backups must be enabled on the
+volume first, see "go-anf backup enable".`,
+	Example: `  go-anf backup create mybackup -g myrg -a myaccount -p mypool -v myvol --label before-upgrade`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, volumeName, err := volumeScope(cmd)
+		if err != nil {
+			return err
+		}
+		label, _ := cmd.Flags().GetString("label")
+		useExistingSnapshot, _ := cmd.Flags().GetBool("use-existing-snapshot")
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		volume, err := client.GetANFVolume(cmd.Context(), resourceGroupName, accountName, poolName, volumeName)
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Creating backup %v of volume %v...", args[0], volumeName))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginCreateANFBackup(cmd.Context(), str(volume.Location), resourceGroupName, accountName, poolName, volumeName, args[0], label, useExistingSnapshot))
+		}
+		backup, err := client.CreateANFBackup(cmd.Context(), str(volume.Location), resourceGroupName, accountName, poolName, volumeName, args[0], label, useExistingSnapshot)
+		if err != nil {
+			return err
+		}
+
+		return printBackup(backup)
+	},
+}
+
+// backupListCmd represents the backup list command
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List backups of a volume, oldest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, volumeName, err := volumeScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		backups, err := client.ListANFBackups(cmd.Context(), resourceGroupName, accountName, poolName, volumeName)
+		if err != nil {
+			return err
+		}
+		sort.SliceStable(backups, func(i, j int) bool {
+			return backupCreated(backups[i]).Before(backupCreated(backups[j]))
+		})
+
+		if !humanOutput() {
+			return printOutput(backups)
+		}
+
+		rows := make([][]string, 0, len(backups))
+		for _, backup := range backups {
+			properties := backupProperties(backup)
+			rows = append(rows, []string{
+				resourceName(backup.Name),
+				backupType(backup),
+				str(properties.Label),
+				formatTime(backupCreated(backup)),
+				backupSize(backup),
+				str(properties.ProvisioningState),
+			})
+		}
+		printTable([]string{"NAME", "TYPE", "LABEL", "CREATED", "SIZE", "STATE"}, rows)
+		return nil
+	},
+}
+
+// backupShowCmd represents the backup show command
+var backupShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show a backup",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, volumeName, err := volumeScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		backup, err := client.GetANFBackup(cmd.Context(), resourceGroupName, accountName, poolName, volumeName, args[0])
+		if err != nil {
+			return err
+		}
+
+		return printBackup(backup)
+	},
+}
+
+// backupDeleteCmd represents the backup delete command
+var backupDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a backup",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, volumeName, err := volumeScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		if !confirm(cmd, fmt.Sprintf("Delete backup %v of volume %v?", args[0], volumeName)) {
+			return nil
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Deleting backup %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginDeleteANFBackup(cmd.Context(), resourceGroupName, accountName, poolName, volumeName, args[0]))
+		}
+		return client.DeleteANFBackup(cmd.Context(), resourceGroupName, accountName, poolName, volumeName, args[0])
+	},
+}
+
+// backupRestoreCmd represents the backup restore command
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <name>",
+	Short: "Restore a backup into a new volume",
+	Long: `Restore a backup of a volume into a new volume, the backed up volume is
+left untouched.
+
+The new volume gets the subnet, protocols, quota, security style and
+export policy of the backed up volume unless they are overridden. To
+restore a backup of a volume that no longer exists, use
+"go-anf volume create --backup-id".`,
+	Example: `  go-anf backup restore mybackup -g myrg -a myaccount -p mypool -v myvol --to myvol-restored
+  go-anf backup restore mybackup -g myrg -a myaccount -p mypool -v myvol --to myvol-restored --to-pool otherpool --quota 200GiB`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, volumeName, err := volumeScope(cmd)
+		if err != nil {
+			return err
+		}
+		targetName, err := requiredString(cmd, "to")
+		if err != nil {
+			return err
+		}
+		targetPoolName, _ := cmd.Flags().GetString("to-pool")
+		if targetPoolName == "" {
+			targetPoolName = poolName
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		backup, err := client.GetANFBackup(cmd.Context(), resourceGroupName, accountName, poolName, volumeName, args[0])
+		if err != nil {
+			return err
+		}
+		source, err := client.GetANFVolume(cmd.Context(), resourceGroupName, accountName, poolName, volumeName)
+		if err != nil {
+			return err
+		}
+		pool, err := client.GetANFCapacityPool(cmd.Context(), resourceGroupName, accountName, targetPoolName)
+		if err != nil {
+			return err
+		}
+
+		spec := sdkutils.VolumeSpec{
+			Location:       str(source.Location),
+			ResourceGroup:  resourceGroupName,
+			Account:        accountName,
+			Pool:           targetPoolName,
+			Name:           targetName,
+			ServiceLevel:   poolServiceLevel(pool),
+			ProtocolTypes:  volumeProtocolTypes(source),
+			UsageThreshold: volumeQuotaBytes(source),
+			BackupID:       str(backup.ID),
+			ExportRules:    sdkutils.NewExportPolicyBuilder(source).Rules(),
+			Tags:           tagsFromFlag(cmd),
+		}
+		if properties := source.Properties; properties != nil {
+			spec.SubnetID = str(properties.SubnetID)
+			if properties.SecurityStyle != nil {
+				spec.SecurityStyle = string(*properties.SecurityStyle)
+			}
+			spec.KerberosEnabled = properties.KerberosEnabled
+			spec.LdapEnabled = properties.LdapEnabled
+		}
+		if subnetID, _ := cmd.Flags().GetString("subnet-id"); subnetID != "" {
+			spec.SubnetID = subnetID
+		}
+		if quota, _ := cmd.Flags().GetString("quota"); quota != "" {
+			if spec.UsageThreshold, err = utils.ParseSize(quota); err != nil {
+				return usageError{err}
+			}
+		}
+		if err := spec.Validate(); err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Restoring backup %v into volume %v...", args[0], targetName))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginCreateANFVolume(cmd.Context(), spec))
+		}
+		volume, err := client.CreateANFVolume(cmd.Context(), spec)
+		if err != nil {
+			return err
+		}
+
+		return printVolume(volume)
+	},
+}
+
+// backupEnableCmd represents the backup enable command
+var backupEnableCmd = &cobra.Command{
+	Use:   "enable <volume>",
+	Short: "Enable backups of a volume and assign a backup policy",
+	Long: `Enable backups of a volume in the backup vault of the account and
+optionally assign a backup policy taking scheduled backups. Without
+--policy, only on-demand backups are taken.`,
+	Example: `  go-anf backup enable myvol -g myrg -a myaccount -p mypool --policy mypolicy
+  go-anf backup enable myvol -g myrg -a myaccount -p mypool --vault myvault`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, err := poolScope(cmd)
+		if err != nil {
+			return err
+		}
+		policyName, _ := cmd.Flags().GetString("policy")
+		vaultName, _ := cmd.Flags().GetString("vault")
+		policyEnforced, _ := cmd.Flags().GetBool("policy-enforced")
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		vault, err := client.GetANFBackupVault(cmd.Context(), resourceGroupName, accountName, vaultName)
+		if err != nil {
+			return err
+		}
+		policyID := ""
+		if policyName != "" {
+			policy, err := client.GetANFBackupPolicy(cmd.Context(), resourceGroupName, accountName, policyName)
+			if err != nil {
+				return err
+			}
+			policyID = str(policy.ID)
+		}
+		volume, err := client.GetANFVolume(cmd.Context(), resourceGroupName, accountName, poolName, args[0])
+		if err != nil {
+			return err
+		}
+
+		patch := sdkutils.NewANFVolumeBackupPatch(str(vault.ID), policyID, policyEnforced)
+
+		utils.ConsoleOutput(fmt.Sprintf("Enabling backups of volume %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginUpdateANFVolume(cmd.Context(), str(volume.Location), resourceGroupName, accountName, poolName, args[0], patch, nil))
+		}
+		volume, err = client.UpdateANFVolume(cmd.Context(), str(volume.Location), resourceGroupName, accountName, poolName, args[0], patch, nil)
+		if err != nil {
+			return err
+		}
+
+		return printVolume(volume)
+	},
+}
+
+// backupDisableCmd represents the backup disable command
+var backupDisableCmd = &cobra.Command{
+	Use:   "disable <volume>",
+	Short: "Disable backups of a volume",
+	Long: `Disable backups of a volume, its backup policy no longer takes
+scheduled backups. The existing backups are kept in the backup vault.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, err := poolScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		if !confirm(cmd, fmt.Sprintf("Disable backups of volume %v?", args[0])) {
+			return nil
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		volume, err := client.GetANFVolume(cmd.Context(), resourceGroupName, accountName, poolName, args[0])
+		if err != nil {
+			return err
+		}
+
+		patch := armnetapp.VolumePatchProperties{
+			DataProtection: &armnetapp.VolumePatchPropertiesDataProtection{
+				Backup: &armnetapp.VolumeBackupProperties{
+					BackupEnabled: to.Ptr(false),
+				},
+			},
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Disabling backups of volume %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginUpdateANFVolume(cmd.Context(), str(volume.Location), resourceGroupName, accountName, poolName, args[0], patch, nil))
+		}
+		volume, err = client.UpdateANFVolume(cmd.Context(), str(volume.Location), resourceGroupName, accountName, poolName, args[0], patch, nil)
+		if err != nil {
+			return err
+		}
+
+		return printVolume(volume)
+	},
+}
+
+// backupVaultCmd represents the backup vault command
+var backupVaultCmd = &cobra.Command{
+	Use:   "vault",
+	Short: "Inspect backup vaults",
+	Long: `Inspect the backup vaults of an account. Azure creates the backup vault
+of an account and API version 2022-05-01 offers no way to create, update
+or delete it.`,
+}
+
+// backupVaultListCmd represents the backup vault list command
+var backupVaultListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List backup vaults of an account",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, err := accountScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		vaults, err := client.ListANFBackupVaults(cmd.Context(), resourceGroupName, accountName)
+		if err != nil {
+			return err
+		}
+
+		if !humanOutput() {
+			return printOutput(vaults)
+		}
+
+		rows := make([][]string, 0, len(vaults))
+		for _, vault := range vaults {
+			rows = append(rows, []string{
+				resourceName(vault.Name),
+				str(vault.Location),
+				str(vault.ID),
+			})
+		}
+		printTable([]string{"NAME", "LOCATION", "ID"}, rows)
+		return nil
+	},
+}
+
+// backupPolicyCmd represents the backup policy command
+var backupPolicyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Manage backup policies",
+	Long: `Create, list, show, update and delete backup policies.
+
+A backup policy belongs to an account and keeps a number of daily, weekly
+and monthly backups of the volumes it is assigned to with
+"go-anf backup enable". A policy keeps at least 2 daily backups.`,
+}
+
+// backupPolicyCreateCmd represents the backup policy create command
+var backupPolicyCreateCmd = &cobra.Command{
+	Use:     "create <name>",
+	Short:   "Create a backup policy",
+	Example: `  go-anf backup policy create mypolicy -g myrg -a myaccount --daily-keep 7 --weekly-keep 4 --monthly-keep 12`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, err := accountScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		// A new policy starts from the flag defaults
+		retention := models.BackupPolicyRetention{}
+		retention.DailyBackupsToKeep, _ = cmd.Flags().GetInt32("daily-keep")
+		applyRetentionFlags(cmd, &retention)
+		enabled, _ := cmd.Flags().GetBool("enabled")
+
+		properties, err := sdkutils.NewANFBackupPolicyProperties(retention, enabled)
+		if err != nil {
+			return err
+		}
+
+		location, _ := cmd.Flags().GetString("location")
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		if location == "" {
+			account, err := client.GetANFAccount(cmd.Context(), resourceGroupName, accountName)
+			if err != nil {
+				return err
+			}
+			location = str(account.Location)
+		}
+
+		policy := armnetapp.BackupPolicy{
+			Location:   to.Ptr(location),
+			Tags:       tagsFromFlag(cmd),
+			Properties: properties,
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Creating backup policy %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginCreateANFBackupPolicy(cmd.Context(), resourceGroupName, accountName, args[0], policy))
+		}
+		created, err := client.CreateANFBackupPolicy(cmd.Context(), resourceGroupName, accountName, args[0], policy)
+		if err != nil {
+			return err
+		}
+
+		return printBackupPolicy(created)
+	},
+}
+
+// backupPolicyListCmd represents the backup policy list command
+var backupPolicyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List backup policies of an account",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, err := accountScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		policies, err := client.ListANFBackupPolicies(cmd.Context(), resourceGroupName, accountName)
+		if err != nil {
+			return err
+		}
+
+		if !humanOutput() {
+			return printOutput(policies)
+		}
+
+		rows := make([][]string, 0, len(policies))
+		for _, policy := range policies {
+			retention := retentionFromPolicy(policy)
+			rows = append(rows, []string{
+				resourceName(policy.Name),
+				strconv.FormatBool(backupPolicyEnabled(policy)),
+				strconv.Itoa(int(retention.DailyBackupsToKeep)),
+				strconv.Itoa(int(retention.WeeklyBackupsToKeep)),
+				strconv.Itoa(int(retention.MonthlyBackupsToKeep)),
+				strconv.Itoa(int(backupPolicyVolumes(policy))),
+			})
+		}
+		printTable([]string{"NAME", "ENABLED", "DAILY", "WEEKLY", "MONTHLY", "VOLUMES"}, rows)
+		return nil
+	},
+}
+
+// backupPolicyShowCmd represents the backup policy show command
+var backupPolicyShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show a backup policy",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, err := accountScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		policy, err := client.GetANFBackupPolicy(cmd.Context(), resourceGroupName, accountName, args[0])
+		if err != nil {
+			return err
+		}
+
+		return printBackupPolicy(policy)
+	},
+}
+
+// backupPolicyUpdateCmd represents the backup policy update command
+var backupPolicyUpdateCmd = &cobra.Command{
+	Use:   "update <name>",
+	Short: "Update the retention of a backup policy",
+	Long: `Update the retention of a backup policy.
+
+Only the retention flags that are given change, the rest of the policy
+is kept as is.`,
+	Example: `  go-anf backup policy update mypolicy -g myrg -a myaccount --monthly-keep 24
+  go-anf backup policy update mypolicy -g myrg -a myaccount --enabled=false`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, err := accountScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		current, err := client.GetANFBackupPolicy(cmd.Context(), resourceGroupName, accountName, args[0])
+		if err != nil {
+			return err
+		}
+
+		retention := retentionFromPolicy(current)
+		applyRetentionFlags(cmd, &retention)
+		enabled := backupPolicyEnabled(current)
+		if cmd.Flags().Changed("enabled") {
+			enabled, _ = cmd.Flags().GetBool("enabled")
+		}
+
+		properties, err := sdkutils.NewANFBackupPolicyProperties(retention, enabled)
+		if err != nil {
+			return err
+		}
+
+		patch := armnetapp.BackupPolicyPatch{
+			Location:   current.Location,
+			Tags:       tagsFromFlag(cmd),
+			Properties: properties,
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Updating backup policy %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginUpdateANFBackupPolicy(cmd.Context(), resourceGroupName, accountName, args[0], patch))
+		}
+		policy, err := client.UpdateANFBackupPolicy(cmd.Context(), resourceGroupName, accountName, args[0], patch)
+		if err != nil {
+			return err
+		}
+
+		return printBackupPolicy(policy)
+	},
+}
+
+// backupPolicyDeleteCmd represents the backup policy delete command
+var backupPolicyDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a backup policy",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, err := accountScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		if !confirm(cmd, fmt.Sprintf("Delete backup policy %v in account %v?", args[0], accountName)) {
+			return nil
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Deleting backup policy %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginDeleteANFBackupPolicy(cmd.Context(), resourceGroupName, accountName, args[0]))
+		}
+		return client.DeleteANFBackupPolicy(cmd.Context(), resourceGroupName, accountName, args[0])
+	},
+}
+
+// applyRetentionFlags overrides the retention with every retention flag given on the command line
+func applyRetentionFlags(cmd *cobra.Command, retention *models.BackupPolicyRetention) {
+	flags := cmd.Flags()
+
+	if flags.Changed("daily-keep") {
+		retention.DailyBackupsToKeep, _ = flags.GetInt32("daily-keep")
+	}
+	if flags.Changed("weekly-keep") {
+		retention.WeeklyBackupsToKeep, _ = flags.GetInt32("weekly-keep")
+	}
+	if flags.Changed("monthly-keep") {
+		retention.MonthlyBackupsToKeep, _ = flags.GetInt32("monthly-keep")
+	}
+}
+
+// retentionFromPolicy converts the retention of an existing policy back into its flag form
+func retentionFromPolicy(policy *armnetapp.BackupPolicy) models.BackupPolicyRetention {
+	retention := models.BackupPolicyRetention{}
+	if policy.Properties == nil {
+		return retention
+	}
+
+	retention.DailyBackupsToKeep = int32Value(policy.Properties.DailyBackupsToKeep)
+	retention.WeeklyBackupsToKeep = int32Value(policy.Properties.WeeklyBackupsToKeep)
+	retention.MonthlyBackupsToKeep = int32Value(policy.Properties.MonthlyBackupsToKeep)
+	return retention
+}
+
+func backupPolicyEnabled(policy *armnetapp.BackupPolicy) bool {
+	return policy.Properties != nil && policy.Properties.Enabled != nil && *policy.Properties.Enabled
+}
+
+func backupPolicyVolumes(policy *armnetapp.BackupPolicy) int32 {
+	if policy.Properties == nil {
+		return 0
+	}
+	return int32Value(policy.Properties.VolumesAssigned)
+}
+
+func printBackupPolicy(policy *armnetapp.BackupPolicy) error {
+	if !humanOutput() {
+		return printOutput(policy)
+	}
+
+	utils.PrintHeader(fmt.Sprintf("Backup policy %v", resourceName(policy.Name)))
+
+	retention := retentionFromPolicy(policy)
+	provisioningState := ""
+	if policy.Properties != nil {
+		provisioningState = str(policy.Properties.ProvisioningState)
+	}
+
+	printFields([][]string{
+		{"ID", str(policy.ID)},
+		{"Location", str(policy.Location)},
+		{"Enabled", strconv.FormatBool(backupPolicyEnabled(policy))},
+		{"Daily", fmt.Sprintf("keep %v", retention.DailyBackupsToKeep)},
+		{"Weekly", fmt.Sprintf("keep %v", retention.WeeklyBackupsToKeep)},
+		{"Monthly", fmt.Sprintf("keep %v", retention.MonthlyBackupsToKeep)},
+		{"Volumes assigned", strconv.Itoa(int(backupPolicyVolumes(policy)))},
+		{"Provisioning state", provisioningState},
+		{"Tags", formatTags(policy.Tags)},
+	})
+	return nil
+}
+
+// backupProperties returns the properties of a backup, never nil
+func backupProperties(backup *armnetapp.Backup) *armnetapp.BackupProperties {
+	if backup.Properties == nil {
+		return &armnetapp.BackupProperties{}
+	}
+	return backup.Properties
+}
+
+func backupCreated(backup *armnetapp.Backup) time.Time {
+	if created := backupProperties(backup).CreationDate; created != nil {
+		return *created
+	}
+	return time.Time{}
+}
+
+func backupType(backup *armnetapp.Backup) string {
+	if backupType := backupProperties(backup).BackupType; backupType != nil {
+		return string(*backupType)
+	}
+	return ""
+}
+
+func backupSize(backup *armnetapp.Backup) string {
+	if size := backupProperties(backup).Size; size != nil {
+		return utils.FormatSize(*size)
+	}
+	return ""
+}
+
+func printBackup(backup *armnetapp.Backup) error {
+	if !humanOutput() {
+		return printOutput(backup)
+	}
+
+	utils.PrintHeader(fmt.Sprintf("Backup %v", resourceName(backup.Name)))
+
+	properties := backupProperties(backup)
+	fields := [][]string{
+		{"ID", str(backup.ID)},
+		{"Backup ID", str(properties.BackupID)},
+		{"Location", str(backup.Location)},
+		{"Volume", str(properties.VolumeName)},
+		{"Type", backupType(backup)},
+		{"Label", str(properties.Label)},
+		{"Created", formatTime(backupCreated(backup))},
+		{"Size", backupSize(backup)},
+		{"Provisioning state", str(properties.ProvisioningState)},
+	}
+	if properties.FailureReason != nil {
+		fields = append(fields, []string{"Failure reason", *properties.FailureReason})
+	}
+
+	printFields(fields)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	backupCmd.AddCommand(backupCreateCmd, backupListCmd, backupShowCmd, backupDeleteCmd, backupRestoreCmd, backupEnableCmd, backupDisableCmd, backupVaultCmd, backupPolicyCmd)
+	backupVaultCmd.AddCommand(backupVaultListCmd)
+	backupPolicyCmd.AddCommand(backupPolicyCreateCmd, backupPolicyListCmd, backupPolicyShowCmd, backupPolicyUpdateCmd, backupPolicyDeleteCmd)
+
+	for _, cmd := range []*cobra.Command{backupCreateCmd, backupListCmd, backupShowCmd, backupDeleteCmd, backupRestoreCmd} {
+		addResourceGroupFlag(cmd)
+		cmd.Flags().StringP("account", "a", "", "Name of the account")
+		cmd.Flags().StringP("pool", "p", "", "Name of the capacity pool")
+		cmd.Flags().StringP("volume", "v", "", "Name of the volume")
+	}
+	for _, cmd := range []*cobra.Command{backupEnableCmd, backupDisableCmd} {
+		addResourceGroupFlag(cmd)
+		cmd.Flags().StringP("account", "a", "", "Name of the account")
+		cmd.Flags().StringP("pool", "p", "", "Name of the capacity pool of the volume")
+	}
+	for _, cmd := range []*cobra.Command{backupVaultListCmd, backupPolicyCreateCmd, backupPolicyListCmd, backupPolicyShowCmd, backupPolicyUpdateCmd, backupPolicyDeleteCmd} {
+		addResourceGroupFlag(cmd)
+		cmd.Flags().StringP("account", "a", "", "Name of the account")
+	}
+
+	backupCreateCmd.Flags().String("label", "", "Label of the backup")
+	backupCreateCmd.Flags().Bool("use-existing-snapshot", false, "Back up the most recent snapshot of the volume instead of taking a new one")
+
+	backupRestoreCmd.Flags().String("to", "", "Name of the new volume the backup is restored into")
+	backupRestoreCmd.Flags().String("to-pool", "", "Capacity pool of the new volume, defaults to the pool of the backed up volume")
+	backupRestoreCmd.Flags().String("subnet-id", "", "Resource ID of the subnet of the new volume, defaults to the subnet of the backed up volume")
+	backupRestoreCmd.Flags().String("quota", "", "Quota of the new volume in whole GiB, defaults to the quota of the backed up volume")
+	addTagsFlag(backupRestoreCmd)
+
+	backupEnableCmd.Flags().String("policy", "", "Name of the backup policy to assign, only on-demand backups are taken without one")
+	backupEnableCmd.Flags().String("vault", "", "Name of the backup vault, defaults to the vault of the account")
+	backupEnableCmd.Flags().Bool("policy-enforced", true, "Whether the backup policy takes scheduled backups of the volume")
+
+	backupPolicyCreateCmd.Flags().StringP("location", "l", "", "Azure region of the policy, defaults to the account location")
+	for _, cmd := range []*cobra.Command{backupPolicyCreateCmd, backupPolicyUpdateCmd} {
+		cmd.Flags().Int32("daily-keep", 2, "Number of daily backups to keep, at least 2")
+		cmd.Flags().Int32("weekly-keep", 0, "Number of weekly backups to keep")
+		cmd.Flags().Int32("monthly-keep", 0, "Number of monthly backups to keep")
+		cmd.Flags().Bool("enabled", true, "Whether the policy takes backups")
+		addTagsFlag(cmd)
+	}
+
+	addYesFlag(backupDeleteCmd)
+	addYesFlag(backupDisableCmd)
+	addYesFlag(backupPolicyDeleteCmd)
+
+	for _, cmd := range []*cobra.Command{backupCreateCmd, backupDeleteCmd, backupRestoreCmd, backupEnableCmd, backupDisableCmd, backupPolicyCreateCmd, backupPolicyUpdateCmd, backupPolicyDeleteCmd} {
+		addNoWaitFlag(cmd)
+	}
+}
@@ -0,0 +1,230 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/patrikcze/go-anf/pkg/emulator"
+	"github.com/patrikcze/go-anf/pkg/output"
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+	"github.com/patrikcze/go-anf/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// anfClient is created on first use and shared by all commands, so the
+// authentication file is read and the credential is built only once
+var anfClient *sdkutils.Client
+
+// getClient returns the shared client, authenticated with the active profile,
+// or talking to the emulator referenced by GO_ANF_EMULATOR
+func getClient() (*sdkutils.Client, error) {
+	if anfClient == nil {
+		var client *sdkutils.Client
+		var err error
+		if endpoint := os.Getenv(emulatorVariable); endpoint != "" {
+			subscriptionID := profileSubscriptionID
+			if subscriptionID == "" {
+				subscriptionID = emulator.SubscriptionID
+			}
+			client, err = sdkutils.NewClient(emulator.Credential{}, subscriptionID, retryOptions().ClientOptions(emulator.ClientOptions(endpoint)))
+		} else {
+			client, err = sdkutils.NewClientFromAuthFile(profileSubscriptionID, profileTenantID, retryOptions().ClientOptions(nil))
+		}
+		if err != nil {
+			return nil, err
+		}
+		client.SetWaitOptions(&sdkutils.WaitOptions{Timeout: timeout})
+
+		if j, err := getJournal(); err == nil {
+			client.SetJournal(j)
+		} else {
+			utils.ConsoleOutput(fmt.Sprintf("Warning: long-running operations are not recorded: %v", err))
+		}
+		anfClient = client
+	}
+
+	return anfClient, nil
+}
+
+// stdin is shared by every prompt so buffered input is not lost between questions
+var stdin = bufio.NewReader(os.Stdin)
+
+// retryOptions returns the retry options selected by the global flags, retries are logged to stderr
+func retryOptions() sdkutils.RetryOptions {
+	options := sdkutils.RetryOptions{
+		MaxRetries:       maxRetries,
+		RetryDelay:       retryDelay,
+		MaxRetryDelay:    maxRetryDelay,
+		IgnoreRetryAfter: !honorRetryAfter,
+		OnRetry: func(message string) {
+			utils.ConsoleOutput(fmt.Sprintf("Backing off: %v", message))
+		},
+	}
+	if options.MaxRetries == 0 {
+		// 0 selects the default retries in azcore
+		options.MaxRetries = -1
+	}
+	return options
+}
+
+// addResourceGroupFlag registers the --resource-group flag shared by all resource commands
+func addResourceGroupFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("resource-group", "g", "", "Name of the resource group")
+}
+
+// addTagsFlag registers the --tags flag used by create and update commands
+func addTagsFlag(cmd *cobra.Command) {
+	cmd.Flags().StringToString("tags", nil, "Resource tags in key=value format, e.g. --tags env=dev,owner=me")
+}
+
+// addYesFlag registers the --yes flag used to skip confirmation prompts
+func addYesFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolP("yes", "y", false, "Do not prompt for confirmation")
+}
+
+// addNoWaitFlag registers the --no-wait flag of commands starting a long-running operation
+func addNoWaitFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("no-wait", false, "Do not wait for the operation to finish, print its ID and the resource ID")
+}
+
+// noWait reports whether a command was asked not to wait for its operation
+func noWait(cmd *cobra.Command) bool {
+	value, _ := cmd.Flags().GetBool("no-wait")
+	return value
+}
+
+// requiredString returns the value of a string flag and fails when it is empty
+func requiredString(cmd *cobra.Command, name string) (string, error) {
+	value, _ := cmd.Flags().GetString(name)
+	if strings.TrimSpace(value) == "" {
+		return "", invalidUsage("flag --%v is required", name)
+	}
+	return value, nil
+}
+
+// accountScope returns the resource group and account flags required by account scoped commands
+func accountScope(cmd *cobra.Command) (string, string, error) {
+	resourceGroupName, err := requiredString(cmd, "resource-group")
+	if err != nil {
+		return "", "", err
+	}
+	accountName, err := requiredString(cmd, "account")
+	if err != nil {
+		return "", "", err
+	}
+	return resourceGroupName, accountName, nil
+}
+
+// poolScope returns the resource group, account and pool flags required by pool scoped commands
+func poolScope(cmd *cobra.Command) (string, string, string, error) {
+	resourceGroupName, accountName, err := accountScope(cmd)
+	if err != nil {
+		return "", "", "", err
+	}
+	poolName, err := requiredString(cmd, "pool")
+	if err != nil {
+		return "", "", "", err
+	}
+	return resourceGroupName, accountName, poolName, nil
+}
+
+// volumeScope returns the resource group, account, pool and volume flags required by volume scoped commands
+func volumeScope(cmd *cobra.Command) (string, string, string, string, error) {
+	resourceGroupName, accountName, poolName, err := poolScope(cmd)
+	if err != nil {
+		return "", "", "", "", err
+	}
+	volumeName, err := requiredString(cmd, "volume")
+	if err != nil {
+		return "", "", "", "", err
+	}
+	return resourceGroupName, accountName, poolName, volumeName, nil
+}
+
+// tagsFromFlag converts the --tags flag into the map expected by the SDK,
+// nil is returned when the flag was not set so updates leave tags untouched
+func tagsFromFlag(cmd *cobra.Command) map[string]*string {
+	if !cmd.Flags().Changed("tags") {
+		return nil
+	}
+
+	tags, _ := cmd.Flags().GetStringToString("tags")
+	result := make(map[string]*string, len(tags))
+	for key, value := range tags {
+		result[key] = to.Ptr(value)
+	}
+	return result
+}
+
+// confirm asks the user for a yes/no answer unless --yes was given
+func confirm(cmd *cobra.Command, prompt string) bool {
+	if yes, _ := cmd.Flags().GetBool("yes"); yes {
+		return true
+	}
+
+	return askYesNo(prompt)
+}
+
+// humanOutput reports whether the default table format was selected without a --query,
+// in which case commands print their own human readable view
+func humanOutput() bool {
+	return outputFormat == output.FormatTable && outputQuery == ""
+}
+
+// printOutput writes SDK objects to stdout in the format selected with --output and --query
+func printOutput(data interface{}) error {
+	return output.Print(os.Stdout, outputFormat, outputQuery, data)
+}
+
+// printTable writes rows as aligned columns to stdout
+func printTable(headers []string, rows [][]string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+}
+
+// printFields writes name/value pairs as aligned columns to stdout
+func printFields(fields [][]string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, field := range fields {
+		fmt.Fprintf(w, "%v:\t%v\n", field[0], field[1])
+	}
+	w.Flush()
+}
+
+// str dereferences an optional string returned by the SDK
+func str(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// resourceName strips the parent prefix ARM adds to nested resource names,
+// e.g. account/pool/volume becomes volume
+func resourceName(name *string) string {
+	value := str(name)
+	return value[strings.LastIndex(value, "/")+1:]
+}
+
+// formatTags renders resource tags as a comma separated key=value list
+func formatTags(tags map[string]*string) string {
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", key, str(value)))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
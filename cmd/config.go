@@ -7,34 +7,143 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/patrikcze/go-anf/pkg/config"
 	"github.com/spf13/cobra"
 )
 
 // configCmd represents the config command
 var configCmd = &cobra.Command{
 	Use:   "config",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("config called")
+	Short: "Manage configuration profiles",
+	Long: `Manage the named profiles stored in the configuration file
+($HOME/.go-anf.yaml unless --config is given).
+
+A profile holds the subscription, tenant and authentication file used
+to reach Azure, plus the default resource group, location and account
+used by every other command when the matching flag is omitted.
+
+The active profile is selected with --profile, falling back to the one
+chosen with "go-anf config use".`,
+}
+
+// configUseCmd represents the config use command
+var configUseCmd = &cobra.Command{
+	Use:   "use <profile>",
+	Short: "Make a profile the current one",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, path, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if _, err := cfg.Profile(args[0]); err != nil {
+			return err
+		}
+
+		cfg.CurrentProfile = args[0]
+		if err := cfg.Save(path); err != nil {
+			return err
+		}
+
+		fmt.Printf("Switched to profile %v\n", args[0])
+		return nil
 	},
 }
 
-func init() {
-	rootCmd.AddCommand(configCmd)
+// configListCmd represents the config list command
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, _, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		if !humanOutput() {
+			return printOutput(cfg)
+		}
+
+		current := cfg.ActiveProfileName("")
+		rows := [][]string{}
+		for _, name := range cfg.ProfileNames() {
+			profile := cfg.Profiles[name]
+			marker := ""
+			if name == current {
+				marker = "*"
+			}
+			rows = append(rows, []string{marker, name, profile.SubscriptionID, profile.ResourceGroup, profile.Location, profile.Account})
+		}
+		printTable([]string{"CURRENT", "NAME", "SUBSCRIPTION", "RESOURCE GROUP", "LOCATION", "ACCOUNT"}, rows)
+		return nil
+	},
+}
+
+// configGetCmd represents the config get command
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a value of the active profile",
+	Long: fmt.Sprintf(`Print a value of the active profile.
 
-	// Here you will define your flags and configuration settings.
+Valid keys are: %v`, config.Keys()),
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, _, err := loadConfig()
+		if err != nil {
+			return err
+		}
 
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// configCmd.PersistentFlags().String("foo", "", "A help for foo")
+		profile, err := cfg.Profile(cfg.ActiveProfileName(profileName))
+		if err != nil {
+			return err
+		}
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// configCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+		value, err := profile.Get(args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(value)
+		return nil
+	},
+}
+
+// configSetCmd represents the config set command
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a value of the active profile, creating the profile if needed",
+	Long: fmt.Sprintf(`Change a value of the active profile, creating the profile if needed.
+
+Valid keys are: %v`, config.Keys()),
+	Example: `  go-anf config set resource-group myrg
+  go-anf config set location westeurope --profile prod`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, path, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		name := cfg.ActiveProfileName(profileName)
+		profile, found := cfg.Profiles[name]
+		if !found {
+			profile = &config.Profile{}
+			cfg.Profiles[name] = profile
+		}
+		if cfg.CurrentProfile == "" {
+			cfg.CurrentProfile = name
+		}
+
+		if err := profile.Set(args[0], args[1]); err != nil {
+			return err
+		}
+
+		return cfg.Save(path)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configUseCmd, configListCmd, configGetCmd, configSetCmd)
 }
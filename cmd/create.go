@@ -6,35 +6,115 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/patrikcze/go-anf/pkg/config"
+	"github.com/patrikcze/go-anf/pkg/iam"
+	"github.com/patrikcze/go-anf/pkg/models"
+	"github.com/patrikcze/go-anf/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
-// createCmd represents the create command
+// createCmd represents the config create command
 var createCmd = &cobra.Command{
-	Use:   "create",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("create called")
+	Use:   "create [profile]",
+	Short: "Create or edit a profile interactively",
+	Long: `Create or edit a profile interactively.
+
+Every question shows the current value in brackets, press enter to keep
+it. When the authentication file does not exist yet, the wizard offers
+to create it from a service principal client ID and secret; the secret
+is read without echo.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, path, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		name := cfg.ActiveProfileName(profileName)
+		if len(args) == 1 {
+			name = args[0]
+		}
+		name = ask("Profile name", name)
+
+		profile, found := cfg.Profiles[name]
+		if !found {
+			profile = &config.Profile{}
+		}
+
+		profile.SubscriptionID = ask("Subscription ID", profile.SubscriptionID)
+		profile.TenantID = ask("Tenant ID", profile.TenantID)
+		profile.ResourceGroup = ask("Default resource group", profile.ResourceGroup)
+		profile.Location = ask("Default location", profile.Location)
+		profile.Account = ask("Default account", profile.Account)
+
+		authFile := profile.AuthFile
+		if authFile == "" {
+			if home, err := os.UserHomeDir(); err == nil {
+				authFile = filepath.Join(home, ".azure", fmt.Sprintf("go-anf-%v.json", name))
+			}
+		}
+		profile.AuthFile = ask("Authentication file", authFile)
+
+		if _, err := os.Stat(profile.AuthFile); os.IsNotExist(err) && askYesNo("Authentication file does not exist, create it now?") {
+			clientID := ask("Service principal client ID", "")
+			clientSecret := utils.GetPassword("Service principal client secret: ")
+			if clientID == "" || clientSecret == "" {
+				return fmt.Errorf("client ID and client secret are required to create the authentication file")
+			}
+
+			if err := os.MkdirAll(filepath.Dir(profile.AuthFile), 0700); err != nil {
+				return err
+			}
+			err := iam.WriteAuthJSON(profile.AuthFile, &models.AzureAuthInfo{
+				ClientID:       &clientID,
+				ClientSecret:   &clientSecret,
+				SubscriptionID: &profile.SubscriptionID,
+				TenantID:       &profile.TenantID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		cfg.Profiles[name] = profile
+		if cfg.CurrentProfile == "" || askYesNo(fmt.Sprintf("Make %v the current profile?", name)) {
+			cfg.CurrentProfile = name
+		}
+
+		if err := cfg.Save(path); err != nil {
+			return err
+		}
+
+		fmt.Printf("Profile %v saved to %v\n", name, path)
+		return nil
 	},
 }
 
-func init() {
-	configCmd.AddCommand(createCmd)
+// ask prints a question with its default value and returns the answer or the default
+func ask(question, defaultValue string) string {
+	if defaultValue != "" {
+		fmt.Printf("%v [%v]: ", question, defaultValue)
+	} else {
+		fmt.Printf("%v: ", question)
+	}
 
-	// Here you will define your flags and configuration settings.
+	answer, _ := stdin.ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return defaultValue
+	}
+	return answer
+}
 
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// createCmd.PersistentFlags().String("foo", "", "A help for foo")
+func askYesNo(question string) bool {
+	answer := strings.ToLower(ask(fmt.Sprintf("%v [y/N]", question), ""))
+	return answer == "y" || answer == "yes"
+}
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// createCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+func init() {
+	configCmd.AddCommand(createCmd)
 }
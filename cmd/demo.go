@@ -0,0 +1,305 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+	"github.com/patrikcze/go-anf/pkg/uri"
+	"github.com/patrikcze/go-anf/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// demoStep records the duration and outcome of a single demo step
+type demoStep struct {
+	name     string
+	duration time.Duration
+	err      error
+}
+
+// demoRun holds the state shared by the demo steps
+type demoRun struct {
+	ctx    context.Context
+	client *sdkutils.Client
+	steps  []demoStep
+	// created holds the IDs of the resources created by the demo, in creation order
+	created []string
+}
+
+// demoCmd represents the demo command
+var demoCmd = &cobra.Command{
+	Use:   "demo",
+	Short: "Run the end-to-end scenario described in the README",
+	Long: `Run the end-to-end scenario described in the README:
+
+  1. create an account
+  2. create a capacity pool
+  3. create an NFSv3 and an NFSv4.1 volume
+  4. take a snapshot of the NFSv3 volume
+  5. create a new volume from the snapshot
+  6. resize the NFSv4.1 volume
+
+With --cleanup every resource created by the demo is deleted again, also
+when a step failed. A timing summary of each step is printed at the end,
+which makes the demo usable as a smoke test of a subscription or region.
+
+Resource names are derived from --prefix, the demo refuses to run when an
+account with the same name already exists.`,
+	Example: `  go-anf demo -g myrg -l westeurope --subnet-id <subnetID> --cleanup
+  go-anf demo -g myrg -l eastus2 --subnet-id <subnetID> --prefix smoke --service-level Premium`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, err := requiredString(cmd, "resource-group")
+		if err != nil {
+			return err
+		}
+		location, err := requiredString(cmd, "location")
+		if err != nil {
+			return err
+		}
+		subnetID, err := requiredString(cmd, "subnet-id")
+		if err != nil {
+			return err
+		}
+		prefix, err := requiredString(cmd, "prefix")
+		if err != nil {
+			return err
+		}
+		serviceLevel, _ := cmd.Flags().GetString("service-level")
+		cleanup, _ := cmd.Flags().GetBool("cleanup")
+
+		sizes := map[string]int64{}
+		for _, name := range []string{"pool-size", "volume-size", "resize-to"} {
+			value, _ := cmd.Flags().GetString(name)
+			sizeBytes, err := utils.ParseSize(value)
+			if err != nil {
+				return invalidUsage("invalid --%v: %v", name, err)
+			}
+			sizes[name] = sizeBytes
+		}
+		if sizes["pool-size"]%poolSizeIncrement != 0 {
+			return invalidUsage("invalid --pool-size, capacity pools are sized in whole TiB")
+		}
+		if sizes["volume-size"]%sdkutils.VolumeQuotaIncrement != 0 || sizes["resize-to"]%sdkutils.VolumeQuotaIncrement != 0 {
+			return invalidUsage("invalid volume size, volumes are sized in whole GiB")
+		}
+		// Three volumes are created and one of them is resized afterwards
+		if 2*sizes["volume-size"]+sizes["resize-to"] > sizes["pool-size"] {
+			return invalidUsage("the demo volumes do not fit into a %v capacity pool", utils.FormatSize(sizes["pool-size"]))
+		}
+
+		accountName := prefix + "-account"
+		poolName := prefix + "-pool"
+		nfsv3VolumeName := prefix + "-nfsv3"
+		nfsv41VolumeName := prefix + "-nfsv41"
+		snapshotName := prefix + "-snapshot"
+		cloneVolumeName := prefix + "-nfsv3-clone"
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		// Only a missing account lets the demo go on, authentication or network failures are returned as is
+		if _, err := client.GetANFAccount(cmd.Context(), resourceGroupName, accountName); err == nil {
+			return fmt.Errorf("account %v already exists in resource group %v, choose another --prefix", accountName, resourceGroupName)
+		} else if !errors.Is(err, sdkutils.ErrNotFound) {
+			return err
+		}
+
+		run := &demoRun{ctx: cmd.Context(), client: client}
+		var snapshotID string
+
+		err = run.step(fmt.Sprintf("Create account %v", accountName), func() (string, error) {
+			account, err := run.client.CreateANFAccount(run.ctx, location, resourceGroupName, accountName, nil, nil)
+			if err != nil {
+				return "", err
+			}
+			return *account.ID, nil
+		})
+
+		if err == nil {
+			err = run.step(fmt.Sprintf("Create capacity pool %v", poolName), func() (string, error) {
+				pool, err := run.client.CreateANFCapacityPool(run.ctx, location, resourceGroupName, accountName, poolName, serviceLevel, sizes["pool-size"], nil)
+				if err != nil {
+					return "", err
+				}
+				return *pool.ID, nil
+			})
+		}
+
+		for _, volume := range [][2]string{{nfsv3VolumeName, "NFSv3"}, {nfsv41VolumeName, "NFSv4.1"}} {
+			if err != nil {
+				break
+			}
+			volumeName, protocolType := volume[0], volume[1]
+			err = run.step(fmt.Sprintf("Create %v volume %v", protocolType, volumeName), func() (string, error) {
+				return run.createVolume(location, resourceGroupName, accountName, poolName, volumeName, serviceLevel, subnetID, "", protocolType, sizes["volume-size"])
+			})
+		}
+
+		if err == nil {
+			err = run.step(fmt.Sprintf("Create snapshot %v of volume %v", snapshotName, nfsv3VolumeName), func() (string, error) {
+				snapshot, err := run.client.CreateANFSnapshot(run.ctx, location, resourceGroupName, accountName, poolName, nfsv3VolumeName, snapshotName, nil)
+				if err != nil {
+					return "", err
+				}
+				snapshotID = *snapshot.ID
+				return snapshotID, nil
+			})
+		}
+
+		if err == nil {
+			err = run.step(fmt.Sprintf("Create volume %v from snapshot %v", cloneVolumeName, snapshotName), func() (string, error) {
+				return run.createVolume(location, resourceGroupName, accountName, poolName, cloneVolumeName, serviceLevel, subnetID, snapshotID, "NFSv3", sizes["volume-size"])
+			})
+		}
+
+		if err == nil {
+			err = run.step(fmt.Sprintf("Resize volume %v to %v", nfsv41VolumeName, utils.FormatSize(sizes["resize-to"])), func() (string, error) {
+				_, err := run.client.UpdateANFVolume(
+					run.ctx,
+					location,
+					resourceGroupName,
+					accountName,
+					poolName,
+					nfsv41VolumeName,
+					armnetapp.VolumePatchProperties{
+						UsageThreshold: to.Ptr(sizes["resize-to"]),
+					},
+					nil,
+				)
+				return "", err
+			})
+		}
+
+		if cleanup {
+			// Children go first, so the resources are deleted in reverse creation order
+			for i := len(run.created) - 1; i >= 0; i-- {
+				resourceID := run.created[i]
+				cleanupErr := run.step(fmt.Sprintf("Delete %v", uri.GetResourceName(resourceID)), func() (string, error) {
+					return "", run.delete(resourceID)
+				})
+				if err == nil {
+					err = cleanupErr
+				}
+			}
+		}
+
+		run.printSummary()
+
+		if err != nil {
+			return fmt.Errorf("demo failed: %w", err)
+		}
+		if !cleanup {
+			utils.ConsoleOutput(fmt.Sprintf("Demo resources were kept in account %v, run the demo with --cleanup to remove them", accountName))
+		}
+		return nil
+	},
+}
+
+// step runs a single demo step and records its duration, a step creating a
+// resource returns its ID, which is waited for and remembered for the cleanup
+func (run *demoRun) step(name string, fn func() (string, error)) error {
+	utils.ConsoleOutput(fmt.Sprintf("%v...", name))
+	start := time.Now()
+
+	resourceID, err := fn()
+	if err == nil && resourceID != "" {
+		run.created = append(run.created, resourceID)
+		err = run.client.WaitForANFResource(run.ctx, resourceID, nil)
+	}
+
+	run.steps = append(run.steps, demoStep{name: name, duration: time.Since(start), err: err})
+	if err != nil {
+		utils.ConsoleOutput(fmt.Sprintf("%v failed: %v", name, err))
+	}
+	return err
+}
+
+// createVolume creates a single protocol volume and returns its ID
+func (run *demoRun) createVolume(location, resourceGroupName, accountName, poolName, volumeName, serviceLevel, subnetID, snapshotID, protocolType string, sizeBytes int64) (string, error) {
+	volume, err := run.client.CreateANFVolume(run.ctx, sdkutils.VolumeSpec{
+		Location:       location,
+		ResourceGroup:  resourceGroupName,
+		Account:        accountName,
+		Pool:           poolName,
+		Name:           volumeName,
+		ServiceLevel:   serviceLevel,
+		SubnetID:       subnetID,
+		SnapshotID:     snapshotID,
+		ProtocolTypes:  []string{protocolType},
+		UsageThreshold: sizeBytes,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return *volume.ID, nil
+}
+
+// delete removes a resource created by the demo and waits until ARM no longer reports it
+func (run *demoRun) delete(resourceID string) error {
+	resourceGroupName := uri.GetResourceGroup(resourceID)
+	accountName := uri.GetANFAccount(resourceID)
+
+	var err error
+	switch {
+	case uri.IsANFSnapshot(resourceID):
+		err = run.client.DeleteANFSnapshot(run.ctx, resourceGroupName, accountName, uri.GetANFCapacityPool(resourceID), uri.GetANFVolume(resourceID), uri.GetANFSnapshot(resourceID))
+	case uri.IsANFVolume(resourceID):
+		err = run.client.DeleteANFVolume(run.ctx, resourceGroupName, accountName, uri.GetANFCapacityPool(resourceID), uri.GetANFVolume(resourceID))
+	case uri.IsANFCapacityPool(resourceID):
+		err = run.client.DeleteANFCapacityPool(run.ctx, resourceGroupName, accountName, uri.GetANFCapacityPool(resourceID))
+	case uri.IsANFAccount(resourceID):
+		err = run.client.DeleteANFAccount(run.ctx, resourceGroupName, accountName)
+	default:
+		return fmt.Errorf("unexpected resource %v", resourceID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return run.client.WaitForNoANFResource(run.ctx, resourceID, nil)
+}
+
+// printSummary prints the duration and result of every step
+func (run *demoRun) printSummary() {
+	var total time.Duration
+	rows := make([][]string, 0, len(run.steps)+1)
+	for _, step := range run.steps {
+		result := "OK"
+		if step.err != nil {
+			result = "FAILED"
+		}
+		total += step.duration
+		rows = append(rows, []string{step.name, step.duration.Round(time.Second).String(), result})
+	}
+	rows = append(rows, []string{"Total", total.Round(time.Second).String(), ""})
+
+	fmt.Println()
+	utils.PrintHeader("Demo summary")
+	printTable([]string{"STEP", "DURATION", "RESULT"}, rows)
+}
+
+func init() {
+	rootCmd.AddCommand(demoCmd)
+
+	addResourceGroupFlag(demoCmd)
+	demoCmd.Flags().StringP("location", "l", "", "Azure region of the demo resources")
+	demoCmd.Flags().String("subnet-id", "", "Resource ID of the subnet delegated to Microsoft.NetApp/volumes")
+	demoCmd.Flags().String("prefix", "go-anf-demo", "Prefix of the names of the demo resources")
+	demoCmd.Flags().String("service-level", "Standard", "Service level of the capacity pool and volumes")
+	demoCmd.Flags().String("pool-size", "4TiB", "Size of the capacity pool")
+	demoCmd.Flags().String("volume-size", "100GiB", "Quota of each demo volume")
+	demoCmd.Flags().String("resize-to", "200GiB", "New quota of the NFSv4.1 volume in the resize step")
+	demoCmd.Flags().Bool("cleanup", false, "Delete all demo resources at the end")
+}
@@ -0,0 +1,334 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/patrikcze/go-anf/pkg/runbook"
+	"github.com/patrikcze/go-anf/pkg/uri"
+	"github.com/patrikcze/go-anf/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// drCmd represents the dr command
+var drCmd = &cobra.Command{
+	Use:   "dr",
+	Short: "Run disaster recovery runbooks on replicated volumes",
+	Long: `Run disaster recovery runbooks on the destination volume of a
+cross-region replication: failover, failback and drill.
+
+A run first checks its pre-flight conditions, such as the mirror state
+and the lag of the replication, then executes its steps one by one. With
+--dry-run only the pre-flight checks are run and the planned steps are
+reported. Every run is saved in $HOME/.go-anf/runbooks after each check
+and step, with timestamps, so it serves as the report for auditors and
+as the checkpoint to resume an interrupted or failed run from with
+"go-anf dr resume".`,
+}
+
+// drFailoverCmd represents the dr failover command
+var drFailoverCmd = &cobra.Command{
+	Use:   "failover <volume>",
+	Short: "Fail over to the destination volume of a replication",
+	Long: `Fail over to the destination volume of a replication: break the
+replication so the destination volume becomes writable, then verify it.
+
+The pre-flight checks require a mirrored replication without a transfer
+in progress and a lag within --max-lag. When the source region is down,
+--force runs the failover even though checks failed and breaks the
+replication while a transfer is in progress.`,
+	Example: `  go-anf dr failover myvol -g myrg -a myaccount-westus -p mypool --dry-run
+  go-anf dr failover myvol -g myrg -a myaccount-westus -p mypool --max-lag 30m --report failover.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRunbook(runbook.Failover),
+}
+
+// drFailbackCmd represents the dr failback command
+var drFailbackCmd = &cobra.Command{
+	Use:   "failback <volume>",
+	Short: "Fail back from the destination volume of a replication to its source",
+	Long: `Fail back from the destination volume of a failed over replication to
+its source volume: replicate the changes made on the destination volume
+back to the source volume, break that reversed replication and restore
+the original direction of the replication.
+
+Stop writing to the destination volume before failing back, the changes
+made after the reverse transfer are lost, and mount the source volume
+again afterwards.`,
+	Example: `  go-anf dr failback myvol -g myrg -a myaccount-westus -p mypool`,
+	Args:    cobra.ExactArgs(1),
+	RunE:    runRunbook(runbook.Failback),
+}
+
+// drDrillCmd represents the dr drill command
+var drDrillCmd = &cobra.Command{
+	Use:   "drill <volume>",
+	Short: "Test the failover to the destination volume of a replication",
+	Long: `Test the failover to the destination volume of a replication: break the
+replication, verify the destination volume is writable, then resync it.
+
+The source volume stays in use during the drill. The changes made on the
+destination volume during the drill are overwritten by the resync.`,
+	Example: `  go-anf dr drill myvol -g myrg -a myaccount-westus -p mypool --report drill.json`,
+	Args:    cobra.ExactArgs(1),
+	RunE:    runRunbook(runbook.Drill),
+}
+
+// drResumeCmd represents the dr resume command
+var drResumeCmd = &cobra.Command{
+	Use:   "resume <run id>",
+	Short: "Resume an interrupted or failed run from its last completed step",
+	Long: `Resume an interrupted or failed run from its last completed step. The
+step that was interrupted or failed is run again, the pre-flight checks
+are not repeated.`,
+	Example: `  go-anf dr resume failover-20230102T150405Z`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getRunbookStore()
+		if err != nil {
+			return err
+		}
+		run, err := store.Load(args[0])
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Resuming %v run %v...", run.Runbook, run.ID))
+		return executeRun(cmd, store, run)
+	},
+}
+
+// drReportCmd represents the dr report command
+var drReportCmd = &cobra.Command{
+	Use:     "report <run id>",
+	Short:   "Show the report of a run",
+	Example: `  go-anf dr report failover-20230102T150405Z -o json`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getRunbookStore()
+		if err != nil {
+			return err
+		}
+		run, err := store.Load(args[0])
+		if err != nil {
+			return err
+		}
+
+		return printRun(run)
+	},
+}
+
+// drListCmd represents the dr list command
+var drListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List runs, the most recent first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getRunbookStore()
+		if err != nil {
+			return err
+		}
+		runs, err := store.List()
+		if err != nil {
+			return err
+		}
+
+		if !humanOutput() {
+			return printOutput(runs)
+		}
+
+		rows := make([][]string, 0, len(runs))
+		for _, run := range runs {
+			status := run.Status
+			if run.DryRun {
+				status += " (dry run)"
+			}
+			rows = append(rows, []string{
+				run.ID,
+				run.Runbook,
+				status,
+				formatTime(run.StartedAt),
+				run.Duration().Round(time.Second).String(),
+				uri.GetANFVolume(run.DestinationVolumeID),
+			})
+		}
+		printTable([]string{"ID", "RUNBOOK", "STATUS", "STARTED", "DURATION", "VOLUME"}, rows)
+		return nil
+	},
+}
+
+// getRunbookStore opens the store of the runs
+func getRunbookStore() (*runbook.Store, error) {
+	dir, err := runbook.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return runbook.Open(dir)
+}
+
+// runRunbook returns the RunE of the command starting a runbook on a volume
+func runRunbook(name string) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, err := poolScope(cmd)
+		if err != nil {
+			return err
+		}
+		maxLag, _ := cmd.Flags().GetDuration("max-lag")
+		force, _ := cmd.Flags().GetBool("force")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		volume, err := client.GetANFVolume(cmd.Context(), resourceGroupName, accountName, poolName, args[0])
+		if err != nil {
+			return err
+		}
+		run, err := runbook.NewRun(name, str(volume.ID), maxLag, force, dryRun)
+		if err != nil {
+			return err
+		}
+
+		if !dryRun && !confirm(cmd, fmt.Sprintf("Run the %v runbook on volume %v?", name, args[0])) {
+			return nil
+		}
+
+		store, err := getRunbookStore()
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Starting %v run %v on volume %v...", name, run.ID, args[0]))
+		return executeRun(cmd, store, run)
+	}
+}
+
+// executeRun executes a run until it finished or failed, then prints its report.
+// Ctrl-C stops the run after saving it, so it can be resumed.
+func executeRun(cmd *cobra.Command, store *runbook.Store, run *runbook.Run) error {
+	client, err := getClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	runner := &runbook.Runner{Client: client, Store: store, OnProgress: utils.ConsoleOutput}
+	runErr := runner.Execute(ctx, run)
+
+	if reportPath, _ := cmd.Flags().GetString("report"); reportPath != "" {
+		if err := writeRunReport(reportPath, run); err != nil {
+			return err
+		}
+	}
+	if err := printRun(run); err != nil {
+		return err
+	}
+
+	if runErr != nil && run.Status == runbook.StatusFailed && !run.DryRun && len(run.Steps) > 0 {
+		return fmt.Errorf("%w, resume with: go-anf dr resume %v", runErr, run.ID)
+	}
+	return runErr
+}
+
+// writeRunReport writes the JSON report of a run to a file
+func writeRunReport(path string, run *runbook.Run) error {
+	content, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot serialize report: %v", err)
+	}
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		return fmt.Errorf("cannot write report: %v", err)
+	}
+
+	return nil
+}
+
+func printRun(run *runbook.Run) error {
+	if !humanOutput() {
+		return printOutput(run)
+	}
+
+	utils.PrintHeader(fmt.Sprintf("%v run %v", run.Runbook, run.ID))
+
+	finished := ""
+	if run.FinishedAt != nil {
+		finished = formatTime(*run.FinishedAt)
+	}
+	fields := [][]string{
+		{"Status", run.Status},
+		{"Dry run", fmt.Sprintf("%v", run.DryRun)},
+		{"Forced", fmt.Sprintf("%v", run.Force)},
+		{"Source", run.SourceVolumeID},
+		{"Destination", run.DestinationVolumeID},
+		{"Started", formatTime(run.StartedAt)},
+		{"Finished", finished},
+		{"Duration", run.Duration().Round(time.Second).String()},
+	}
+	if run.Error != "" {
+		fields = append(fields, []string{"Error", run.Error})
+	}
+	printFields(fields)
+
+	fmt.Println()
+	checks := make([][]string, 0, len(run.Checks))
+	for _, check := range run.Checks {
+		result := "passed"
+		if !check.Passed {
+			result = "FAILED"
+		}
+		checks = append(checks, []string{check.Name, result, formatTime(check.CheckedAt), check.Detail})
+	}
+	printTable([]string{"CHECK", "RESULT", "CHECKED", "DETAIL"}, checks)
+
+	fmt.Println()
+	steps := make([][]string, 0, len(run.Steps))
+	for _, step := range run.Steps {
+		started, finished, duration := "", "", ""
+		if step.StartedAt != nil {
+			started = formatTime(*step.StartedAt)
+		}
+		if step.FinishedAt != nil {
+			finished = formatTime(*step.FinishedAt)
+			duration = step.FinishedAt.Sub(*step.StartedAt).Round(time.Second).String()
+		}
+		detail := step.Detail
+		if step.Error != "" {
+			detail = step.Error
+		}
+		steps = append(steps, []string{step.Name, step.Status, started, finished, duration, detail})
+	}
+	printTable([]string{"STEP", "STATUS", "STARTED", "FINISHED", "DURATION", "DETAIL"}, steps)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(drCmd)
+	drCmd.AddCommand(drFailoverCmd, drFailbackCmd, drDrillCmd, drResumeCmd, drReportCmd, drListCmd)
+
+	for _, cmd := range []*cobra.Command{drFailoverCmd, drFailbackCmd, drDrillCmd} {
+		addResourceGroupFlag(cmd)
+		cmd.Flags().StringP("account", "a", "", "Name of the account of the destination volume")
+		cmd.Flags().StringP("pool", "p", "", "Name of the capacity pool of the destination volume")
+		cmd.Flags().Bool("dry-run", false, "Run the pre-flight checks and report the planned steps without running them")
+		cmd.Flags().Bool("force", false, "Run the steps even though pre-flight checks failed")
+		addYesFlag(cmd)
+	}
+	for _, cmd := range []*cobra.Command{drFailoverCmd, drDrillCmd} {
+		cmd.Flags().Duration("max-lag", 0, "Largest replication lag accepted by the pre-flight checks, defaults to two replication intervals")
+	}
+	for _, cmd := range []*cobra.Command{drFailoverCmd, drFailbackCmd, drDrillCmd, drResumeCmd} {
+		cmd.Flags().String("report", "", "Also write the JSON report of the run to this file")
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/patrikcze/go-anf/pkg/emulator"
+	"github.com/patrikcze/go-anf/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// emulatorVariable points go-anf at an emulator instead of Azure
+const emulatorVariable = "GO_ANF_EMULATOR"
+
+// emulatorCmd represents the emulator command
+var emulatorCmd = &cobra.Command{
+	Use:   "emulator",
+	Short: "Run a local emulator of the Microsoft.NetApp resource provider",
+	Long: `Run an in-memory emulator of the Microsoft.NetApp resource provider:
+accounts, capacity pools, volumes, snapshots and snapshot policies. Nothing
+is persisted, the resources are gone when the emulator stops.
+
+Long-running operations behave like in Azure: they are reported through
+the Azure-AsyncOperation header, the provisioning state moves from
+Creating, Updating or Deleting to Succeeded after --operation-delay, and
+deleted resources are still returned for --cache-delay, like the ARM
+cache does.
+
+Point go-anf at the emulator by setting the GO_ANF_EMULATOR environment
+variable, or the "emulator" key of a profile, to its URL. No
+authentication file is needed, any subscription ID is accepted.`,
+	Example: `  go-anf emulator --listen 127.0.0.1:8080
+  GO_ANF_EMULATOR=http://127.0.0.1:8080 go-anf account create -g myrg -a myaccount -l westeurope
+  go-anf config set emulator http://127.0.0.1:8080 --profile local`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		listen, err := requiredString(cmd, "listen")
+		if err != nil {
+			return err
+		}
+		operationDelay, _ := cmd.Flags().GetDuration("operation-delay")
+		cacheDelay, _ := cmd.Flags().GetDuration("cache-delay")
+		verbose, _ := cmd.Flags().GetBool("verbose")
+
+		var handler http.Handler = emulator.New(emulator.Options{
+			OperationDelay: operationDelay,
+			CacheDelay:     cacheDelay,
+		})
+		if verbose {
+			handler = logRequests(handler)
+		}
+
+		server := &http.Server{Addr: listen, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = server.Shutdown(shutdown)
+		}()
+
+		utils.ConsoleOutput(fmt.Sprintf("Emulator listening on http://%v, stop it with Ctrl+C", listen))
+		utils.ConsoleOutput(fmt.Sprintf("Point go-anf at it with: export %v=http://%v", emulatorVariable, listen))
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("cannot run emulator: %v", err)
+		}
+
+		return nil
+	},
+}
+
+// statusRecorder remembers the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests prints every request served by handler with its status code and duration
+func logRequests(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(recorder, r)
+		utils.ConsoleOutput(fmt.Sprintf("%v %v %v (%v)", r.Method, r.URL.Path, recorder.status, time.Since(start).Round(time.Millisecond)))
+	})
+}
+
+func init() {
+	rootCmd.AddCommand(emulatorCmd)
+
+	emulatorCmd.Flags().String("listen", "127.0.0.1:8080", "Address to listen on")
+	emulatorCmd.Flags().Duration("operation-delay", 2*time.Second, "How long long-running operations stay in progress")
+	emulatorCmd.Flags().Duration("cache-delay", 5*time.Second, "How long deleted resources are still returned")
+	emulatorCmd.Flags().BoolP("verbose", "v", false, "Print every request")
+}
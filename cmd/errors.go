@@ -0,0 +1,112 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/patrikcze/go-anf/pkg/output"
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+)
+
+// Exit codes, documented in the help of the root command
+const (
+	exitError           = 1
+	exitInvalidArgument = 2
+	exitUnauthorized    = 3
+	exitNotFound        = 4
+	exitConflict        = 5
+	exitQuotaExceeded   = 6
+	exitInvalidSubnet   = 7
+	exitThrottled       = 8
+	exitTimeout         = 9
+	exitServiceError    = 10
+)
+
+// errorKinds maps error kinds to their name and exit code, the first matching kind wins
+var errorKinds = []struct {
+	kind     error
+	name     string
+	exitCode int
+}{
+	{errUsage, "InvalidArgument", exitInvalidArgument},
+	{sdkutils.ErrInvalidSubnet, "InvalidSubnet", exitInvalidSubnet},
+	{sdkutils.ErrInvalidArgument, "InvalidArgument", exitInvalidArgument},
+	{sdkutils.ErrUnauthorized, "Unauthorized", exitUnauthorized},
+	{sdkutils.ErrNotFound, "NotFound", exitNotFound},
+	{sdkutils.ErrConflict, "Conflict", exitConflict},
+	{sdkutils.ErrQuotaExceeded, "QuotaExceeded", exitQuotaExceeded},
+	{sdkutils.ErrThrottled, "Throttled", exitThrottled},
+	{sdkutils.ErrTimeout, "Timeout", exitTimeout},
+	{context.DeadlineExceeded, "Timeout", exitTimeout},
+	{sdkutils.ErrServiceUnavailable, "ServiceUnavailable", exitServiceError},
+}
+
+// errUsage is the kind of the errors of invalid command lines: unknown commands and flags, wrong arguments
+var errUsage = errors.New("invalid usage")
+
+// usageError marks an error of the command line
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e usageError) Is(target error) bool {
+	return target == errUsage
+}
+
+func (e usageError) Unwrap() error {
+	return e.err
+}
+
+// invalidUsage returns an error of the command line found by a command itself, e.g. a missing or invalid flag
+func invalidUsage(format string, a ...interface{}) error {
+	return usageError{fmt.Errorf(format, a...)}
+}
+
+// errorObject is the error printed with --output json
+type errorObject struct {
+	Kind     string `json:"kind"`
+	ExitCode int    `json:"exitCode"`
+	Message  string `json:"message"`
+	*sdkutils.Error
+}
+
+// errorKind returns the name and the exit code of an error
+func errorKind(err error) (string, int) {
+	for _, errorKind := range errorKinds {
+		if errors.Is(err, errorKind.kind) {
+			return errorKind.name, errorKind.exitCode
+		}
+	}
+	return "Error", exitError
+}
+
+// exitWithError prints an error, as a JSON object with --output json, and exits with the code of its kind
+func exitWithError(err error) {
+	kind, exitCode := errorKind(err)
+
+	if outputFormat != output.FormatJSON {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(exitCode)
+	}
+
+	object := errorObject{Kind: kind, ExitCode: exitCode}
+	errors.As(err, &object.Error)
+	// The message of the whole chain, e.g. "cannot create volume: ...", wins over the one of the Azure error
+	object.Message = err.Error()
+
+	encoder := json.NewEncoder(os.Stderr)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(map[string]errorObject{"error": object})
+	os.Exit(exitCode)
+}
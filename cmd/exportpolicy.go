@@ -0,0 +1,350 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+	"github.com/patrikcze/go-anf/pkg/utils"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// exportPolicyCmd represents the volume export-policy command
+var exportPolicyCmd = &cobra.Command{
+	Use:   "export-policy",
+	Short: "Manage the export policy of a NFS volume",
+	Long: `List, add, remove and replace the export rules of a NFS volume.
+
+Rules are evaluated in the order of their index, starting at 1, and the
+first rule matching a client applies. Adding a rule at an index moves the
+following rules down, removing one moves them up. An export policy holds
+at most five rules.
+
+Allowed clients are a comma separated list of IP addresses, CIDR ranges
+and host names. The security flavors are the ones of an NFS export: sys
+for AUTH_SYS, krb5, krb5i and krb5p for Kerberos with authentication,
+integrity or privacy, Kerberos requires NFSv4.1 and a Kerberos enabled
+volume.`,
+}
+
+// exportPolicyListCmd represents the volume export-policy list command
+var exportPolicyListCmd = &cobra.Command{
+	Use:   "list <volume>",
+	Short: "List the export rules of a volume",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, err := poolScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		volume, err := client.GetANFVolume(cmd.Context(), resourceGroupName, accountName, poolName, args[0])
+		if err != nil {
+			return err
+		}
+
+		return printExportRules(sdkutils.NewExportPolicyBuilder(volume).Rules())
+	},
+}
+
+// exportPolicyAddCmd represents the volume export-policy add command
+var exportPolicyAddCmd = &cobra.Command{
+	Use:   "add <volume>",
+	Short: "Add an export rule to a volume",
+	Example: `  go-anf volume export-policy add myvol -g myrg -a myaccount -p mypool --allowed-clients 10.0.0.0/24
+  go-anf volume export-policy add myvol -g myrg -a myaccount -p mypool --allowed-clients 10.0.1.10,build01 --index 1 --read-only --root-access=false
+  go-anf volume export-policy add myvol -g myrg -a myaccount -p mypool --allowed-clients 10.0.2.0/24 --protocol NFSv4.1 --security krb5p --chown-mode Unrestricted`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, err := poolScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		volume, err := client.GetANFVolume(cmd.Context(), resourceGroupName, accountName, poolName, args[0])
+		if err != nil {
+			return err
+		}
+
+		rule, err := exportRuleFromFlags(cmd, volume)
+		if err != nil {
+			return err
+		}
+		builder := sdkutils.NewExportPolicyBuilder(volume)
+		if err := builder.Add(rule); err != nil {
+			return err
+		}
+
+		return updateExportPolicy(cmd, volume, builder)
+	},
+}
+
+// exportPolicyRemoveCmd represents the volume export-policy remove command
+var exportPolicyRemoveCmd = &cobra.Command{
+	Use:   "remove <volume> <index>",
+	Short: "Remove an export rule from a volume",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, err := poolScope(cmd)
+		if err != nil {
+			return err
+		}
+		ruleIndex, err := strconv.ParseInt(args[1], 10, 32)
+		if err != nil {
+			return invalidUsage("invalid rule index %v", args[1])
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		volume, err := client.GetANFVolume(cmd.Context(), resourceGroupName, accountName, poolName, args[0])
+		if err != nil {
+			return err
+		}
+
+		builder := sdkutils.NewExportPolicyBuilder(volume)
+		if err := builder.Remove(int32(ruleIndex)); err != nil {
+			return err
+		}
+
+		if !confirm(cmd, fmt.Sprintf("Remove export rule %v of volume %v? Its clients may lose access to the volume", ruleIndex, args[0])) {
+			return nil
+		}
+
+		return updateExportPolicy(cmd, volume, builder)
+	},
+}
+
+// exportPolicyReplaceCmd represents the volume export-policy replace command
+var exportPolicyReplaceCmd = &cobra.Command{
+	Use:   "replace <volume>",
+	Short: "Replace all export rules of a volume",
+	Long: `Replace all export rules of a volume, either by the single rule described
+by the flags or by the rules of a YAML or JSON file, noAccess denies the
+clients of a rule, e.g. to exclude them from a broader rule after it:
+
+  - allowedClients: 10.0.0.13
+    nfsv3: true
+    noAccess: true
+  - allowedClients: 10.0.0.0/24
+    nfsv3: true
+  - allowedClients: 10.0.1.0/24,build01
+    nfsv41: true
+    readOnly: true
+    rootAccess: false
+    chownMode: Unrestricted
+    security: [krb5, krb5p]`,
+	Example: `  go-anf volume export-policy replace myvol -g myrg -a myaccount -p mypool --allowed-clients 10.0.0.0/16
+  go-anf volume export-policy replace myvol -g myrg -a myaccount -p mypool --file rules.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, err := poolScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		volume, err := client.GetANFVolume(cmd.Context(), resourceGroupName, accountName, poolName, args[0])
+		if err != nil {
+			return err
+		}
+
+		var rules []sdkutils.ExportRule
+		if file, _ := cmd.Flags().GetString("file"); file != "" {
+			if rules, err = readExportRules(file); err != nil {
+				return err
+			}
+		} else {
+			rule, err := exportRuleFromFlags(cmd, volume)
+			if err != nil {
+				return err
+			}
+			rules = []sdkutils.ExportRule{rule}
+		}
+
+		builder := sdkutils.NewExportPolicyBuilder(volume)
+		currentRules := len(builder.Rules())
+		if err := builder.Replace(rules); err != nil {
+			return err
+		}
+
+		if !confirm(cmd, fmt.Sprintf("Replace the %v export rules of volume %v with %v rules?", currentRules, args[0], len(rules))) {
+			return nil
+		}
+
+		return updateExportPolicy(cmd, volume, builder)
+	},
+}
+
+// exportRuleFromFlags builds an export rule from the rule flags, the rule allows the NFS versions of the
+// volume unless --protocol is given and also allows CIFS on a dual protocol volume
+func exportRuleFromFlags(cmd *cobra.Command, volume *armnetapp.Volume) (sdkutils.ExportRule, error) {
+	allowedClients, err := requiredString(cmd, "allowed-clients")
+	if err != nil {
+		return sdkutils.ExportRule{}, err
+	}
+
+	volumeProtocols := volumeProtocolTypes(volume)
+	protocolTypes := volumeProtocols
+	if cmd.Flags().Changed("protocol") {
+		if protocolTypes, err = protocolTypesFromFlag(cmd); err != nil {
+			return sdkutils.ExportRule{}, err
+		}
+	}
+
+	rule := sdkutils.ExportRule{
+		AllowedClients: allowedClients,
+		NFSv3:          utils.Contains(protocolTypes, "NFSv3"),
+		NFSv41:         utils.Contains(protocolTypes, "NFSv4.1"),
+		CIFS:           utils.Contains(volumeProtocols, "CIFS"),
+	}
+	rule.RuleIndex, _ = cmd.Flags().GetInt32("index")
+	rule.ReadOnly, _ = cmd.Flags().GetBool("read-only")
+	rootAccess, _ := cmd.Flags().GetBool("root-access")
+	rule.RootAccess = to.Ptr(rootAccess)
+	rule.ChownMode, _ = cmd.Flags().GetString("chown-mode")
+	rule.Security, _ = cmd.Flags().GetStringSlice("security")
+
+	return rule, nil
+}
+
+// readExportRules reads a list of export rules from a YAML or JSON file, unknown fields are rejected
+func readExportRules(path string) ([]sdkutils.ExportRule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read export rules file: %v", err)
+	}
+
+	rules := []sdkutils.ExportRule{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("cannot parse export rules file %v: %v", path, err)
+	}
+
+	return rules, nil
+}
+
+// updateExportPolicy applies the rules of a builder to a volume and prints the resulting rules
+func updateExportPolicy(cmd *cobra.Command, volume *armnetapp.Volume, builder *sdkutils.ExportPolicyBuilder) error {
+	resourceGroupName, accountName, poolName, err := poolScope(cmd)
+	if err != nil {
+		return err
+	}
+	volumeName := resourceName(volume.Name)
+
+	client, err := getClient()
+	if err != nil {
+		return err
+	}
+
+	utils.ConsoleOutput(fmt.Sprintf("Updating export policy of volume %v...", volumeName))
+	if noWait(cmd) {
+		return printStartedOperation(client.BeginUpdateANFVolume(cmd.Context(), str(volume.Location), resourceGroupName, accountName, poolName, volumeName, builder.Patch(), nil))
+	}
+	updated, err := client.UpdateANFVolume(cmd.Context(), str(volume.Location), resourceGroupName, accountName, poolName, volumeName, builder.Patch(), nil)
+	if err != nil {
+		return err
+	}
+
+	return printExportRules(sdkutils.NewExportPolicyBuilder(updated).Rules())
+}
+
+func printExportRules(rules []sdkutils.ExportRule) error {
+	if !humanOutput() {
+		return printOutput(rules)
+	}
+
+	rows := make([][]string, 0, len(rules))
+	for _, rule := range rules {
+		protocols := []string{}
+		if rule.NFSv3 {
+			protocols = append(protocols, "NFSv3")
+		}
+		if rule.NFSv41 {
+			protocols = append(protocols, "NFSv4.1")
+		}
+		if rule.CIFS {
+			protocols = append(protocols, "CIFS")
+		}
+		access := "rw"
+		switch {
+		case rule.NoAccess:
+			access = "none"
+		case rule.ReadOnly:
+			access = "ro"
+		}
+
+		rows = append(rows, []string{
+			fmt.Sprint(rule.RuleIndex),
+			rule.AllowedClients,
+			strings.Join(protocols, ","),
+			access,
+			fmt.Sprint(rule.HasRootAccess()),
+			rule.ChownMode,
+			strings.Join(rule.SecurityFlavors(), ","),
+		})
+	}
+	printTable([]string{"INDEX", "ALLOWED CLIENTS", "PROTOCOLS", "ACCESS", "ROOT ACCESS", "CHOWN MODE", "SECURITY"}, rows)
+	return nil
+}
+
+// addExportRuleFlags adds the flags describing a single export rule
+func addExportRuleFlags(cmd *cobra.Command) {
+	cmd.Flags().String("allowed-clients", "", "Comma separated IP addresses, CIDR ranges and host names allowed to mount the volume")
+	cmd.Flags().StringSlice("protocol", nil, "NFS versions allowed by the rule: NFSv3 or NFSv4.1, defaults to the protocols of the volume")
+	cmd.Flags().Bool("read-only", false, "Allow read only access instead of read write")
+	cmd.Flags().Bool("root-access", true, "Let the root user of the clients act as root on the volume")
+	cmd.Flags().String("chown-mode", "", "Who can change file ownership: Restricted (root only) or Unrestricted (file owners too)")
+	cmd.Flags().StringSlice("security", nil, "Security flavors of the rule: sys, krb5, krb5i or krb5p, defaults to sys")
+}
+
+func init() {
+	volumeCmd.AddCommand(exportPolicyCmd)
+	exportPolicyCmd.AddCommand(exportPolicyListCmd, exportPolicyAddCmd, exportPolicyRemoveCmd, exportPolicyReplaceCmd)
+
+	for _, cmd := range []*cobra.Command{exportPolicyListCmd, exportPolicyAddCmd, exportPolicyRemoveCmd, exportPolicyReplaceCmd} {
+		addResourceGroupFlag(cmd)
+		cmd.Flags().StringP("account", "a", "", "Name of the account")
+		cmd.Flags().StringP("pool", "p", "", "Name of the capacity pool")
+	}
+
+	addExportRuleFlags(exportPolicyAddCmd)
+	exportPolicyAddCmd.Flags().Int32("index", 0, "Index to insert the rule at, the following rules move down, defaults to after the last rule")
+
+	addExportRuleFlags(exportPolicyReplaceCmd)
+	exportPolicyReplaceCmd.Flags().StringP("file", "f", "", "YAML or JSON file with the list of export rules")
+
+	for _, cmd := range []*cobra.Command{exportPolicyRemoveCmd, exportPolicyReplaceCmd} {
+		addYesFlag(cmd)
+	}
+	for _, cmd := range []*cobra.Command{exportPolicyAddCmd, exportPolicyRemoveCmd, exportPolicyReplaceCmd} {
+		addNoWaitFlag(cmd)
+	}
+}
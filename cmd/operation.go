@@ -0,0 +1,273 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/patrikcze/go-anf/pkg/journal"
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+	"github.com/patrikcze/go-anf/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// operationJournal is opened on first use and shared by all commands
+var operationJournal *journal.Journal
+
+// getJournal returns the journal of the long-running operations
+func getJournal() (*journal.Journal, error) {
+	if operationJournal == nil {
+		dir, err := journal.DefaultDir()
+		if err != nil {
+			return nil, err
+		}
+		j, err := journal.Open(dir)
+		if err != nil {
+			return nil, err
+		}
+		operationJournal = j
+	}
+
+	return operationJournal, nil
+}
+
+// operationCmd represents the operation command
+var operationCmd = &cobra.Command{
+	Use:   "operation",
+	Short: "Inspect and resume long-running operations",
+	Long: `Every long-running operation started by go-anf, such as creating or
+deleting a volume, is recorded in a journal in $HOME/.go-anf/operations
+together with the token needed to re-attach to it. When go-anf is
+interrupted while waiting, the operation keeps running in Azure and can
+be picked up again with "go-anf operation wait" or "go-anf operation resume".
+
+Finished operations are kept in the journal for seven days.`,
+}
+
+// operationListCmd represents the operation list command
+var operationListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recorded operations, the most recent first",
+	Long: `List recorded operations, the most recent first. The status of the
+operations still in progress is refreshed from Azure.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		j, err := getJournal()
+		if err != nil {
+			return err
+		}
+		operations, err := j.List()
+		if err != nil {
+			return err
+		}
+
+		if inProgress, _ := cmd.Flags().GetBool("in-progress"); inProgress {
+			filtered := []*sdkutils.Operation{}
+			for _, operation := range operations {
+				if operation.Status == sdkutils.OperationInProgress {
+					filtered = append(filtered, operation)
+				}
+			}
+			operations = filtered
+		}
+
+		for _, operation := range operations {
+			if operation.Status != sdkutils.OperationInProgress {
+				continue
+			}
+			if err := refreshOperation(cmd.Context(), operation); err != nil {
+				utils.ConsoleOutput(fmt.Sprintf("Warning: %v", err))
+			}
+		}
+
+		if !humanOutput() {
+			return printOutput(operations)
+		}
+
+		rows := make([][]string, 0, len(operations))
+		for _, operation := range operations {
+			rows = append(rows, []string{
+				operation.ID,
+				operation.Type,
+				operation.Status,
+				formatTime(operation.StartedAt),
+				operationDuration(operation),
+				operationResource(operation),
+			})
+		}
+		printTable([]string{"ID", "TYPE", "STATUS", "STARTED", "DURATION", "RESOURCE"}, rows)
+		return nil
+	},
+}
+
+// operationShowCmd represents the operation show command
+var operationShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show an operation, refreshing its status from Azure",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		operation, err := loadOperation(args[0])
+		if err != nil {
+			return err
+		}
+
+		if err := refreshOperation(cmd.Context(), operation); err != nil {
+			return err
+		}
+
+		return printOperation(operation)
+	},
+}
+
+// operationWaitCmd represents the operation wait command
+var operationWaitCmd = &cobra.Command{
+	Use:   "wait <id>",
+	Short: "Re-attach to an operation and wait until it finished",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		operation, err := loadOperation(args[0])
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Waiting for %v operation %v...", operation.Type, operation.ID))
+		if err := client.WaitForOperation(cmd.Context(), operation); err != nil {
+			return err
+		}
+
+		return printOperation(operation)
+	},
+}
+
+// operationResumeCmd represents the operation resume command
+var operationResumeCmd = &cobra.Command{
+	Use:   "resume <id>",
+	Short: "Re-attach to an operation and finish what the interrupted command was doing",
+	Long: `Re-attach to an operation and wait until it finished, then wait for its
+resource to reach the state the operation leads to, like the interrupted
+command would have: a created or updated resource to be ready, a deleted
+one to be gone.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		operation, err := loadOperation(args[0])
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Resuming %v operation %v on %v...", operation.Type, operation.ID, operationResource(operation)))
+		if err := client.ResumeOperation(cmd.Context(), operation, nil); err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Operation %v completed", operation.ID))
+		return printOperation(operation)
+	},
+}
+
+// loadOperation reads an operation from the journal
+func loadOperation(id string) (*sdkutils.Operation, error) {
+	j, err := getJournal()
+	if err != nil {
+		return nil, err
+	}
+
+	return j.Load(id)
+}
+
+// refreshOperation checks an operation in progress once, the client records the outcome in the journal
+func refreshOperation(ctx context.Context, operation *sdkutils.Operation) error {
+	if operation.Status != sdkutils.OperationInProgress {
+		return nil
+	}
+
+	client, err := getClient()
+	if err != nil {
+		return err
+	}
+
+	return client.RefreshOperation(ctx, operation)
+}
+
+// operationResource returns the resource of an operation without its subscription and resource group prefix
+func operationResource(operation *sdkutils.Operation) string {
+	if index := strings.Index(operation.ResourceID, "/netAppAccounts/"); index >= 0 {
+		return strings.TrimPrefix(operation.ResourceID[index:], "/netAppAccounts/")
+	}
+	return operation.ResourceID
+}
+
+// operationDuration returns how long an operation ran, or has been running
+func operationDuration(operation *sdkutils.Operation) string {
+	end := time.Now()
+	if operation.FinishedAt != nil {
+		end = *operation.FinishedAt
+	}
+	return end.Sub(operation.StartedAt).Round(time.Second).String()
+}
+
+// printStartedOperation prints the operation returned by a Begin call of a command run with --no-wait
+func printStartedOperation(operation *sdkutils.Operation, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if !humanOutput() {
+		return printOutput(operation)
+	}
+
+	printFields([][]string{
+		{"Operation", operation.ID},
+		{"Resource", operation.ResourceID},
+		{"Status", operation.Status},
+	})
+	if operation.Status == sdkutils.OperationInProgress {
+		utils.ConsoleOutput(fmt.Sprintf("Wait for it with: go-anf operation wait %v", operation.ID))
+	}
+	return nil
+}
+
+func printOperation(operation *sdkutils.Operation) error {
+	if !humanOutput() {
+		return printOutput(operation)
+	}
+
+	utils.PrintHeader(fmt.Sprintf("Operation %v", operation.ID))
+
+	finished := ""
+	if operation.FinishedAt != nil {
+		finished = formatTime(*operation.FinishedAt)
+	}
+
+	printFields([][]string{
+		{"Type", operation.Type},
+		{"Status", operation.Status},
+		{"Resource", operation.ResourceID},
+		{"Started", formatTime(operation.StartedAt)},
+		{"Finished", finished},
+		{"Duration", operationDuration(operation)},
+		{"Error", operation.Error},
+	})
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(operationCmd)
+	operationCmd.AddCommand(operationListCmd, operationShowCmd, operationWaitCmd, operationResumeCmd)
+
+	operationListCmd.Flags().Bool("in-progress", false, "Only list operations still in progress")
+}
@@ -0,0 +1,109 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/patrikcze/go-anf/pkg/topology"
+	"github.com/patrikcze/go-anf/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// planCmd represents the plan command
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show the changes apply would make for a topology file",
+	Long: `Read the live resources of the resource group and show, field by
+field, what "go-anf apply" would create, update and delete to match the
+topology file. Nothing is changed.
+
+With --out the plan is saved together with the topology. Running
+"go-anf apply <plan-file>" later applies exactly the reviewed changes,
+and refuses to run when the live resources changed in the meantime.`,
+	Example: `  go-anf plan -f topology.yaml
+  go-anf plan -f topology.yaml --prune --out plan.out
+  go-anf apply plan.out`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		spec, err := loadTopology(cmd)
+		if err != nil {
+			return err
+		}
+		prune, _ := cmd.Flags().GetBool("prune")
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Comparing topology with resource group %v...", spec.ResourceGroup))
+		plan, err := topology.NewPlan(cmd.Context(), client, spec, prune)
+		if err != nil {
+			return err
+		}
+
+		if err := printPlan(plan); err != nil {
+			return err
+		}
+
+		if out, _ := cmd.Flags().GetString("out"); out != "" {
+			if err := plan.Save(out, spec); err != nil {
+				return err
+			}
+			utils.ConsoleOutput(fmt.Sprintf("Plan saved to %v, run \"go-anf apply %v\" to apply it", out, out))
+		}
+
+		return nil
+	},
+}
+
+// printPlan prints every action with its field changes followed by a summary
+func printPlan(plan *topology.Plan) error {
+	if !humanOutput() {
+		return printOutput(plan)
+	}
+
+	if len(plan.Actions) == 0 {
+		fmt.Println("No changes, the resources match the topology.")
+		return nil
+	}
+
+	symbols := map[string]string{
+		topology.OperationCreate: "+",
+		topology.OperationUpdate: "~",
+		topology.OperationDelete: "-",
+	}
+	orNone := func(value string) string {
+		if value == "" {
+			return "(none)"
+		}
+		return value
+	}
+
+	for _, action := range plan.Actions {
+		fmt.Printf("%v %v %v %v\n", symbols[action.Operation], action.Operation, action.Kind, action.Address())
+		for _, change := range action.Changes {
+			if action.Operation == topology.OperationCreate {
+				fmt.Printf("      %v: %v\n", change.Field, orNone(change.Desired))
+			} else {
+				fmt.Printf("      %v: %v -> %v\n", change.Field, orNone(change.Current), orNone(change.Desired))
+			}
+		}
+	}
+
+	fmt.Printf("\nPlan: %v to create, %v to update, %v to delete.\n", plan.Count(topology.OperationCreate), plan.Count(topology.OperationUpdate), plan.Count(topology.OperationDelete))
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+
+	planCmd.Flags().StringP("file", "f", "", "Topology file in YAML or JSON format")
+	addResourceGroupFlag(planCmd)
+	planCmd.Flags().StringP("location", "l", "", "Default location, used when the topology does not set one")
+	planCmd.Flags().Bool("prune", false, "Include the deletion of resources that are not part of the topology")
+	planCmd.Flags().String("out", "", "Save the plan to this file for a later apply")
+}
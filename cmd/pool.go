@@ -0,0 +1,387 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+	"github.com/patrikcze/go-anf/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// poolSizeIncrement is the granularity capacity pools are provisioned in
+	poolSizeIncrement int64 = 1 << 40
+)
+
+// poolCmd represents the pool command
+var poolCmd = &cobra.Command{
+	Use:   "pool",
+	Short: "Manage capacity pools",
+	Long: `Create, show, list, resize, update and delete capacity pools.
+
+A capacity pool belongs to an account and provides the provisioned
+capacity and service level that its volumes consume.`,
+}
+
+// poolCreateCmd represents the pool create command
+var poolCreateCmd = &cobra.Command{
+	Use:     "create <name>",
+	Short:   "Create a capacity pool",
+	Example: `  go-anf pool create mypool -g myrg -a myaccount --service-level premium --size 4TiB`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, err := accountScope(cmd)
+		if err != nil {
+			return err
+		}
+		serviceLevel, err := requiredString(cmd, "service-level")
+		if err != nil {
+			return err
+		}
+		sizeBytes, err := poolSizeFromFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		location, _ := cmd.Flags().GetString("location")
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		if location == "" {
+			account, err := client.GetANFAccount(cmd.Context(), resourceGroupName, accountName)
+			if err != nil {
+				return err
+			}
+			location = str(account.Location)
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Creating capacity pool %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginCreateANFCapacityPool(cmd.Context(), location, resourceGroupName, accountName, args[0], serviceLevel, sizeBytes, tagsFromFlag(cmd)))
+		}
+		pool, err := client.CreateANFCapacityPool(cmd.Context(), location, resourceGroupName, accountName, args[0], serviceLevel, sizeBytes, tagsFromFlag(cmd))
+		if err != nil {
+			return err
+		}
+
+		return printPool(cmd.Context(), client, resourceGroupName, accountName, pool)
+	},
+}
+
+// poolShowCmd represents the pool show command
+var poolShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show a capacity pool and its allocated and free capacity",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, err := accountScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		pool, err := client.GetANFCapacityPool(cmd.Context(), resourceGroupName, accountName, args[0])
+		if err != nil {
+			return err
+		}
+
+		return printPool(cmd.Context(), client, resourceGroupName, accountName, pool)
+	},
+}
+
+// poolListCmd represents the pool list command
+var poolListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List capacity pools of an account",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, err := accountScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		pools, err := client.ListANFCapacityPools(cmd.Context(), resourceGroupName, accountName)
+		if err != nil {
+			return err
+		}
+
+		if !humanOutput() {
+			return printOutput(pools)
+		}
+
+		rows := make([][]string, 0, len(pools))
+		for _, pool := range pools {
+			poolName := resourceName(pool.Name)
+			allocated, err := poolAllocatedBytes(cmd.Context(), client, resourceGroupName, accountName, poolName)
+			if err != nil {
+				return err
+			}
+			size := poolSizeBytes(pool)
+			rows = append(rows, []string{
+				poolName,
+				poolServiceLevel(pool),
+				utils.FormatSize(size),
+				utils.FormatSize(allocated),
+				utils.FormatSize(size - allocated),
+				poolProvisioningState(pool),
+			})
+		}
+		printTable([]string{"NAME", "SERVICE LEVEL", "SIZE", "ALLOCATED", "FREE", "STATE"}, rows)
+		return nil
+	},
+}
+
+// poolResizeCmd represents the pool resize command
+var poolResizeCmd = &cobra.Command{
+	Use:     "resize <name>",
+	Short:   "Change the provisioned size of a capacity pool",
+	Example: `  go-anf pool resize mypool -g myrg -a myaccount --size 8TiB`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, err := accountScope(cmd)
+		if err != nil {
+			return err
+		}
+		sizeBytes, err := poolSizeFromFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		allocated, err := poolAllocatedBytes(cmd.Context(), client, resourceGroupName, accountName, args[0])
+		if err != nil {
+			return err
+		}
+		if sizeBytes < allocated {
+			return fmt.Errorf("cannot resize pool to %v, its volumes already allocate %v", utils.FormatSize(sizeBytes), utils.FormatSize(allocated))
+		}
+
+		return updatePool(cmd, client, resourceGroupName, accountName, args[0], armnetapp.PoolPatchProperties{
+			Size: to.Ptr(sizeBytes),
+		})
+	},
+}
+
+// poolUpdateCmd represents the pool update command
+var poolUpdateCmd = &cobra.Command{
+	Use:   "update <name>",
+	Short: "Update the QoS type or tags of a capacity pool",
+	Long: `Update the QoS type or tags of a capacity pool.
+
+The service level of a capacity pool cannot be changed by an update,
+Azure rejects it. To change the service level of volumes, create a pool
+with the target service level and move the volumes into it.`,
+	Example: `  go-anf pool update mypool -g myrg -a myaccount --qos-type manual
+  go-anf pool update mypool -g myrg -a myaccount --tags env=prod`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, err := accountScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		poolPatch := armnetapp.PoolPatchProperties{}
+
+		if qosType, _ := cmd.Flags().GetString("qos-type"); qosType != "" {
+			switch strings.ToLower(qosType) {
+			case "auto":
+				poolPatch.QosType = to.Ptr(armnetapp.QosTypeAuto)
+			case "manual":
+				poolPatch.QosType = to.Ptr(armnetapp.QosTypeManual)
+			default:
+				return invalidUsage("invalid qos type, supported qos types are: %v", armnetapp.PossibleQosTypeValues())
+			}
+		}
+
+		if poolPatch.QosType == nil && !cmd.Flags().Changed("tags") {
+			return invalidUsage("nothing to update, use --qos-type or --tags")
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		return updatePool(cmd, client, resourceGroupName, accountName, args[0], poolPatch)
+	},
+}
+
+// poolDeleteCmd represents the pool delete command
+var poolDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a capacity pool",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, err := accountScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		if !confirm(cmd, fmt.Sprintf("Delete capacity pool %v in account %v?", args[0], accountName)) {
+			return nil
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Deleting capacity pool %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginDeleteANFCapacityPool(cmd.Context(), resourceGroupName, accountName, args[0]))
+		}
+		return client.DeleteANFCapacityPool(cmd.Context(), resourceGroupName, accountName, args[0])
+	},
+}
+
+func updatePool(cmd *cobra.Command, client *sdkutils.Client, resourceGroupName, accountName, poolName string, poolPatch armnetapp.PoolPatchProperties) error {
+	current, err := client.GetANFCapacityPool(cmd.Context(), resourceGroupName, accountName, poolName)
+	if err != nil {
+		return err
+	}
+
+	utils.ConsoleOutput(fmt.Sprintf("Updating capacity pool %v...", poolName))
+	if noWait(cmd) {
+		return printStartedOperation(client.BeginUpdateANFCapacityPool(cmd.Context(), str(current.Location), resourceGroupName, accountName, poolName, poolPatch, tagsFromFlag(cmd)))
+	}
+	pool, err := client.UpdateANFCapacityPool(cmd.Context(), str(current.Location), resourceGroupName, accountName, poolName, poolPatch, tagsFromFlag(cmd))
+	if err != nil {
+		return err
+	}
+
+	return printPool(cmd.Context(), client, resourceGroupName, accountName, pool)
+}
+
+func poolSizeFromFlag(cmd *cobra.Command) (int64, error) {
+	size, err := requiredString(cmd, "size")
+	if err != nil {
+		return 0, err
+	}
+
+	sizeBytes, err := utils.ParseSize(size)
+	if err != nil {
+		return 0, usageError{err}
+	}
+	if sizeBytes == 0 || sizeBytes%poolSizeIncrement != 0 {
+		return 0, invalidUsage("invalid pool size %v, capacity pools are sized in whole TiB", size)
+	}
+
+	return sizeBytes, nil
+}
+
+// poolAllocatedBytes sums the quota of every volume in the pool
+func poolAllocatedBytes(ctx context.Context, client *sdkutils.Client, resourceGroupName, accountName, poolName string) (int64, error) {
+	volumes, err := client.ListANFVolumes(ctx, resourceGroupName, accountName, poolName)
+	if err != nil {
+		return 0, err
+	}
+
+	var allocated int64
+	for _, volume := range volumes {
+		if volume.Properties != nil && volume.Properties.UsageThreshold != nil {
+			allocated += *volume.Properties.UsageThreshold
+		}
+	}
+	return allocated, nil
+}
+
+func poolSizeBytes(pool *armnetapp.CapacityPool) int64 {
+	if pool.Properties == nil || pool.Properties.Size == nil {
+		return 0
+	}
+	return *pool.Properties.Size
+}
+
+func poolServiceLevel(pool *armnetapp.CapacityPool) string {
+	if pool.Properties == nil || pool.Properties.ServiceLevel == nil {
+		return ""
+	}
+	return string(*pool.Properties.ServiceLevel)
+}
+
+func poolProvisioningState(pool *armnetapp.CapacityPool) string {
+	if pool.Properties == nil {
+		return ""
+	}
+	return str(pool.Properties.ProvisioningState)
+}
+
+func printPool(ctx context.Context, client *sdkutils.Client, resourceGroupName, accountName string, pool *armnetapp.CapacityPool) error {
+	if !humanOutput() {
+		return printOutput(pool)
+	}
+
+	allocated, err := poolAllocatedBytes(ctx, client, resourceGroupName, accountName, resourceName(pool.Name))
+	if err != nil {
+		return err
+	}
+	size := poolSizeBytes(pool)
+
+	qosType := ""
+	if pool.Properties != nil && pool.Properties.QosType != nil {
+		qosType = string(*pool.Properties.QosType)
+	}
+
+	utils.PrintHeader(fmt.Sprintf("Capacity pool %v", resourceName(pool.Name)))
+	printFields([][]string{
+		{"ID", str(pool.ID)},
+		{"Location", str(pool.Location)},
+		{"Service level", poolServiceLevel(pool)},
+		{"QoS type", qosType},
+		{"Size", utils.FormatSize(size)},
+		{"Allocated", utils.FormatSize(allocated)},
+		{"Free", utils.FormatSize(size - allocated)},
+		{"Provisioning state", poolProvisioningState(pool)},
+		{"Tags", formatTags(pool.Tags)},
+	})
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(poolCmd)
+	poolCmd.AddCommand(poolCreateCmd, poolShowCmd, poolListCmd, poolResizeCmd, poolUpdateCmd, poolDeleteCmd)
+
+	for _, cmd := range []*cobra.Command{poolCreateCmd, poolShowCmd, poolListCmd, poolResizeCmd, poolUpdateCmd, poolDeleteCmd} {
+		addResourceGroupFlag(cmd)
+		cmd.Flags().StringP("account", "a", "", "Name of the account")
+	}
+
+	poolCreateCmd.Flags().StringP("location", "l", "", "Azure region of the pool, defaults to the account location")
+	poolCreateCmd.Flags().String("service-level", "", "Service level of the pool: standard, premium or ultra")
+	poolCreateCmd.Flags().String("size", "", "Provisioned size of the pool in whole TiB, e.g. 4TiB")
+	addTagsFlag(poolCreateCmd)
+
+	poolResizeCmd.Flags().String("size", "", "New provisioned size of the pool in whole TiB, e.g. 8TiB")
+
+	poolUpdateCmd.Flags().String("qos-type", "", "QoS type of the pool: auto or manual")
+	addTagsFlag(poolUpdateCmd)
+
+	addYesFlag(poolDeleteCmd)
+
+	for _, cmd := range []*cobra.Command{poolCreateCmd, poolResizeCmd, poolUpdateCmd, poolDeleteCmd} {
+		addNoWaitFlag(cmd)
+	}
+}
@@ -0,0 +1,574 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+	"github.com/patrikcze/go-anf/pkg/uri"
+	"github.com/patrikcze/go-anf/pkg/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// replicationCmd represents the replication command
+var replicationCmd = &cobra.Command{
+	Use:   "replication",
+	Short: "Manage cross-region replication of volumes",
+	Long: `Replicate volumes into another region, check and manage their replication.
+
+A replication copies a source volume into a read-only DataProtection
+volume in another region on a 10 minutes, hourly or daily schedule.
+Breaking the replication makes the destination volume writable,
+resyncing it resumes the replication, or reverses it when run on the
+source volume.`,
+}
+
+// replicationCreateCmd represents the replication create command
+var replicationCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Replicate a volume into another region",
+	Long: `Replicate a volume into another region and wait until the baseline
+transfer completes and the replication is mirrored.
+
+The destination account, capacity pool and volume are created when they
+do not exist. Their names default to the ones of the source volume, the
+account name suffixed with the destination region, in the resource group
+of the source volume. The destination volume gets the protocols, quota,
+security style and export policy of the source volume.
+
+Running the command again resumes an interrupted replication setup.`,
+	Example: `  go-anf replication create --source <volumeID> --dest-region westus --subnet-id <subnetID> --schedule hourly
+  go-anf replication create --source <volumeID> --dest-region westus --subnet-id <subnetID> --dest-account dr-account --pool-size 4TiB`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sourceID, err := requiredString(cmd, "source")
+		if err != nil {
+			return err
+		}
+		location, err := requiredString(cmd, "dest-region")
+		if err != nil {
+			return err
+		}
+		subnetID, err := requiredString(cmd, "subnet-id")
+		if err != nil {
+			return err
+		}
+
+		spec := sdkutils.ReplicationSpec{
+			SourceVolumeID: sourceID,
+			Location:       location,
+			SubnetID:       subnetID,
+			Tags:           tagsFromFlag(cmd),
+			OnProgress: func(message string) {
+				utils.ConsoleOutput(message)
+			},
+		}
+		spec.ResourceGroup, _ = cmd.Flags().GetString("dest-resource-group")
+		spec.Account, _ = cmd.Flags().GetString("dest-account")
+		spec.Pool, _ = cmd.Flags().GetString("dest-pool")
+		spec.Name, _ = cmd.Flags().GetString("dest-volume")
+		spec.Schedule, _ = cmd.Flags().GetString("schedule")
+		spec.ServiceLevel, _ = cmd.Flags().GetString("service-level")
+		if poolSize, _ := cmd.Flags().GetString("pool-size"); poolSize != "" {
+			if spec.PoolSize, err = utils.ParseSize(poolSize); err != nil {
+				return usageError{err}
+			}
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		volume, err := client.CreateANFReplication(cmd.Context(), spec)
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Volume %v is replicated into %v", sourceID, str(volume.ID)))
+		return printVolume(volume)
+	},
+}
+
+// replicationStatusCmd represents the replication status command
+var replicationStatusCmd = &cobra.Command{
+	Use:   "status <volume>",
+	Short: "Show the replication status of a source or destination volume",
+	Long: `Show the mirror state, the relationship status and the transferred
+data of the replication of a volume, and its lag: the time since the last
+transfer, which is the age of the data of the destination volume.`,
+	Example: `  go-anf replication status myvol -g myrg -a myaccount -p mypool`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, err := poolScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		status, err := client.GetANFVolumeReplicationStatus(cmd.Context(), resourceGroupName, accountName, poolName, args[0])
+		if err != nil {
+			return err
+		}
+
+		return printReplicationStatus(status)
+	},
+}
+
+// replicationBreakCmd represents the replication break command
+var replicationBreakCmd = &cobra.Command{
+	Use:   "break <volume>",
+	Short: "Break the replication of a destination volume",
+	Long: `Break the replication of a destination volume and wait until it is
+broken. The destination volume becomes writable and stops receiving the
+changes of the source volume.`,
+	Example: `  go-anf replication break myvol -g myrg -a myaccount-westus -p mypool`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, err := poolScope(cmd)
+		if err != nil {
+			return err
+		}
+		force, _ := cmd.Flags().GetBool("force")
+
+		if !confirm(cmd, fmt.Sprintf("Break the replication of volume %v? It stops receiving the changes of its source.", args[0])) {
+			return nil
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Breaking the replication of volume %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginBreakANFVolumeReplication(cmd.Context(), resourceGroupName, accountName, poolName, args[0], force))
+		}
+		return client.BreakANFVolumeReplication(cmd.Context(), resourceGroupName, accountName, poolName, args[0], force)
+	},
+}
+
+// replicationResyncCmd represents the replication resync command
+var replicationResyncCmd = &cobra.Command{
+	Use:   "resync <volume>",
+	Short: "Resync a broken replication",
+	Long: `Resync a broken replication and wait until it is mirrored.
+
+Run on the destination volume, the replication resumes and the changes
+made on the destination volume since the break are overwritten. Run on
+the source volume, the replication is reversed: the source volume
+becomes the destination and its changes since the break are overwritten
+with the data of the former destination volume.`,
+	Example: `  go-anf replication resync myvol -g myrg -a myaccount-westus -p mypool`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, err := poolScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		if !confirm(cmd, fmt.Sprintf("Resync the replication of volume %v? The changes made on volume %v since the break are overwritten.", args[0], args[0])) {
+			return nil
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Resyncing the replication of volume %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginResyncANFVolumeReplication(cmd.Context(), resourceGroupName, accountName, poolName, args[0]))
+		}
+		return client.ResyncANFVolumeReplication(cmd.Context(), resourceGroupName, accountName, poolName, args[0])
+	},
+}
+
+// replicationReinitCmd represents the replication reinit command
+var replicationReinitCmd = &cobra.Command{
+	Use:   "reinit <volume>",
+	Short: "Restart the baseline transfer of a destination volume",
+	Long: `Restart the baseline transfer of the replication of a destination
+volume, e.g. after it failed, and wait until it is mirrored.`,
+	Example: `  go-anf replication reinit myvol -g myrg -a myaccount-westus -p mypool`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, err := poolScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Reinitializing the replication of volume %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginReInitializeANFVolumeReplication(cmd.Context(), resourceGroupName, accountName, poolName, args[0]))
+		}
+		return client.ReInitializeANFVolumeReplication(cmd.Context(), resourceGroupName, accountName, poolName, args[0])
+	},
+}
+
+// replicationDeleteCmd represents the replication delete command
+var replicationDeleteCmd = &cobra.Command{
+	Use:   "delete <volume>",
+	Short: "Delete the replication of a volume",
+	Long: `Delete the replication of a volume and wait until both volumes are
+not part of it anymore. The volumes themselves are kept, the destination
+volume can then be deleted like any other volume.`,
+	Example: `  go-anf replication delete myvol -g myrg -a myaccount-westus -p mypool`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, err := poolScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		if !confirm(cmd, fmt.Sprintf("Delete the replication of volume %v?", args[0])) {
+			return nil
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Deleting the replication of volume %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginDeleteANFVolumeReplication(cmd.Context(), resourceGroupName, accountName, poolName, args[0]))
+		}
+		return client.DeleteANFVolumeReplication(cmd.Context(), resourceGroupName, accountName, poolName, args[0])
+	},
+}
+
+// replicationWatchCmd represents the replication watch command
+var replicationWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Continuously check every replication of the subscription",
+	Long: `Poll the replication status of every replicated volume in the
+subscription and flag the replications whose lag exceeds --lag-multiple
+times the interval of their schedule, or whose mirror state is Broken or
+Uninitialized. The table is refreshed after every poll until Ctrl-C.
+Replications into another subscription are listed as not monitored, their
+state can only be read in the subscription of the destination volume.
+
+--events appends a JSON line to a file when a replication gets flagged or
+the reasons change ("alert"), when it recovers ("resolved") and when its
+status cannot be read ("error"), for alerting to tail, e.g.:
+
+  {"time":"2023-01-02T15:04:05Z","event":"alert","volumeId":"<volumeID>","sourceVolumeId":"<volumeID>",
+   "destinationVolumeId":"<volumeID>","schedule":"hourly","mirrorState":"Mirrored","relationshipStatus":"Idle",
+   "lagSeconds":9000,"reasons":["lag exceeds 2h0m0s"]}`,
+	Example: `  go-anf replication watch
+  go-anf replication watch --interval 5m --lag-multiple 1.5 --events /var/log/go-anf/replication.jsonl`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		interval, _ := cmd.Flags().GetDuration("interval")
+		if interval <= 0 {
+			return invalidUsage("invalid --interval %v, it must be positive", interval)
+		}
+		lagMultiple, _ := cmd.Flags().GetFloat64("lag-multiple")
+		if lagMultiple <= 0 {
+			return invalidUsage("invalid --lag-multiple %v, it must be positive", lagMultiple)
+		}
+
+		var events *json.Encoder
+		if path, _ := cmd.Flags().GetString("events"); path != "" {
+			file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				return fmt.Errorf("cannot open event stream: %v", err)
+			}
+			defer file.Close()
+			events = json.NewEncoder(file)
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		// flagged holds the reasons each replication was last reported with, to emit events on changes only
+		flagged := map[string]string{}
+		for {
+			rows, err := pollReplications(ctx, client, lagMultiple)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil {
+				utils.ConsoleOutput(fmt.Sprintf("Warning: %v", err))
+			} else {
+				if events != nil {
+					if err := emitReplicationEvents(events, rows, flagged); err != nil {
+						return err
+					}
+				}
+				if err := printReplicationWatch(rows, interval); err != nil {
+					return err
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	},
+}
+
+// replicationWatchRow is the state of a replication in a poll of replication watch
+type replicationWatchRow struct {
+	VolumeID string                      `json:"volumeId"`
+	Status   *sdkutils.ReplicationStatus `json:"status,omitempty"`
+	Reasons  []string                    `json:"reasons,omitempty"`
+	Error    string                      `json:"error,omitempty"`
+}
+
+// replicationWatchEvent is a line of the event stream of replication watch
+type replicationWatchEvent struct {
+	Time                time.Time `json:"time"`
+	Event               string    `json:"event"`
+	VolumeID            string    `json:"volumeId"`
+	SourceVolumeID      string    `json:"sourceVolumeId,omitempty"`
+	DestinationVolumeID string    `json:"destinationVolumeId,omitempty"`
+	Schedule            string    `json:"schedule,omitempty"`
+	MirrorState         string    `json:"mirrorState,omitempty"`
+	RelationshipStatus  string    `json:"relationshipStatus,omitempty"`
+	LagSeconds          int64     `json:"lagSeconds"`
+	Reasons             []string  `json:"reasons,omitempty"`
+	Error               string    `json:"error,omitempty"`
+}
+
+// pollReplications reads the status of every replication of the subscription and flags the unhealthy ones
+func pollReplications(ctx context.Context, client *sdkutils.Client, lagMultiple float64) ([]*replicationWatchRow, error) {
+	volumeIDs, err := client.ListANFReplicatedVolumes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(volumeIDs)
+
+	rows := make([]*replicationWatchRow, 0, len(volumeIDs))
+	for _, volumeID := range volumeIDs {
+		row := &replicationWatchRow{VolumeID: volumeID}
+		status, err := client.GetANFVolumeReplicationStatus(ctx, uri.GetResourceGroup(volumeID), uri.GetANFAccount(volumeID), uri.GetANFCapacityPool(volumeID), uri.GetANFVolume(volumeID))
+		if err != nil {
+			row.Error = err.Error()
+		} else {
+			row.Status = status
+			row.Reasons = replicationAlerts(status, lagMultiple)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// replicationAlerts returns why a replication is flagged, nil when it is healthy. The reasons do not
+// change while the lag grows, so a replication is alerted once until it recovers.
+func replicationAlerts(status *sdkutils.ReplicationStatus, lagMultiple float64) []string {
+	var reasons []string
+	switch status.MirrorState {
+	case string(armnetapp.MirrorStateBroken), string(armnetapp.MirrorStateUninitialized):
+		reasons = append(reasons, fmt.Sprintf("mirror state is %v", status.MirrorState))
+	}
+	if interval := sdkutils.ReplicationInterval(status.Schedule); interval > 0 && status.LastTransfer != nil {
+		if maxLag := time.Duration(lagMultiple * float64(interval)); status.Lag > maxLag {
+			reasons = append(reasons, fmt.Sprintf("lag exceeds %v", maxLag))
+		}
+	}
+
+	return reasons
+}
+
+// emitReplicationEvents writes the events of the replications whose state changed since the previous poll
+func emitReplicationEvents(events *json.Encoder, rows []*replicationWatchRow, flagged map[string]string) error {
+	now := time.Now().UTC()
+	seen := map[string]bool{}
+	for _, row := range rows {
+		seen[row.VolumeID] = true
+
+		event := &replicationWatchEvent{Time: now, VolumeID: row.VolumeID, Reasons: row.Reasons, Error: row.Error}
+		if row.Status != nil {
+			event.SourceVolumeID = row.Status.SourceVolumeID
+			event.DestinationVolumeID = row.Status.DestinationVolumeID
+			event.Schedule = row.Status.Schedule
+			event.MirrorState = row.Status.MirrorState
+			event.RelationshipStatus = row.Status.RelationshipStatus
+			event.LagSeconds = row.Status.LagSeconds
+		}
+
+		state := strings.Join(row.Reasons, "; ")
+		switch {
+		case row.Error != "":
+			state = "error: " + row.Error
+			event.Event = "error"
+		case state != "":
+			event.Event = "alert"
+		case flagged[row.VolumeID] != "":
+			event.Event = "resolved"
+		}
+		if state == flagged[row.VolumeID] || event.Event == "" {
+			continue
+		}
+
+		flagged[row.VolumeID] = state
+		if err := events.Encode(event); err != nil {
+			return fmt.Errorf("cannot write event: %v", err)
+		}
+	}
+
+	// A replication that was deleted is not reported anymore
+	for volumeID := range flagged {
+		if !seen[volumeID] {
+			delete(flagged, volumeID)
+		}
+	}
+
+	return nil
+}
+
+// printReplicationWatch prints a poll of replication watch, redrawing the screen when stdout is a terminal
+func printReplicationWatch(rows []*replicationWatchRow, interval time.Duration) error {
+	if !humanOutput() {
+		return printOutput(rows)
+	}
+
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		fmt.Print("\033[H\033[2J")
+	} else {
+		fmt.Println()
+	}
+
+	flaggedCount := 0
+	table := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		volume := fmt.Sprintf("%v/%v", uri.GetANFAccount(row.VolumeID), uri.GetANFVolume(row.VolumeID))
+		if row.Error != "" {
+			flaggedCount++
+			table = append(table, []string{volume, "", "", "", "", "", "", row.Error})
+			continue
+		}
+		if len(row.Reasons) > 0 {
+			flaggedCount++
+		}
+
+		status := row.Status
+		lastTransfer, lag := "", ""
+		if status.LastTransfer != nil {
+			lastTransfer = formatTime(*status.LastTransfer)
+			lag = status.Lag.String()
+		}
+		alert := strings.Join(row.Reasons, "; ")
+		if status.NotMonitored != "" {
+			alert = status.NotMonitored
+		}
+		table = append(table, []string{
+			fmt.Sprintf("%v/%v", uri.GetANFAccount(status.DestinationVolumeID), uri.GetANFVolume(status.DestinationVolumeID)),
+			fmt.Sprintf("%v/%v", uri.GetANFAccount(status.SourceVolumeID), uri.GetANFVolume(status.SourceVolumeID)),
+			status.Schedule,
+			status.MirrorState,
+			status.RelationshipStatus,
+			lag,
+			lastTransfer,
+			alert,
+		})
+	}
+
+	utils.PrintHeader(fmt.Sprintf("Replications at %v: %v watched, %v flagged, next poll in %v", formatTime(time.Now()), len(rows), flaggedCount, interval))
+	printTable([]string{"DESTINATION", "SOURCE", "SCHEDULE", "MIRROR STATE", "RELATIONSHIP", "LAG", "LAST TRANSFER", "ALERT"}, table)
+	return nil
+}
+
+func printReplicationStatus(status *sdkutils.ReplicationStatus) error {
+	if !humanOutput() {
+		return printOutput(status)
+	}
+
+	utils.PrintHeader(fmt.Sprintf("Replication of volume %v", uri.GetANFVolume(status.DestinationVolumeID)))
+
+	fields := [][]string{
+		{"Source", status.SourceVolumeID},
+		{"Destination", status.DestinationVolumeID},
+		{"Schedule", status.Schedule},
+		{"Healthy", fmt.Sprintf("%v", status.Healthy)},
+		{"Mirror state", status.MirrorState},
+		{"Relationship status", status.RelationshipStatus},
+		{"Total transferred", utils.FormatSize(status.TotalTransferBytes)},
+		{"Last transfer", ""},
+		{"Lag", ""},
+	}
+	if status.LastTransfer != nil {
+		fields[7][1] = formatTime(*status.LastTransfer)
+		fields[8][1] = status.Lag.String()
+	}
+	if status.ErrorMessage != "" {
+		fields = append(fields, []string{"Error", status.ErrorMessage})
+	}
+	if status.NotMonitored != "" {
+		fields = append(fields, []string{"Monitoring", status.NotMonitored})
+	}
+
+	printFields(fields)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(replicationCmd)
+	replicationCmd.AddCommand(replicationCreateCmd, replicationStatusCmd, replicationBreakCmd, replicationResyncCmd, replicationReinitCmd, replicationDeleteCmd, replicationWatchCmd)
+
+	for _, cmd := range []*cobra.Command{replicationStatusCmd, replicationBreakCmd, replicationResyncCmd, replicationReinitCmd, replicationDeleteCmd} {
+		addResourceGroupFlag(cmd)
+		cmd.Flags().StringP("account", "a", "", "Name of the account")
+		cmd.Flags().StringP("pool", "p", "", "Name of the capacity pool of the volume")
+	}
+
+	replicationCreateCmd.Flags().String("source", "", "Resource ID of the volume to replicate")
+	replicationCreateCmd.Flags().String("dest-region", "", "Azure region of the destination volume")
+	replicationCreateCmd.Flags().String("subnet-id", "", "Resource ID of the subnet of the destination volume, in the destination region")
+	replicationCreateCmd.Flags().String("schedule", "hourly", "Replication schedule: 10minutely, hourly or daily")
+	replicationCreateCmd.Flags().String("dest-resource-group", "", "Resource group of the destination, defaults to the resource group of the source volume")
+	replicationCreateCmd.Flags().String("dest-account", "", "Account of the destination, defaults to the source account name suffixed with the destination region")
+	replicationCreateCmd.Flags().String("dest-pool", "", "Capacity pool of the destination, defaults to the name of the source pool")
+	replicationCreateCmd.Flags().String("dest-volume", "", "Name of the destination volume, defaults to the name of the source volume")
+	replicationCreateCmd.Flags().String("service-level", "", "Service level of a created destination pool, defaults to the service level of the source volume")
+	replicationCreateCmd.Flags().String("pool-size", "", "Size of a created destination pool in whole TiB, defaults to the smallest pool the volume fits in")
+	addTagsFlag(replicationCreateCmd)
+
+	replicationBreakCmd.Flags().Bool("force", false, "Break the replication even while a transfer is in progress")
+
+	addYesFlag(replicationBreakCmd)
+	addYesFlag(replicationResyncCmd)
+	addYesFlag(replicationDeleteCmd)
+
+	for _, cmd := range []*cobra.Command{replicationBreakCmd, replicationResyncCmd, replicationReinitCmd, replicationDeleteCmd} {
+		addNoWaitFlag(cmd)
+	}
+
+	replicationWatchCmd.Flags().Duration("interval", time.Minute, "Time between two polls")
+	replicationWatchCmd.Flags().Float64("lag-multiple", 2, "Flag replications whose lag exceeds this multiple of the interval of their schedule")
+	replicationWatchCmd.Flags().String("events", "", "Append a JSON line to this file for every alert, recovery and error")
+}
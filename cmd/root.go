@@ -5,47 +5,207 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/patrikcze/go-anf/pkg/config"
+	"github.com/patrikcze/go-anf/pkg/output"
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+	"github.com/patrikcze/go-anf/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
+var (
+	cfgFile      string
+	profileName  string
+	outputFormat string
+	outputQuery  string
 
+	maxRetries      int32
+	retryDelay      time.Duration
+	maxRetryDelay   time.Duration
+	honorRetryAfter bool
+	timeout         time.Duration
+
+	// profileSubscriptionID and profileTenantID replace the ones of the authentication file of the active profile
+	profileSubscriptionID string
+	profileTenantID       string
+
+	// commandStarted tells the errors of the command from the ones of its command line
+	commandStarted bool
+)
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "go-anf",
-	Short: "A brief description of your application",
-	Long: `A longer description that spans multiple lines and likely contains
-examples and usage of using your application. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
-	// Uncomment the following line if your bare application
-	// has an action associated with it:
-	// Run: func(cmd *cobra.Command, args []string) { },
+	Short: "Manage Azure NetApp Files resources",
+	Long: `go-anf performs management operations against the Microsoft.NetApp
+resource provider: accounts, capacity pools, volumes, snapshots and
+snapshot policies.
+
+Authentication uses the service principal file of the active profile
+(see "go-anf config") or the one referenced by the AZURE_AUTH_LOCATION
+environment variable. Setting GO_ANF_EMULATOR to the URL of a running
+"go-anf emulator" runs every command against the emulator instead.
+
+Results are printed as human readable tables by default, --output
+selects json, yaml or tsv instead and --query filters them with a
+JMESPath expression, e.g. --query "[].name".
+
+A failed command prints its error to stderr, as a JSON object with
+--output json, and exits with a code telling the kind of the failure:
+  1  unclassified error
+  2  invalid command line or request
+  3  authentication or authorization failure
+  4  resource not found
+  5  conflict with the state of a resource, e.g. another operation in progress
+  6  quota, limit or capacity exceeded
+  7  invalid subnet
+  8  throttled by Azure Resource Manager
+  9  timed out
+  10 Azure service error`,
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+	PersistentPreRunE: preRun,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// A failed command exits with the code of the kind of its error, see the help of the root command.
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		// Command lines are parsed and validated before preRun starts the command
+		if !commandStarted {
+			err = usageError{err}
+		}
+		exitWithError(err)
 	}
 }
 
-func init() {
-	// Here you will define your flags and configuration settings.
-	// Cobra supports persistent flags, which, if defined here,
-	// will be global for your application.
+// loadConfig reads the configuration file selected by --config
+func loadConfig() (*config.Config, string, error) {
+	path := cfgFile
+	if path == "" {
+		defaultPath, err := config.DefaultPath()
+		if err != nil {
+			return nil, "", err
+		}
+		path = defaultPath
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, "", err
+	}
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.go-anf.yaml)")
+	return cfg, path, nil
+}
 
-	// Cobra also supports local flags, which will only run
-	// when this action is called directly.
-	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+// preRun validates the global flags and applies the active profile
+func preRun(cmd *cobra.Command, args []string) error {
+	if err := output.ValidateFormat(outputFormat); err != nil {
+		return err
+	}
+	if err := output.ValidateQuery(outputQuery); err != nil {
+		return err
+	}
+	commandStarted = true
+
+	return applyProfile(cmd, args)
 }
 
+// applyProfile resolves the active profile, points authentication at it and
+// fills every resource-group, location and account flag that was not given
+func applyProfile(cmd *cobra.Command, args []string) error {
+	for parent := cmd; parent != nil; parent = parent.Parent() {
+		if parent == configCmd {
+			return nil
+		}
+	}
 
+	cfg, _, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	profile, found := cfg.Profiles[cfg.ActiveProfileName(profileName)]
+	if !found {
+		if profileName != "" {
+			return fmt.Errorf("profile %v not found, available profiles are: %v", profileName, cfg.ProfileNames())
+		}
+		return nil
+	}
+
+	// An explicitly selected profile wins over the environment
+	environment := map[string]string{
+		"AZURE_AUTH_LOCATION": profile.AuthFile,
+		emulatorVariable:      profile.Emulator,
+	}
+	// Credentials from the environment may belong to another subscription than the resources
+	// of the profile, its subscription, tenant and resource defaults only go with its own credentials
+	credentialsFromEnvironment := false
+	if profileName == "" {
+		for _, key := range []string{"AZURE_AUTH_LOCATION", emulatorVariable} {
+			if value := os.Getenv(key); value != "" && value != environment[key] {
+				credentialsFromEnvironment = true
+			}
+		}
+	}
+	for key, value := range environment {
+		if value != "" && (profileName != "" || os.Getenv(key) == "") {
+			os.Setenv(key, value)
+		}
+	}
+	if !credentialsFromEnvironment {
+		profileSubscriptionID = profile.SubscriptionID
+		profileTenantID = profile.TenantID
+	}
+
+	defaults := map[string]string{
+		"max-retries":       profile.MaxRetries,
+		"retry-delay":       profile.RetryDelay,
+		"max-retry-delay":   profile.MaxRetryDelay,
+		"honor-retry-after": profile.HonorRetryAfter,
+		"timeout":           profile.Timeout,
+	}
+	resourceDefaults := map[string]string{
+		"resource-group": profile.ResourceGroup,
+		"location":       profile.Location,
+		"account":        profile.Account,
+	}
+	for name, value := range resourceDefaults {
+		if flag := cmd.Flags().Lookup(name); value == "" || flag == nil || flag.Changed {
+			continue
+		}
+		if credentialsFromEnvironment {
+			utils.ConsoleOutput(fmt.Sprintf("Warning: credentials come from the environment, the subscription and resource defaults of profile %v are not applied, select it with --profile to use them", cfg.ActiveProfileName(profileName)))
+			break
+		}
+		defaults[name] = value
+	}
+	for name, value := range defaults {
+		flag := cmd.Flags().Lookup(name)
+		if flag != nil && !flag.Changed && value != "" {
+			if err := flag.Value.Set(value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.go-anf.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Name of the configuration profile to use")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", output.FormatTable, fmt.Sprintf("Output format, one of: %v", strings.Join(output.Formats, ", ")))
+	rootCmd.PersistentFlags().StringVar(&outputQuery, "query", "", "JMESPath expression applied to the result, e.g. \"[].name\"")
+	rootCmd.PersistentFlags().Int32Var(&maxRetries, "max-retries", sdkutils.DefaultMaxRetries, "Number of retries of a throttled or failed Azure request, 0 disables the retries")
+	rootCmd.PersistentFlags().DurationVar(&retryDelay, "retry-delay", sdkutils.DefaultRetryDelay, "Delay before the first retry, it doubles after every retry")
+	rootCmd.PersistentFlags().DurationVar(&maxRetryDelay, "max-retry-delay", sdkutils.DefaultMaxRetryDelay, "Maximum delay between two retries")
+	rootCmd.PersistentFlags().BoolVar(&honorRetryAfter, "honor-retry-after", true, "Wait as long as Azure asks in the Retry-After header before retrying and polling")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Maximum duration of every long-running operation and wait, e.g. 30m (default no limit for operations, 30m for waits)")
+}
@@ -0,0 +1,286 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+	"github.com/patrikcze/go-anf/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Manage volume snapshots",
+	Long: `Create, list, show and delete snapshots of a volume, or revert a
+volume to one of its snapshots.`,
+}
+
+// snapshotCreateCmd represents the snapshot create command
+var snapshotCreateCmd = &cobra.Command{
+	Use:     "create <name>",
+	Short:   "Create a snapshot of a volume",
+	Example: `  go-anf snapshot create mysnap -g myrg -a myaccount -p mypool -v myvol`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, volumeName, err := volumeScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		volume, err := client.GetANFVolume(cmd.Context(), resourceGroupName, accountName, poolName, volumeName)
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Creating snapshot %v of volume %v...", args[0], volumeName))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginCreateANFSnapshot(cmd.Context(), str(volume.Location), resourceGroupName, accountName, poolName, volumeName, args[0], nil))
+		}
+		snapshot, err := client.CreateANFSnapshot(cmd.Context(), str(volume.Location), resourceGroupName, accountName, poolName, volumeName, args[0], nil)
+		if err != nil {
+			return err
+		}
+
+		return printSnapshot(snapshot)
+	},
+}
+
+// snapshotListCmd represents the snapshot list command
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List snapshots of a volume, oldest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, volumeName, err := volumeScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		snapshots, err := client.ListANFSnapshots(cmd.Context(), resourceGroupName, accountName, poolName, volumeName)
+		if err != nil {
+			return err
+		}
+		sortSnapshots(snapshots)
+
+		if !humanOutput() {
+			return printOutput(snapshots)
+		}
+
+		rows := make([][]string, 0, len(snapshots))
+		for _, snapshot := range snapshots {
+			rows = append(rows, []string{
+				resourceName(snapshot.Name),
+				formatTime(snapshotCreated(snapshot)),
+				snapshotProvisioningState(snapshot),
+			})
+		}
+		printTable([]string{"NAME", "CREATED", "STATE"}, rows)
+		return nil
+	},
+}
+
+// snapshotShowCmd represents the snapshot show command
+var snapshotShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show a snapshot",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, volumeName, err := volumeScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		snapshot, err := client.GetANFSnapshot(cmd.Context(), resourceGroupName, accountName, poolName, volumeName, args[0])
+		if err != nil {
+			return err
+		}
+
+		return printSnapshot(snapshot)
+	},
+}
+
+// snapshotDeleteCmd represents the snapshot delete command
+var snapshotDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a snapshot",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, volumeName, err := volumeScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		if !confirm(cmd, fmt.Sprintf("Delete snapshot %v of volume %v?", args[0], volumeName)) {
+			return nil
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Deleting snapshot %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginDeleteANFSnapshot(cmd.Context(), resourceGroupName, accountName, poolName, volumeName, args[0]))
+		}
+		return client.DeleteANFSnapshot(cmd.Context(), resourceGroupName, accountName, poolName, volumeName, args[0])
+	},
+}
+
+// snapshotRevertCmd represents the snapshot revert command
+var snapshotRevertCmd = &cobra.Command{
+	Use:   "revert <name>",
+	Short: "Revert a volume to a snapshot",
+	Long: `Revert a volume to the state captured by a snapshot.
+
+All data written after the snapshot was taken is lost and every newer
+snapshot of the volume is deleted. The snapshots that will be lost are
+listed before asking for confirmation.`,
+	Example: `  go-anf snapshot revert mysnap -g myrg -a myaccount -p mypool -v myvol`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, volumeName, err := volumeScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		snapshots, err := client.ListANFSnapshots(cmd.Context(), resourceGroupName, accountName, poolName, volumeName)
+		if err != nil {
+			return err
+		}
+		sortSnapshots(snapshots)
+
+		var target *armnetapp.Snapshot
+		for _, snapshot := range snapshots {
+			if resourceName(snapshot.Name) == args[0] {
+				target = snapshot
+				break
+			}
+		}
+		if target == nil {
+			return fmt.Errorf("snapshot %v not found on volume %v", args[0], volumeName)
+		}
+
+		newer := [][]string{}
+		for _, snapshot := range snapshots {
+			if snapshotCreated(snapshot).After(snapshotCreated(target)) {
+				newer = append(newer, []string{resourceName(snapshot.Name), formatTime(snapshotCreated(snapshot))})
+			}
+		}
+
+		if len(newer) > 0 && humanOutput() {
+			utils.PrintHeader(fmt.Sprintf("The following %v snapshot(s) will be deleted", len(newer)))
+			printTable([]string{"NAME", "CREATED"}, newer)
+			fmt.Println()
+		} else if len(newer) > 0 {
+			utils.ConsoleOutput(fmt.Sprintf("%v newer snapshot(s) will be deleted", len(newer)))
+		}
+
+		prompt := fmt.Sprintf("Revert volume %v to snapshot %v taken %v? Data written since then will be lost", volumeName, args[0], formatTime(snapshotCreated(target)))
+		if !confirm(cmd, prompt) {
+			return nil
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Reverting volume %v to snapshot %v...", volumeName, args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginRevertANFVolume(cmd.Context(), resourceGroupName, accountName, poolName, volumeName, str(target.ID)))
+		}
+		return client.RevertANFVolume(cmd.Context(), resourceGroupName, accountName, poolName, volumeName, str(target.ID))
+	},
+}
+
+// sortSnapshots orders snapshots by creation time, oldest first
+func sortSnapshots(snapshots []*armnetapp.Snapshot) {
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshotCreated(snapshots[i]).Before(snapshotCreated(snapshots[j]))
+	})
+}
+
+func snapshotCreated(snapshot *armnetapp.Snapshot) time.Time {
+	if snapshot.Properties == nil || snapshot.Properties.Created == nil {
+		return time.Time{}
+	}
+	return *snapshot.Properties.Created
+}
+
+func snapshotProvisioningState(snapshot *armnetapp.Snapshot) string {
+	if snapshot.Properties == nil {
+		return ""
+	}
+	return str(snapshot.Properties.ProvisioningState)
+}
+
+func formatTime(value time.Time) string {
+	if value.IsZero() {
+		return ""
+	}
+	return value.Local().Format(time.RFC3339)
+}
+
+func printSnapshot(snapshot *armnetapp.Snapshot) error {
+	if !humanOutput() {
+		return printOutput(snapshot)
+	}
+
+	utils.PrintHeader(fmt.Sprintf("Snapshot %v", resourceName(snapshot.Name)))
+
+	snapshotID := ""
+	if snapshot.Properties != nil {
+		snapshotID = str(snapshot.Properties.SnapshotID)
+	}
+
+	printFields([][]string{
+		{"ID", str(snapshot.ID)},
+		{"Snapshot ID", snapshotID},
+		{"Location", str(snapshot.Location)},
+		{"Created", formatTime(snapshotCreated(snapshot))},
+		{"Provisioning state", snapshotProvisioningState(snapshot)},
+	})
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+	snapshotCmd.AddCommand(snapshotCreateCmd, snapshotListCmd, snapshotShowCmd, snapshotDeleteCmd, snapshotRevertCmd)
+
+	for _, cmd := range []*cobra.Command{snapshotCreateCmd, snapshotListCmd, snapshotShowCmd, snapshotDeleteCmd, snapshotRevertCmd} {
+		addResourceGroupFlag(cmd)
+		cmd.Flags().StringP("account", "a", "", "Name of the account")
+		cmd.Flags().StringP("pool", "p", "", "Name of the capacity pool")
+		cmd.Flags().StringP("volume", "v", "", "Name of the volume")
+	}
+
+	addYesFlag(snapshotDeleteCmd)
+	addYesFlag(snapshotRevertCmd)
+
+	for _, cmd := range []*cobra.Command{snapshotCreateCmd, snapshotDeleteCmd, snapshotRevertCmd} {
+		addNoWaitFlag(cmd)
+	}
+}
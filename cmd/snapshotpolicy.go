@@ -0,0 +1,467 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+
+*/
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+	"github.com/patrikcze/go-anf/pkg/models"
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+	"github.com/patrikcze/go-anf/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// snapshotPolicyCmd represents the snapshot-policy command
+var snapshotPolicyCmd = &cobra.Command{
+	Use:   "snapshot-policy",
+	Short: "Manage snapshot policies",
+	Long: `Create, list, show, update and delete snapshot policies and assign
+them to volumes.
+
+A snapshot policy belongs to an account and combines up to four
+schedules (hourly, daily, weekly and monthly), each keeping its own
+number of snapshots. Times are in UTC.`,
+}
+
+// snapshotPolicyCreateCmd represents the snapshot-policy create command
+var snapshotPolicyCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a snapshot policy",
+	Example: `  go-anf snapshot-policy create mypolicy -g myrg -a myaccount --hourly-keep 24 --hourly-minute 5 --daily-keep 7 --daily-at 02:30
+  go-anf snapshot-policy create mypolicy -g myrg -a myaccount --weekly-keep 4 --weekly-day Saturday --weekly-at 23:00 --monthly-keep 12 --monthly-days 1,15 --monthly-at 01:00`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, err := accountScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		schedules := models.SnapshotPolicySchedules{}
+		if err := applyScheduleFlags(cmd, &schedules); err != nil {
+			return err
+		}
+		enabled, _ := cmd.Flags().GetBool("enabled")
+
+		properties, err := sdkutils.NewANFSnapshotPolicyProperties(schedules, enabled)
+		if err != nil {
+			return err
+		}
+
+		location, _ := cmd.Flags().GetString("location")
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		if location == "" {
+			account, err := client.GetANFAccount(cmd.Context(), resourceGroupName, accountName)
+			if err != nil {
+				return err
+			}
+			location = str(account.Location)
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Creating snapshot policy %v...", args[0]))
+		policy, err := client.CreateANFSnapshotPolicy(cmd.Context(), resourceGroupName, accountName, args[0], armnetapp.SnapshotPolicy{
+			Location:   to.Ptr(location),
+			Tags:       tagsFromFlag(cmd),
+			Properties: properties,
+		})
+		if err != nil {
+			return err
+		}
+
+		return printSnapshotPolicy(policy)
+	},
+}
+
+// snapshotPolicyListCmd represents the snapshot-policy list command
+var snapshotPolicyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List snapshot policies of an account",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, err := accountScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		policies, err := client.ListANFSnapshotPolicies(cmd.Context(), resourceGroupName, accountName)
+		if err != nil {
+			return err
+		}
+
+		if !humanOutput() {
+			return printOutput(policies)
+		}
+
+		rows := make([][]string, 0, len(policies))
+		for _, policy := range policies {
+			schedules := schedulesFromPolicy(policy)
+			rows = append(rows, []string{
+				resourceName(policy.Name),
+				strconv.FormatBool(snapshotPolicyEnabled(policy)),
+				strconv.Itoa(int(schedules.HourlySnapshotsToKeep)),
+				strconv.Itoa(int(schedules.DailySnapshotsToKeep)),
+				strconv.Itoa(int(schedules.WeeklySnapshotsToKeep)),
+				strconv.Itoa(int(schedules.MonthlySnapshotsToKeep)),
+			})
+		}
+		printTable([]string{"NAME", "ENABLED", "HOURLY", "DAILY", "WEEKLY", "MONTHLY"}, rows)
+		return nil
+	},
+}
+
+// snapshotPolicyShowCmd represents the snapshot-policy show command
+var snapshotPolicyShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show a snapshot policy",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, err := accountScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		policy, err := client.GetANFSnapshotPolicy(cmd.Context(), resourceGroupName, accountName, args[0])
+		if err != nil {
+			return err
+		}
+
+		return printSnapshotPolicy(policy)
+	},
+}
+
+// snapshotPolicyUpdateCmd represents the snapshot-policy update command
+var snapshotPolicyUpdateCmd = &cobra.Command{
+	Use:   "update <name>",
+	Short: "Update the schedules of a snapshot policy",
+	Long: `Update the schedules of a snapshot policy.
+
+Only the schedule flags that are given change, the rest of the policy is
+kept as is. Use --<schedule>-keep 0 to remove a schedule.`,
+	Example: `  go-anf snapshot-policy update mypolicy -g myrg -a myaccount --daily-keep 14
+  go-anf snapshot-policy update mypolicy -g myrg -a myaccount --hourly-keep 0 --enabled=false`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, err := accountScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		current, err := client.GetANFSnapshotPolicy(cmd.Context(), resourceGroupName, accountName, args[0])
+		if err != nil {
+			return err
+		}
+
+		schedules := schedulesFromPolicy(current)
+		if err := applyScheduleFlags(cmd, &schedules); err != nil {
+			return err
+		}
+		enabled := snapshotPolicyEnabled(current)
+		if cmd.Flags().Changed("enabled") {
+			enabled, _ = cmd.Flags().GetBool("enabled")
+		}
+
+		properties, err := sdkutils.NewANFSnapshotPolicyPatchProperties(schedules, enabled)
+		if err != nil {
+			return err
+		}
+
+		patch := armnetapp.SnapshotPolicyPatch{
+			Location:   current.Location,
+			Tags:       tagsFromFlag(cmd),
+			Properties: properties,
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Updating snapshot policy %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginUpdateANFSnapshotPolicy(cmd.Context(), resourceGroupName, accountName, args[0], patch))
+		}
+		policy, err := client.UpdateANFSnapshotPolicy(cmd.Context(), resourceGroupName, accountName, args[0], patch)
+		if err != nil {
+			return err
+		}
+
+		return printSnapshotPolicy(policy)
+	},
+}
+
+// snapshotPolicyDeleteCmd represents the snapshot-policy delete command
+var snapshotPolicyDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a snapshot policy",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, err := accountScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		if !confirm(cmd, fmt.Sprintf("Delete snapshot policy %v in account %v?", args[0], accountName)) {
+			return nil
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Deleting snapshot policy %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginDeleteANFSnapshotPolicy(cmd.Context(), resourceGroupName, accountName, args[0]))
+		}
+		return client.DeleteANFSnapshotPolicy(cmd.Context(), resourceGroupName, accountName, args[0])
+	},
+}
+
+// snapshotPolicyAssignCmd represents the snapshot-policy assign command
+var snapshotPolicyAssignCmd = &cobra.Command{
+	Use:     "assign <volume>",
+	Short:   "Assign a snapshot policy to a volume",
+	Example: `  go-anf snapshot-policy assign myvol -g myrg -a myaccount -p mypool --policy mypolicy`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, err := poolScope(cmd)
+		if err != nil {
+			return err
+		}
+		policyName, err := requiredString(cmd, "policy")
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		policy, err := client.GetANFSnapshotPolicy(cmd.Context(), resourceGroupName, accountName, policyName)
+		if err != nil {
+			return err
+		}
+		volume, err := client.GetANFVolume(cmd.Context(), resourceGroupName, accountName, poolName, args[0])
+		if err != nil {
+			return err
+		}
+
+		patch := armnetapp.VolumePatchProperties{
+			DataProtection: &armnetapp.VolumePatchPropertiesDataProtection{
+				Snapshot: &armnetapp.VolumeSnapshotProperties{
+					SnapshotPolicyID: policy.ID,
+				},
+			},
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Assigning snapshot policy %v to volume %v...", policyName, args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginUpdateANFVolume(cmd.Context(), str(volume.Location), resourceGroupName, accountName, poolName, args[0], patch, nil))
+		}
+		volume, err = client.UpdateANFVolume(cmd.Context(), str(volume.Location), resourceGroupName, accountName, poolName, args[0], patch, nil)
+		if err != nil {
+			return err
+		}
+
+		return printVolume(volume)
+	},
+}
+
+// applyScheduleFlags overrides the schedules with every schedule flag given on the command line
+func applyScheduleFlags(cmd *cobra.Command, schedules *models.SnapshotPolicySchedules) error {
+	flags := cmd.Flags()
+
+	if flags.Changed("hourly-keep") {
+		schedules.HourlySnapshotsToKeep, _ = flags.GetInt32("hourly-keep")
+	}
+	if flags.Changed("hourly-minute") {
+		schedules.HourlyMinute, _ = flags.GetInt32("hourly-minute")
+	}
+
+	if flags.Changed("daily-keep") {
+		schedules.DailySnapshotsToKeep, _ = flags.GetInt32("daily-keep")
+	}
+	if flags.Changed("daily-at") {
+		at, _ := flags.GetString("daily-at")
+		hour, minute, err := utils.ParseClock(at)
+		if err != nil {
+			return err
+		}
+		schedules.DailyHour, schedules.DailyMinute = hour, minute
+	}
+
+	if flags.Changed("weekly-keep") {
+		schedules.WeeklySnapshotsToKeep, _ = flags.GetInt32("weekly-keep")
+	}
+	if flags.Changed("weekly-day") {
+		schedules.WeeklyDays, _ = flags.GetStringSlice("weekly-day")
+	}
+	if flags.Changed("weekly-at") {
+		at, _ := flags.GetString("weekly-at")
+		hour, minute, err := utils.ParseClock(at)
+		if err != nil {
+			return err
+		}
+		schedules.WeeklyHour, schedules.WeeklyMinute = hour, minute
+	}
+
+	if flags.Changed("monthly-keep") {
+		schedules.MonthlySnapshotsToKeep, _ = flags.GetInt32("monthly-keep")
+	}
+	if flags.Changed("monthly-days") {
+		schedules.MonthlyDays, _ = flags.GetInt32Slice("monthly-days")
+	}
+	if flags.Changed("monthly-at") {
+		at, _ := flags.GetString("monthly-at")
+		hour, minute, err := utils.ParseClock(at)
+		if err != nil {
+			return err
+		}
+		schedules.MonthlyHour, schedules.MonthlyMinute = hour, minute
+	}
+
+	return nil
+}
+
+// schedulesFromPolicy converts the schedules of an existing policy back into their flag form
+func schedulesFromPolicy(policy *armnetapp.SnapshotPolicy) models.SnapshotPolicySchedules {
+	schedules := models.SnapshotPolicySchedules{}
+	if policy.Properties == nil {
+		return schedules
+	}
+	properties := policy.Properties
+
+	if hourly := properties.HourlySchedule; hourly != nil {
+		schedules.HourlySnapshotsToKeep = int32Value(hourly.SnapshotsToKeep)
+		schedules.HourlyMinute = int32Value(hourly.Minute)
+	}
+	if daily := properties.DailySchedule; daily != nil {
+		schedules.DailySnapshotsToKeep = int32Value(daily.SnapshotsToKeep)
+		schedules.DailyHour = int32Value(daily.Hour)
+		schedules.DailyMinute = int32Value(daily.Minute)
+	}
+	if weekly := properties.WeeklySchedule; weekly != nil {
+		schedules.WeeklySnapshotsToKeep = int32Value(weekly.SnapshotsToKeep)
+		schedules.WeeklyHour = int32Value(weekly.Hour)
+		schedules.WeeklyMinute = int32Value(weekly.Minute)
+		if str(weekly.Day) != "" {
+			schedules.WeeklyDays = strings.Split(str(weekly.Day), ",")
+		}
+	}
+	if monthly := properties.MonthlySchedule; monthly != nil {
+		schedules.MonthlySnapshotsToKeep = int32Value(monthly.SnapshotsToKeep)
+		schedules.MonthlyHour = int32Value(monthly.Hour)
+		schedules.MonthlyMinute = int32Value(monthly.Minute)
+		for _, day := range strings.Split(str(monthly.DaysOfMonth), ",") {
+			if value, err := strconv.Atoi(strings.TrimSpace(day)); err == nil {
+				schedules.MonthlyDays = append(schedules.MonthlyDays, int32(value))
+			}
+		}
+	}
+
+	return schedules
+}
+
+func int32Value(value *int32) int32 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+func snapshotPolicyEnabled(policy *armnetapp.SnapshotPolicy) bool {
+	return policy.Properties != nil && policy.Properties.Enabled != nil && *policy.Properties.Enabled
+}
+
+func printSnapshotPolicy(policy *armnetapp.SnapshotPolicy) error {
+	if !humanOutput() {
+		return printOutput(policy)
+	}
+
+	utils.PrintHeader(fmt.Sprintf("Snapshot policy %v", resourceName(policy.Name)))
+
+	schedules := schedulesFromPolicy(policy)
+	fields := [][]string{
+		{"ID", str(policy.ID)},
+		{"Location", str(policy.Location)},
+		{"Enabled", strconv.FormatBool(snapshotPolicyEnabled(policy))},
+	}
+
+	if schedules.HourlySnapshotsToKeep > 0 {
+		fields = append(fields, []string{"Hourly", fmt.Sprintf("keep %v, at minute %02d", schedules.HourlySnapshotsToKeep, schedules.HourlyMinute)})
+	}
+	if schedules.DailySnapshotsToKeep > 0 {
+		fields = append(fields, []string{"Daily", fmt.Sprintf("keep %v, at %02d:%02d", schedules.DailySnapshotsToKeep, schedules.DailyHour, schedules.DailyMinute)})
+	}
+	if schedules.WeeklySnapshotsToKeep > 0 {
+		fields = append(fields, []string{"Weekly", fmt.Sprintf("keep %v, on %v at %02d:%02d", schedules.WeeklySnapshotsToKeep, strings.Join(schedules.WeeklyDays, ","), schedules.WeeklyHour, schedules.WeeklyMinute)})
+	}
+	if schedules.MonthlySnapshotsToKeep > 0 {
+		days := make([]string, 0, len(schedules.MonthlyDays))
+		for _, day := range schedules.MonthlyDays {
+			days = append(days, strconv.Itoa(int(day)))
+		}
+		fields = append(fields, []string{"Monthly", fmt.Sprintf("keep %v, on day %v at %02d:%02d", schedules.MonthlySnapshotsToKeep, strings.Join(days, ","), schedules.MonthlyHour, schedules.MonthlyMinute)})
+	}
+
+	printFields(fields)
+	return nil
+}
+
+func addScheduleFlags(cmd *cobra.Command) {
+	cmd.Flags().Int32("hourly-keep", 0, "Number of hourly snapshots to keep, 0 disables the hourly schedule")
+	cmd.Flags().Int32("hourly-minute", 0, "Minute of the hour the hourly snapshot is taken")
+	cmd.Flags().Int32("daily-keep", 0, "Number of daily snapshots to keep, 0 disables the daily schedule")
+	cmd.Flags().String("daily-at", "00:00", "Time of day (HH:MM, UTC) the daily snapshot is taken")
+	cmd.Flags().Int32("weekly-keep", 0, "Number of weekly snapshots to keep, 0 disables the weekly schedule")
+	cmd.Flags().StringSlice("weekly-day", nil, "Days of the week the weekly snapshot is taken, e.g. Monday,Friday")
+	cmd.Flags().String("weekly-at", "00:00", "Time of day (HH:MM, UTC) the weekly snapshot is taken")
+	cmd.Flags().Int32("monthly-keep", 0, "Number of monthly snapshots to keep, 0 disables the monthly schedule")
+	cmd.Flags().Int32Slice("monthly-days", nil, "Days of the month the monthly snapshot is taken, e.g. 1,15")
+	cmd.Flags().String("monthly-at", "00:00", "Time of day (HH:MM, UTC) the monthly snapshot is taken")
+	cmd.Flags().Bool("enabled", true, "Whether the policy takes snapshots")
+	addTagsFlag(cmd)
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotPolicyCmd)
+	snapshotPolicyCmd.AddCommand(snapshotPolicyCreateCmd, snapshotPolicyListCmd, snapshotPolicyShowCmd, snapshotPolicyUpdateCmd, snapshotPolicyDeleteCmd, snapshotPolicyAssignCmd)
+
+	for _, cmd := range []*cobra.Command{snapshotPolicyCreateCmd, snapshotPolicyListCmd, snapshotPolicyShowCmd, snapshotPolicyUpdateCmd, snapshotPolicyDeleteCmd, snapshotPolicyAssignCmd} {
+		addResourceGroupFlag(cmd)
+		cmd.Flags().StringP("account", "a", "", "Name of the account")
+	}
+
+	snapshotPolicyCreateCmd.Flags().StringP("location", "l", "", "Azure region of the policy, defaults to the account location")
+	addScheduleFlags(snapshotPolicyCreateCmd)
+	addScheduleFlags(snapshotPolicyUpdateCmd)
+
+	snapshotPolicyAssignCmd.Flags().StringP("pool", "p", "", "Name of the capacity pool of the volume")
+	snapshotPolicyAssignCmd.Flags().String("policy", "", "Name of the snapshot policy to assign")
+
+	addYesFlag(snapshotPolicyDeleteCmd)
+
+	for _, cmd := range []*cobra.Command{snapshotPolicyUpdateCmd, snapshotPolicyDeleteCmd, snapshotPolicyAssignCmd} {
+		addNoWaitFlag(cmd)
+	}
+}
@@ -0,0 +1,417 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+	"github.com/patrikcze/go-anf/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// volumeCmd represents the volume command
+var volumeCmd = &cobra.Command{
+	Use:   "volume",
+	Short: "Manage volumes",
+	Long: `Create, show, list and delete volumes.
+
+A volume lives in a capacity pool, is delegated to a subnet and exposes
+an NFSv3, NFSv4.1 or SMB (CIFS) file system. Dual protocol volumes serve
+the same files over SMB and NFSv3 or NFSv4.1, they need an Active
+Directory connection on the account like SMB volumes. NFSv4.1 dual
+protocol volumes also need --ldap.`,
+}
+
+// volumeCreateCmd represents the volume create command
+var volumeCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a volume",
+	Long: `Create a volume in a capacity pool and wait until it is available,
+unless --no-wait is given.
+
+The volume cannot be pinned to an availability zone: the API version used
+by go-anf (2022-05-01) has no zone property on volumes, Azure places it.`,
+	Example: `  go-anf volume create myvol -g myrg -a myaccount -p mypool --quota 100GiB --subnet-id <subnetID>
+  go-anf volume create myvol41 -g myrg -a myaccount -p mypool --quota 100GiB --subnet-id <subnetID> --protocol NFSv4.1
+  go-anf volume create myclone -g myrg -a myaccount -p mypool --quota 100GiB --subnet-id <subnetID> --snapshot-id <snapshotID>
+  go-anf volume create myrestore -g myrg -a myaccount -p mypool --quota 100GiB --subnet-id <subnetID> --backup-id <backupID>
+  go-anf volume create myshare -g myrg -a myaccount -p mypool --quota 100GiB --subnet-id <subnetID> --protocol NFSv3,CIFS --security-style unix
+  go-anf volume create mykrb -g myrg -a myaccount -p mypool --quota 100GiB --subnet-id <subnetID> --protocol NFSv4.1 --kerberos`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, err := poolScope(cmd)
+		if err != nil {
+			return err
+		}
+		subnetID, err := requiredString(cmd, "subnet-id")
+		if err != nil {
+			return err
+		}
+		quota, err := requiredString(cmd, "quota")
+		if err != nil {
+			return err
+		}
+		quotaBytes, err := utils.ParseSize(quota)
+		if err != nil {
+			return usageError{err}
+		}
+		protocolTypes, err := protocolTypesFromFlag(cmd)
+		if err != nil {
+			return err
+		}
+		dataProtection, err := dataProtectionFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		pool, err := client.GetANFCapacityPool(cmd.Context(), resourceGroupName, accountName, poolName)
+		if err != nil {
+			return err
+		}
+
+		spec := sdkutils.VolumeSpec{
+			ResourceGroup:  resourceGroupName,
+			Account:        accountName,
+			Pool:           poolName,
+			Name:           args[0],
+			SubnetID:       subnetID,
+			ProtocolTypes:  protocolTypes,
+			UsageThreshold: quotaBytes,
+			Tags:           tagsFromFlag(cmd),
+			DataProtection: dataProtection,
+		}
+		spec.Location, _ = cmd.Flags().GetString("location")
+		if spec.Location == "" {
+			spec.Location = str(pool.Location)
+		}
+		spec.ServiceLevel, _ = cmd.Flags().GetString("service-level")
+		if spec.ServiceLevel == "" {
+			spec.ServiceLevel = poolServiceLevel(pool)
+		}
+		spec.SnapshotID, _ = cmd.Flags().GetString("snapshot-id")
+		spec.BackupID, _ = cmd.Flags().GetString("backup-id")
+		spec.UnixReadOnly, _ = cmd.Flags().GetBool("unix-read-only")
+		spec.SecurityStyle, _ = cmd.Flags().GetString("security-style")
+		spec.NetworkFeatures, _ = cmd.Flags().GetString("network-features")
+		spec.UnixPermissions, _ = cmd.Flags().GetString("unix-permissions")
+		// The optional flags are only sent when given, so the service defaults apply otherwise
+		if cmd.Flags().Changed("throughput") {
+			throughput, _ := cmd.Flags().GetFloat32("throughput")
+			spec.ThroughputMibps = to.Ptr(throughput)
+		}
+		if cmd.Flags().Changed("kerberos") {
+			kerberos, _ := cmd.Flags().GetBool("kerberos")
+			spec.KerberosEnabled = to.Ptr(kerberos)
+		}
+		if cmd.Flags().Changed("ldap") {
+			ldap, _ := cmd.Flags().GetBool("ldap")
+			spec.LdapEnabled = to.Ptr(ldap)
+		}
+		if cmd.Flags().Changed("snapshot-directory-visible") {
+			visible, _ := cmd.Flags().GetBool("snapshot-directory-visible")
+			spec.SnapshotDirectoryVisible = to.Ptr(visible)
+		}
+		if allowedClients, _ := cmd.Flags().GetString("allowed-clients"); allowedClients != "" {
+			rule := sdkutils.DefaultExportRule(spec.ProtocolTypes, spec.UnixReadOnly, spec.KerberosEnabled != nil && *spec.KerberosEnabled)
+			rule.AllowedClients = allowedClients
+			spec.ExportRules = []sdkutils.ExportRule{rule}
+		}
+		if err := spec.Validate(); err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Creating volume %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginCreateANFVolume(cmd.Context(), spec))
+		}
+		volume, err := client.CreateANFVolume(cmd.Context(), spec)
+		if err != nil {
+			return err
+		}
+
+		return printVolume(volume)
+	},
+}
+
+// volumeShowCmd represents the volume show command
+var volumeShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show a volume",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, err := poolScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		volume, err := client.GetANFVolume(cmd.Context(), resourceGroupName, accountName, poolName, args[0])
+		if err != nil {
+			return err
+		}
+
+		return printVolume(volume)
+	},
+}
+
+// volumeListCmd represents the volume list command
+var volumeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List volumes of a capacity pool",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, err := poolScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		volumes, err := client.ListANFVolumes(cmd.Context(), resourceGroupName, accountName, poolName)
+		if err != nil {
+			return err
+		}
+
+		if !humanOutput() {
+			return printOutput(volumes)
+		}
+
+		rows := make([][]string, 0, len(volumes))
+		for _, volume := range volumes {
+			rows = append(rows, []string{
+				resourceName(volume.Name),
+				strings.Join(volumeProtocolTypes(volume), ","),
+				utils.FormatSize(volumeQuotaBytes(volume)),
+				strings.Join(volumeMountPaths(volume), ","),
+				volumeProvisioningState(volume),
+			})
+		}
+		printTable([]string{"NAME", "PROTOCOLS", "QUOTA", "MOUNT PATH", "STATE"}, rows)
+		return nil
+	},
+}
+
+// volumeDeleteCmd represents the volume delete command
+var volumeDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a volume",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resourceGroupName, accountName, poolName, err := poolScope(cmd)
+		if err != nil {
+			return err
+		}
+
+		if !confirm(cmd, fmt.Sprintf("Delete volume %v in capacity pool %v? All data on the volume will be lost", args[0], poolName)) {
+			return nil
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		utils.ConsoleOutput(fmt.Sprintf("Deleting volume %v...", args[0]))
+		if noWait(cmd) {
+			return printStartedOperation(client.BeginDeleteANFVolume(cmd.Context(), resourceGroupName, accountName, poolName, args[0]))
+		}
+		return client.DeleteANFVolume(cmd.Context(), resourceGroupName, accountName, poolName, args[0])
+	},
+}
+
+// protocolTypesFromFlag normalizes the --protocol values to the names the service expects
+func protocolTypesFromFlag(cmd *cobra.Command) ([]string, error) {
+	values, _ := cmd.Flags().GetStringSlice("protocol")
+
+	protocolTypes := make([]string, 0, len(values))
+	for _, value := range values {
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "nfsv3":
+			protocolTypes = append(protocolTypes, "NFSv3")
+		case "nfsv4.1", "nfsv41":
+			protocolTypes = append(protocolTypes, "NFSv4.1")
+		case "cifs", "smb":
+			protocolTypes = append(protocolTypes, "CIFS")
+		default:
+			return nil, invalidUsage("invalid protocol type %v, valid protocol types are: NFSv3, NFSv4.1, CIFS", value)
+		}
+	}
+
+	if len(protocolTypes) == 0 {
+		return nil, invalidUsage("at least one protocol type is required")
+	}
+
+	return protocolTypes, nil
+}
+
+// dataProtectionFromFlags builds the snapshot policy and replication settings of a new volume, nil when there are none
+func dataProtectionFromFlags(cmd *cobra.Command) (*armnetapp.VolumePropertiesDataProtection, error) {
+	var dataProtection *armnetapp.VolumePropertiesDataProtection
+
+	if snapshotPolicyID, _ := cmd.Flags().GetString("snapshot-policy-id"); snapshotPolicyID != "" {
+		dataProtection = &armnetapp.VolumePropertiesDataProtection{
+			Snapshot: &armnetapp.VolumeSnapshotProperties{
+				SnapshotPolicyID: to.Ptr(snapshotPolicyID),
+			},
+		}
+	}
+
+	if remoteVolumeID, _ := cmd.Flags().GetString("replication-source-id"); remoteVolumeID != "" {
+		schedule, _ := cmd.Flags().GetString("replication-schedule")
+		replicationSchedule, err := sdkutils.ValidateANFReplicationSchedule(schedule)
+		if err != nil {
+			return nil, err
+		}
+
+		if dataProtection == nil {
+			dataProtection = &armnetapp.VolumePropertiesDataProtection{}
+		}
+		dataProtection.Replication = &armnetapp.ReplicationObject{
+			EndpointType:           to.Ptr(armnetapp.EndpointTypeDst),
+			RemoteVolumeResourceID: to.Ptr(remoteVolumeID),
+			ReplicationSchedule:    to.Ptr(replicationSchedule),
+		}
+	}
+
+	return dataProtection, nil
+}
+
+func volumeProtocolTypes(volume *armnetapp.Volume) []string {
+	protocolTypes := []string{}
+	if volume.Properties == nil {
+		return protocolTypes
+	}
+	for _, protocolType := range volume.Properties.ProtocolTypes {
+		protocolTypes = append(protocolTypes, str(protocolType))
+	}
+	return protocolTypes
+}
+
+func volumeQuotaBytes(volume *armnetapp.Volume) int64 {
+	if volume.Properties == nil || volume.Properties.UsageThreshold == nil {
+		return 0
+	}
+	return *volume.Properties.UsageThreshold
+}
+
+func volumeProvisioningState(volume *armnetapp.Volume) string {
+	if volume.Properties == nil {
+		return ""
+	}
+	return str(volume.Properties.ProvisioningState)
+}
+
+// volumeMountPaths returns the ip:/path pairs clients use to mount the volume
+func volumeMountPaths(volume *armnetapp.Volume) []string {
+	mountPaths := []string{}
+	if volume.Properties == nil {
+		return mountPaths
+	}
+	for _, mountTarget := range volume.Properties.MountTargets {
+		mountPaths = append(mountPaths, fmt.Sprintf("%v:/%v", str(mountTarget.IPAddress), str(volume.Properties.CreationToken)))
+	}
+	return mountPaths
+}
+
+func printVolume(volume *armnetapp.Volume) error {
+	if !humanOutput() {
+		return printOutput(volume)
+	}
+
+	utils.PrintHeader(fmt.Sprintf("Volume %v", resourceName(volume.Name)))
+
+	fields := [][]string{
+		{"ID", str(volume.ID)},
+		{"Location", str(volume.Location)},
+		{"Protocols", strings.Join(volumeProtocolTypes(volume), ",")},
+		{"Quota", utils.FormatSize(volumeQuotaBytes(volume))},
+		{"Mount path", strings.Join(volumeMountPaths(volume), ",")},
+		{"Provisioning state", volumeProvisioningState(volume)},
+		{"Tags", formatTags(volume.Tags)},
+	}
+
+	if properties := volume.Properties; properties != nil {
+		if properties.ServiceLevel != nil {
+			fields = append(fields, []string{"Service level", string(*properties.ServiceLevel)})
+		}
+		fields = append(fields, []string{"Subnet ID", str(properties.SubnetID)})
+		if properties.SecurityStyle != nil {
+			fields = append(fields, []string{"Security style", string(*properties.SecurityStyle)})
+		}
+		if properties.LdapEnabled != nil {
+			fields = append(fields, []string{"LDAP", fmt.Sprintf("%v", *properties.LdapEnabled)})
+		}
+
+		if dataProtection := properties.DataProtection; dataProtection != nil {
+			if dataProtection.Snapshot != nil {
+				fields = append(fields, []string{"Snapshot policy", str(dataProtection.Snapshot.SnapshotPolicyID)})
+			}
+			if backup := dataProtection.Backup; backup != nil && backup.BackupEnabled != nil && *backup.BackupEnabled {
+				fields = append(fields, []string{"Backup policy", str(backup.BackupPolicyID)})
+				fields = append(fields, []string{"Backup vault", str(backup.VaultID)})
+			}
+			if dataProtection.Replication != nil {
+				fields = append(fields, []string{"Replication source", str(dataProtection.Replication.RemoteVolumeResourceID)})
+			}
+		}
+	}
+
+	printFields(fields)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(volumeCmd)
+	volumeCmd.AddCommand(volumeCreateCmd, volumeShowCmd, volumeListCmd, volumeDeleteCmd)
+
+	for _, cmd := range []*cobra.Command{volumeCreateCmd, volumeShowCmd, volumeListCmd, volumeDeleteCmd} {
+		addResourceGroupFlag(cmd)
+		cmd.Flags().StringP("account", "a", "", "Name of the account")
+		cmd.Flags().StringP("pool", "p", "", "Name of the capacity pool")
+	}
+
+	volumeCreateCmd.Flags().StringP("location", "l", "", "Azure region of the volume, defaults to the pool location")
+	volumeCreateCmd.Flags().String("service-level", "", "Service level of the volume, defaults to the pool service level")
+	volumeCreateCmd.Flags().String("subnet-id", "", "Resource ID of the subnet delegated to Microsoft.NetApp/volumes")
+	volumeCreateCmd.Flags().String("quota", "", "Quota of the volume in whole GiB, e.g. 100GiB or 4TiB")
+	volumeCreateCmd.Flags().StringSlice("protocol", []string{"NFSv3"}, "Protocol types of the volume: NFSv3, NFSv4.1 or CIFS, or CIFS with one NFS version for a dual protocol volume")
+	volumeCreateCmd.Flags().String("snapshot-id", "", "Resource ID of a snapshot to create the volume from")
+	volumeCreateCmd.Flags().String("backup-id", "", "Resource ID of a backup to restore into the new volume")
+	volumeCreateCmd.Flags().Bool("unix-read-only", false, "Export the volume read only to NFS clients instead of read write")
+	volumeCreateCmd.Flags().String("allowed-clients", "", "Comma separated IP addresses, CIDR ranges and host names allowed to mount the volume, defaults to 0.0.0.0/0")
+	volumeCreateCmd.Flags().String("snapshot-policy-id", "", "Resource ID of a snapshot policy to assign to the volume")
+	volumeCreateCmd.Flags().String("replication-source-id", "", "Resource ID of the source volume, creates the volume as a replication destination")
+	volumeCreateCmd.Flags().String("replication-schedule", "hourly", "Replication schedule of a destination volume: 10minutely, hourly or daily")
+	volumeCreateCmd.Flags().Float32("throughput", 0, "Throughput of the volume in MiB/s, for capacity pools with a manual QoS type")
+	volumeCreateCmd.Flags().String("security-style", "", "Security style of the volume: ntfs or unix, decides whether Windows ACLs or UNIX permissions apply to a dual protocol volume")
+	volumeCreateCmd.Flags().String("network-features", "", "Network features of the volume: Basic or Standard")
+	volumeCreateCmd.Flags().Bool("kerberos", false, "Enable Kerberos on a NFSv4.1 volume")
+	volumeCreateCmd.Flags().Bool("ldap", false, "Resolve NFS users and groups through LDAP, requires an Active Directory connection on the account")
+	volumeCreateCmd.Flags().Bool("snapshot-directory-visible", true, "Show the .snapshot directory to NFS clients")
+	volumeCreateCmd.Flags().String("unix-permissions", "", "Octal permissions of the volume root, e.g. 0755")
+	addTagsFlag(volumeCreateCmd)
+
+	addYesFlag(volumeDeleteCmd)
+
+	for _, cmd := range []*cobra.Command{volumeCreateCmd, volumeDeleteCmd} {
+		addNoWaitFlag(cmd)
+	}
+}
@@ -8,8 +8,10 @@ require (
 	github.com/Azure/azure-sdk-for-go/sdk/azidentity v1.3.0
 	github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp v1.0.0
 	github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources v1.1.1
+	github.com/jmespath/go-jmespath v0.4.0
 	github.com/spf13/cobra v1.7.0
 	golang.org/x/term v0.8.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,211 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// This package reads and writes the go-anf configuration file,
+// a set of named profiles holding the subscription, tenant,
+// authentication file and the defaults used by the commands.
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultProfileName is the profile used when none was created or selected
+	DefaultProfileName = "default"
+
+	configFileName = ".go-anf.yaml"
+)
+
+// Profile object definition
+type Profile struct {
+	SubscriptionID string `json:"subscription,omitempty" yaml:"subscription,omitempty"`
+	TenantID       string `json:"tenant,omitempty" yaml:"tenant,omitempty"`
+	ResourceGroup  string `json:"resourceGroup,omitempty" yaml:"resourceGroup,omitempty"`
+	Location       string `json:"location,omitempty" yaml:"location,omitempty"`
+	Account        string `json:"account,omitempty" yaml:"account,omitempty"`
+	AuthFile       string `json:"authFile,omitempty" yaml:"authFile,omitempty"`
+	Emulator       string `json:"emulator,omitempty" yaml:"emulator,omitempty"`
+
+	// Retry and timeout settings, in the format of the matching global flags
+	MaxRetries      string `json:"maxRetries,omitempty" yaml:"maxRetries,omitempty"`
+	RetryDelay      string `json:"retryDelay,omitempty" yaml:"retryDelay,omitempty"`
+	MaxRetryDelay   string `json:"maxRetryDelay,omitempty" yaml:"maxRetryDelay,omitempty"`
+	HonorRetryAfter string `json:"honorRetryAfter,omitempty" yaml:"honorRetryAfter,omitempty"`
+	Timeout         string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// Config object definition
+type Config struct {
+	CurrentProfile string              `json:"currentProfile,omitempty" yaml:"currentProfile,omitempty"`
+	Profiles       map[string]*Profile `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+}
+
+// profileKeys maps the keys accepted by Get and Set to the profile fields
+var profileKeys = map[string]func(*Profile) *string{
+	"subscription":   func(p *Profile) *string { return &p.SubscriptionID },
+	"tenant":         func(p *Profile) *string { return &p.TenantID },
+	"resource-group": func(p *Profile) *string { return &p.ResourceGroup },
+	"location":       func(p *Profile) *string { return &p.Location },
+	"account":        func(p *Profile) *string { return &p.Account },
+	"auth-file":      func(p *Profile) *string { return &p.AuthFile },
+	"emulator":       func(p *Profile) *string { return &p.Emulator },
+
+	"max-retries":       func(p *Profile) *string { return &p.MaxRetries },
+	"retry-delay":       func(p *Profile) *string { return &p.RetryDelay },
+	"max-retry-delay":   func(p *Profile) *string { return &p.MaxRetryDelay },
+	"honor-retry-after": func(p *Profile) *string { return &p.HonorRetryAfter },
+	"timeout":           func(p *Profile) *string { return &p.Timeout },
+}
+
+// profileValidators checks the values of the profile keys that are not free text
+var profileValidators = map[string]func(value string) error{
+	"max-retries": func(value string) error {
+		_, err := strconv.ParseInt(value, 10, 32)
+		return err
+	},
+	"retry-delay":     validateDuration,
+	"max-retry-delay": validateDuration,
+	"timeout":         validateDuration,
+	"honor-retry-after": func(value string) error {
+		_, err := strconv.ParseBool(value)
+		return err
+	},
+}
+
+func validateDuration(value string) error {
+	_, err := time.ParseDuration(value)
+	return err
+}
+
+// DefaultPath returns the location of the configuration file, $HOME/.go-anf.yaml
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot find home directory: %v", err)
+	}
+
+	return filepath.Join(home, configFileName), nil
+}
+
+// Load reads the configuration file, a missing file results in an empty configuration
+func Load(path string) (*Config, error) {
+	config := &Config{
+		Profiles: map[string]*Profile{},
+	}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config file: %v", err)
+	}
+
+	if err := yaml.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("cannot parse config file %v: %v", path, err)
+	}
+	if config.Profiles == nil {
+		config.Profiles = map[string]*Profile{}
+	}
+
+	return config, nil
+}
+
+// Save writes the configuration file readable by the current user only
+func (c *Config) Save(path string) error {
+	var content bytes.Buffer
+	encoder := yaml.NewEncoder(&content)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return fmt.Errorf("cannot serialize config: %v", err)
+	}
+
+	if err := os.WriteFile(path, content.Bytes(), 0600); err != nil {
+		return fmt.Errorf("cannot write config file: %v", err)
+	}
+
+	return nil
+}
+
+// ActiveProfileName returns the requested profile name, falling back to the current and then the default profile
+func (c *Config) ActiveProfileName(requested string) string {
+	if requested != "" {
+		return requested
+	}
+	if c.CurrentProfile != "" {
+		return c.CurrentProfile
+	}
+	return DefaultProfileName
+}
+
+// Profile returns a profile by name
+func (c *Config) Profile(name string) (*Profile, error) {
+	profile, found := c.Profiles[name]
+	if !found {
+		return nil, fmt.Errorf("profile %v not found, available profiles are: %v", name, c.ProfileNames())
+	}
+
+	return profile, nil
+}
+
+// ProfileNames returns the sorted names of all profiles
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Keys returns the sorted keys accepted by Get and Set
+func Keys() []string {
+	keys := make([]string, 0, len(profileKeys))
+	for key := range profileKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Get returns the value of a profile key
+func (p *Profile) Get(key string) (string, error) {
+	field, found := profileKeys[strings.ToLower(key)]
+	if !found {
+		return "", fmt.Errorf("invalid key %v, valid keys are: %v", key, Keys())
+	}
+
+	return *field(p), nil
+}
+
+// Set changes the value of a profile key
+func (p *Profile) Set(key, value string) error {
+	field, found := profileKeys[strings.ToLower(key)]
+	if !found {
+		return fmt.Errorf("invalid key %v, valid keys are: %v", key, Keys())
+	}
+
+	if validate, found := profileValidators[strings.ToLower(key)]; found && value != "" {
+		if err := validate(value); err != nil {
+			return fmt.Errorf("invalid value %v for key %v: %v", value, key, err)
+		}
+	}
+
+	*field(p) = value
+	return nil
+}
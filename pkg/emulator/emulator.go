@@ -0,0 +1,642 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// This package emulates the Microsoft.NetApp resource provider of Azure
+// Resource Manager in memory: accounts, capacity pools, volumes, snapshots,
+// snapshot policies, backup policies, backup vaults, backups and volume
+// replications, with long-running operations reported through
+// Azure-AsyncOperation headers, so that the sdkutils client and the whole
+// command line can run without a subscription.
+
+package emulator
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// APIVersion is the Microsoft.NetApp API version served by the emulator
+	APIVersion = "2022-05-01"
+	// SubscriptionID is the subscription used to talk to the emulator, any other one works as well
+	SubscriptionID = "00000000-0000-0000-0000-000000000000"
+
+	provider = "Microsoft.NetApp"
+
+	stateCreating  = "Creating"
+	stateUpdating  = "Updating"
+	stateDeleting  = "Deleting"
+	stateSucceeded = "Succeeded"
+
+	statusInProgress = "InProgress"
+	statusSucceeded  = "Succeeded"
+)
+
+// Options object definition
+type Options struct {
+	// OperationDelay is how long long-running operations stay in progress
+	OperationDelay time.Duration
+	// CacheDelay is how long a deleted resource is still returned by reads and lists,
+	// like the ARM cache does after a deletion completed
+	CacheDelay time.Duration
+}
+
+// Emulator is an http.Handler serving the Microsoft.NetApp REST API from memory
+type Emulator struct {
+	options Options
+
+	mu           sync.Mutex
+	resources    map[string]*resource
+	operations   map[string]*operation
+	replications map[string]*replication
+	ipAddresses  int
+}
+
+// resource is a stored resource, document is its JSON representation as returned by GET
+type resource struct {
+	kind      *kind
+	id        string
+	document  map[string]interface{}
+	created   time.Time
+	deleted   time.Time
+	operation *operation
+}
+
+// operation is a long-running operation, complete applies its effect once it ends
+type operation struct {
+	id         string
+	resourceID string
+	status     string
+	started    time.Time
+	ends       time.Time
+	complete   func()
+}
+
+// armError is an error response in the ARM format
+type armError struct {
+	status  int
+	code    string
+	message string
+}
+
+// route is the parsed path of a request against a resource group
+type route struct {
+	subscription  string
+	resourceGroup string
+	kind          *kind
+	id            string // resource ID, or the ID of the parent for collections
+	names         []string
+	collection    bool
+	action        string
+}
+
+// New creates an empty emulator
+func New(options Options) *Emulator {
+	return &Emulator{
+		options:      options,
+		resources:    map[string]*resource{},
+		operations:   map[string]*operation{},
+		replications: map[string]*replication{},
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (e *Emulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("x-ms-request-id", newUUID())
+	w.Header().Set("x-ms-correlation-request-id", newUUID())
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		writeError(w, &armError{http.StatusUnauthorized, "AuthenticationFailed", "Authentication failed. The 'Authorization' header is missing."})
+		return
+	}
+	if r.URL.Query().Get("api-version") == "" {
+		writeError(w, &armError{http.StatusBadRequest, "MissingApiVersionParameter", "The api-version query parameter (?api-version=) is required for all requests."})
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.settle(time.Now())
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) < 4 || !strings.EqualFold(segments[0], "subscriptions") {
+		writeError(w, notFound(r.URL.Path))
+		return
+	}
+
+	// Subscription scoped paths: accounts of the subscription and operation results
+	if strings.EqualFold(segments[2], "providers") && strings.EqualFold(segments[3], provider) {
+		switch {
+		case len(segments) == 5 && strings.EqualFold(segments[4], "netAppAccounts") && r.Method == http.MethodGet:
+			e.list(w, func(res *resource) bool {
+				return res.kind == accounts && strings.EqualFold(subscriptionOf(res.id), segments[1])
+			})
+		case len(segments) == 8 && strings.EqualFold(segments[4], "locations") && strings.EqualFold(segments[6], "operationResults") && r.Method == http.MethodGet:
+			e.operationResult(w, r, segments[7])
+		default:
+			writeError(w, notFound(r.URL.Path))
+		}
+		return
+	}
+
+	route, apiErr := parseRoute(segments)
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	switch {
+	case route.action != "":
+		apiErr = e.volumeAction(w, r, route)
+	case route.collection && r.Method == http.MethodGet:
+		e.list(w, func(res *resource) bool {
+			return res.kind == route.kind && strings.EqualFold(parentID(res.id), route.id)
+		})
+	case route.collection:
+		apiErr = methodNotAllowed(r.Method)
+	case r.Method == http.MethodGet:
+		apiErr = e.get(w, route)
+	case r.Method == http.MethodPut:
+		apiErr = e.put(w, r, route)
+	case r.Method == http.MethodPatch:
+		apiErr = e.patch(w, r, route)
+	case r.Method == http.MethodDelete:
+		apiErr = e.delete(w, r, route)
+	default:
+		apiErr = methodNotAllowed(r.Method)
+	}
+	if apiErr != nil {
+		writeError(w, apiErr)
+	}
+}
+
+// parseRoute splits a resource group scoped path into the resource type, ID and action
+func parseRoute(segments []string) (*route, *armError) {
+	path := "/" + strings.Join(segments, "/")
+	if len(segments) < 7 || !strings.EqualFold(segments[2], "resourceGroups") || !strings.EqualFold(segments[4], "providers") || !strings.EqualFold(segments[5], provider) {
+		return nil, notFound(path)
+	}
+
+	r := &route{subscription: segments[1], resourceGroup: segments[3]}
+	id := fmt.Sprintf("/subscriptions/%v/resourceGroups/%v/providers/%v", segments[1], segments[3], provider)
+
+	var parent *kind
+	rest := segments[6:]
+	for i := 0; i < len(rest); i += 2 {
+		k := kindOf(rest[i], parent)
+		if k == nil {
+			// The last segment after a volume may be an action
+			if i == len(rest)-1 && parent == volumes {
+				r.action = rest[i]
+				r.id = id
+				return r, nil
+			}
+			return nil, &armError{http.StatusBadRequest, "InvalidResourceType", fmt.Sprintf("The resource type '%v' could not be found in the namespace '%v' for api version '%v'.", rest[i], provider, APIVersion)}
+		}
+
+		r.kind = k
+		if i == len(rest)-1 {
+			r.collection = true
+			r.id = id
+			return r, nil
+		}
+
+		id = fmt.Sprintf("%v/%v/%v", id, k.collection, rest[i+1])
+		r.names = append(r.names, rest[i+1])
+		parent = k
+	}
+
+	r.id = id
+	return r, nil
+}
+
+// settle completes the operations that ended before now, in the order they ended
+func (e *Emulator) settle(now time.Time) {
+	pending := []*operation{}
+	for _, op := range e.operations {
+		if op.status == statusInProgress && !now.Before(op.ends) {
+			pending = append(pending, op)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].ends.Before(pending[j].ends) })
+
+	for _, op := range pending {
+		op.status = statusSucceeded
+		if res := e.resources[strings.ToLower(op.resourceID)]; res != nil && res.operation == op {
+			res.operation = nil
+		}
+		op.complete()
+	}
+}
+
+// lookup returns a resource that is visible to reads, deleted resources stay visible during the cache delay
+func (e *Emulator) lookup(id string) *resource {
+	res := e.resources[strings.ToLower(id)]
+	if res == nil || (!res.deleted.IsZero() && time.Since(res.deleted) >= e.options.CacheDelay) {
+		return nil
+	}
+	return res
+}
+
+// live returns a resource that exists and was not deleted
+func (e *Emulator) live(id string) *resource {
+	res := e.resources[strings.ToLower(id)]
+	if res == nil || !res.deleted.IsZero() {
+		return nil
+	}
+	return res
+}
+
+// children returns the live resources of a kind directly below a parent
+func (e *Emulator) children(parent string, k *kind) []*resource {
+	found := []*resource{}
+	for _, res := range e.resources {
+		if res.kind == k && res.deleted.IsZero() && strings.EqualFold(parentID(res.id), parent) {
+			found = append(found, res)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].id < found[j].id })
+	return found
+}
+
+// start begins a long-running operation on a resource and sets the headers used to poll it
+func (e *Emulator) start(w http.ResponseWriter, r *http.Request, res *resource, complete func()) *operation {
+	now := time.Now()
+	op := &operation{
+		id:         newUUID(),
+		resourceID: res.id,
+		status:     statusInProgress,
+		started:    now,
+		ends:       now.Add(e.options.OperationDelay),
+		complete:   complete,
+	}
+	e.operations[op.id] = op
+	res.operation = op
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	location := strings.ToLower(strings.ReplaceAll(stringValue(res.document, "location"), " ", ""))
+	if location == "" {
+		location = "global"
+	}
+	asyncURL := fmt.Sprintf("%v://%v/subscriptions/%v/providers/%v/locations/%v/operationResults/%v?api-version=%v", scheme, r.Host, subscriptionOf(res.id), provider, location, op.id, APIVersion)
+
+	w.Header().Set("Azure-AsyncOperation", asyncURL)
+	setRetryAfter(w, op)
+	return op
+}
+
+// operationResult reports the status of a long-running operation
+func (e *Emulator) operationResult(w http.ResponseWriter, r *http.Request, id string) {
+	op := e.operations[id]
+	if op == nil {
+		writeError(w, &armError{http.StatusNotFound, "OperationNotFound", fmt.Sprintf("The operation '%v' was not found.", id)})
+		return
+	}
+
+	result := map[string]interface{}{
+		"id":        r.URL.Path,
+		"name":      op.id,
+		"status":    op.status,
+		"startTime": op.started.UTC().Format(time.RFC3339Nano),
+	}
+	if op.status == statusInProgress {
+		elapsed := time.Since(op.started).Seconds()
+		total := op.ends.Sub(op.started).Seconds()
+		result["percentComplete"] = math.Floor(100 * elapsed / total)
+		setRetryAfter(w, op)
+	} else {
+		result["endTime"] = op.ends.UTC().Format(time.RFC3339Nano)
+		result["percentComplete"] = 100
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// get returns a single resource
+func (e *Emulator) get(w http.ResponseWriter, route *route) *armError {
+	res := e.lookup(route.id)
+	if res == nil {
+		return resourceNotFound(route)
+	}
+
+	writeJSON(w, http.StatusOK, res.document)
+	return nil
+}
+
+// list returns the visible resources accepted by match
+func (e *Emulator) list(w http.ResponseWriter, match func(*resource) bool) {
+	ids := []string{}
+	for key, res := range e.resources {
+		if match(res) && e.lookup(res.id) != nil {
+			ids = append(ids, key)
+		}
+	}
+	sort.Strings(ids)
+
+	value := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		value = append(value, e.resources[id].document)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"value": value})
+}
+
+// put creates or replaces a resource, the properties of an existing resource not given in the body are kept
+func (e *Emulator) put(w http.ResponseWriter, r *http.Request, route *route) *armError {
+	body, apiErr := readBody(r)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	parent := e.live(parentID(route.id))
+	if route.kind.parent != nil && parent == nil {
+		return &armError{http.StatusNotFound, "ParentResourceNotFound", fmt.Sprintf("Can not perform requested operation on nested resource. Parent resource '%v' not found.", strings.Join(route.names[:len(route.names)-1], "/"))}
+	}
+
+	existing := e.live(route.id)
+	if existing != nil && existing.operation != nil {
+		return anotherOperation(existing)
+	}
+	if stringValue(body, "location") == "" {
+		return &armError{http.StatusBadRequest, "LocationRequired", "The location property is required for this definition."}
+	}
+
+	document := map[string]interface{}{}
+	properties := map[string]interface{}{}
+	if existing != nil {
+		document = copyDocument(existing.document)
+		properties = mapValue(document, "properties")
+	}
+	for key, value := range mapValue(body, "properties") {
+		properties[key] = value
+	}
+	document["id"] = route.id
+	document["name"] = strings.Join(route.names, "/")
+	document["type"] = route.kind.typeName
+	document["location"] = body["location"]
+	if tags, found := body["tags"]; found {
+		document["tags"] = tags
+	}
+	document["etag"] = newEtag()
+	document["properties"] = properties
+
+	res := &resource{kind: route.kind, id: route.id, document: document, created: time.Now()}
+	if existing != nil {
+		res.created = existing.created
+	}
+	if apiErr := route.kind.prepare(e, res, existing, parent); apiErr != nil {
+		return apiErr
+	}
+	e.resources[strings.ToLower(route.id)] = res
+
+	status := http.StatusCreated
+	if existing != nil {
+		status = http.StatusOK
+	}
+
+	if route.kind.synchronous {
+		properties["provisioningState"] = stateSucceeded
+		writeJSON(w, status, document)
+		return nil
+	}
+
+	properties["provisioningState"] = stateCreating
+	if existing != nil {
+		properties["provisioningState"] = stateUpdating
+	}
+	e.start(w, r, res, func() {
+		properties["provisioningState"] = stateSucceeded
+		if route.kind.created != nil {
+			route.kind.created(e, res)
+		}
+	})
+	writeJSON(w, status, document)
+	return nil
+}
+
+// patch updates the tags and the given properties of a resource
+func (e *Emulator) patch(w http.ResponseWriter, r *http.Request, route *route) *armError {
+	body, apiErr := readBody(r)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	existing := e.live(route.id)
+	if existing == nil {
+		return resourceNotFound(route)
+	}
+	if existing.operation != nil {
+		return anotherOperation(existing)
+	}
+
+	document := copyDocument(existing.document)
+	properties := mapValue(document, "properties")
+	merge(properties, mapValue(body, "properties"))
+	if tags, found := body["tags"]; found {
+		document["tags"] = tags
+	}
+	document["etag"] = newEtag()
+
+	res := &resource{kind: existing.kind, id: existing.id, document: document, created: existing.created}
+	if apiErr := res.kind.prepare(e, res, existing, e.live(parentID(route.id))); apiErr != nil {
+		return apiErr
+	}
+	e.resources[strings.ToLower(route.id)] = res
+
+	properties["provisioningState"] = stateUpdating
+	e.start(w, r, res, func() {
+		properties["provisioningState"] = stateSucceeded
+		if res.kind.created != nil {
+			res.kind.created(e, res)
+		}
+	})
+	w.WriteHeader(http.StatusAccepted)
+	return nil
+}
+
+// delete removes a resource once its deletion completes, together with the resources removed along with it
+func (e *Emulator) delete(w http.ResponseWriter, r *http.Request, route *route) *armError {
+	existing := e.live(route.id)
+	if existing == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	if existing.operation != nil {
+		return anotherOperation(existing)
+	}
+	if existing.kind.deletable != nil {
+		if apiErr := existing.kind.deletable(e, existing); apiErr != nil {
+			return apiErr
+		}
+	}
+
+	removed := []*resource{existing}
+	for _, k := range existing.kind.cascade {
+		for _, child := range e.children(existing.id, k) {
+			removed = append(removed, child)
+		}
+	}
+
+	mapValue(existing.document, "properties")["provisioningState"] = stateDeleting
+	e.start(w, r, existing, func() {
+		for _, res := range removed {
+			res.deleted = time.Now()
+		}
+		if existing.kind.removed != nil {
+			existing.kind.removed(e, existing)
+		}
+	})
+	w.WriteHeader(http.StatusAccepted)
+	return nil
+}
+
+// readBody decodes a JSON request body
+func readBody(r *http.Request) (map[string]interface{}, *armError) {
+	content, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, &armError{http.StatusBadRequest, "InvalidRequestContent", fmt.Sprintf("The request content could not be read: %v", err)}
+	}
+
+	body := map[string]interface{}{}
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, &body); err != nil {
+			return nil, &armError{http.StatusBadRequest, "InvalidRequestContent", fmt.Sprintf("The request content was invalid and could not be deserialized: %v", err)}
+		}
+	}
+
+	return body, nil
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// writeError writes an error response in the ARM format
+func writeError(w http.ResponseWriter, apiErr *armError) {
+	w.Header().Set("x-ms-error-code", apiErr.code)
+	writeJSON(w, apiErr.status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    apiErr.code,
+			"message": apiErr.message,
+		},
+	})
+}
+
+// setRetryAfter tells the client when an operation in progress is expected to end
+func setRetryAfter(w http.ResponseWriter, op *operation) {
+	remaining := time.Until(op.ends)
+	if remaining > 0 {
+		w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(remaining.Seconds()))))
+	}
+}
+
+func notFound(path string) *armError {
+	return &armError{http.StatusNotFound, "NotFound", fmt.Sprintf("No HTTP resource was found that matches the request URI '%v'.", path)}
+}
+
+func resourceNotFound(route *route) *armError {
+	return &armError{http.StatusNotFound, "ResourceNotFound", fmt.Sprintf("The Resource '%v/%v' under resource group '%v' was not found.", route.kind.typeName, strings.Join(route.names, "/"), route.resourceGroup)}
+}
+
+func anotherOperation(res *resource) *armError {
+	return &armError{http.StatusConflict, "AnotherOperationInProgress", fmt.Sprintf("Another operation is in progress on resource '%v'.", stringValue(res.document, "name"))}
+}
+
+func methodNotAllowed(method string) *armError {
+	return &armError{http.StatusMethodNotAllowed, "MethodNotAllowed", fmt.Sprintf("The requested method '%v' is not allowed.", method)}
+}
+
+// parentID returns the ID of the resource a resource or collection is nested in
+func parentID(id string) string {
+	segments := strings.Split(id, "/")
+	if len(segments) < 2 {
+		return ""
+	}
+	return strings.Join(segments[:len(segments)-2], "/")
+}
+
+// subscriptionOf returns the subscription of a resource ID
+func subscriptionOf(id string) string {
+	segments := strings.Split(strings.Trim(id, "/"), "/")
+	if len(segments) < 2 {
+		return ""
+	}
+	return segments[1]
+}
+
+// merge applies a JSON merge patch, nested objects are merged and null removes a value
+func merge(target, patch map[string]interface{}) {
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		if patchObject, ok := value.(map[string]interface{}); ok {
+			if targetObject, ok := target[key].(map[string]interface{}); ok {
+				merge(targetObject, patchObject)
+				continue
+			}
+		}
+		target[key] = value
+	}
+}
+
+// copyDocument returns a deep copy of a JSON document
+func copyDocument(document map[string]interface{}) map[string]interface{} {
+	content, _ := json.Marshal(document)
+	copied := map[string]interface{}{}
+	_ = json.Unmarshal(content, &copied)
+	return copied
+}
+
+// mapValue returns a nested object, creating it when missing
+func mapValue(document map[string]interface{}, key string) map[string]interface{} {
+	value, ok := document[key].(map[string]interface{})
+	if !ok {
+		value = map[string]interface{}{}
+		document[key] = value
+	}
+	return value
+}
+
+// stringValue returns a string value, empty when missing
+func stringValue(document map[string]interface{}, key string) string {
+	value, _ := document[key].(string)
+	return value
+}
+
+// numberValue returns a number value, zero when missing
+func numberValue(document map[string]interface{}, key string) float64 {
+	value, _ := document[key].(float64)
+	return value
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// newEtag returns a weak etag based on the current time
+func newEtag() string {
+	return fmt.Sprintf("W/\"datetime'%v'\"", time.Now().UTC().Format(time.RFC3339Nano))
+}
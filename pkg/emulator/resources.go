@@ -0,0 +1,845 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// Resource types served by the emulator, with the defaults and the
+// validations the Microsoft.NetApp resource provider applies to them.
+
+package emulator
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	gib = float64(1 << 30)
+	tib = float64(1 << 40)
+
+	minPoolSize   = 2 * tib
+	maxPoolSize   = 500 * tib
+	minVolumeSize = 100 * gib
+	maxVolumeSize = 100 * tib
+
+	maxSnapshotsPerVolume = 255
+	minDailyBackups       = 2
+	maxBackupsPerVolume   = 1019
+
+	// vaultName is the name of the backup vault the service creates in every account
+	vaultName = "vault1"
+
+	// transferSnapshotPrefix starts the name of the snapshot taken on the destination volume by every replication transfer
+	transferSnapshotPrefix = "snapmirror."
+)
+
+// replicationIntervals are the times between two scheduled transfers of a replication
+var replicationIntervals = map[string]time.Duration{
+	"_10minutely": 10 * time.Minute,
+	"hourly":      time.Hour,
+	"daily":       24 * time.Hour,
+}
+
+// kind describes a resource type
+type kind struct {
+	collection  string
+	typeName    string
+	parent      *kind
+	synchronous bool
+	// cascade lists the child types deleted together with the resource
+	cascade []*kind
+	// prepare validates a resource being created or updated and fills its read-only properties
+	prepare func(e *Emulator, res, existing, parent *resource) *armError
+	// created runs when the creation, replacement or update of a resource completes
+	created func(e *Emulator, res *resource)
+	// deletable fails when the resource cannot be deleted yet
+	deletable func(e *Emulator, res *resource) *armError
+	// removed runs when the deletion of a resource completes
+	removed func(e *Emulator, res *resource)
+}
+
+// replication is a cross region replication between two volumes, keyed by the destination volume
+type replication struct {
+	sourceID           string
+	destinationID      string
+	authorized         bool
+	mirrorState        string
+	relationshipStatus string
+	transferred        float64
+	lastTransfer       time.Time
+}
+
+var (
+	accounts         = &kind{collection: "netAppAccounts", typeName: "Microsoft.NetApp/netAppAccounts"}
+	snapshotPolicies = &kind{collection: "snapshotPolicies", typeName: "Microsoft.NetApp/netAppAccounts/snapshotPolicies", parent: accounts, synchronous: true}
+	pools            = &kind{collection: "capacityPools", typeName: "Microsoft.NetApp/netAppAccounts/capacityPools", parent: accounts}
+	volumes          = &kind{collection: "volumes", typeName: "Microsoft.NetApp/netAppAccounts/capacityPools/volumes", parent: pools}
+	snapshots        = &kind{collection: "snapshots", typeName: "Microsoft.NetApp/netAppAccounts/capacityPools/volumes/snapshots", parent: volumes}
+	backupPolicies   = &kind{collection: "backupPolicies", typeName: "Microsoft.NetApp/netAppAccounts/backupPolicies", parent: accounts}
+	vaults           = &kind{collection: "vaults", typeName: "Microsoft.NetApp/netAppAccounts/vaults", parent: accounts, synchronous: true}
+	backups          = &kind{collection: "backups", typeName: "Microsoft.NetApp/netAppAccounts/capacityPools/volumes/backups", parent: volumes}
+
+	kinds = []*kind{accounts, snapshotPolicies, pools, volumes, snapshots, backupPolicies, vaults, backups}
+
+	// throughputPerTiB is the throughput in MiB/s each TiB of a service level provides
+	throughputPerTiB = map[string]float64{"Standard": 16, "StandardZRS": 32, "Premium": 64, "Ultra": 128}
+)
+
+func init() {
+	accounts.prepare = prepareAccount
+	accounts.created = createdAccount
+	accounts.cascade = []*kind{snapshotPolicies, backupPolicies, vaults}
+	accounts.deletable = func(e *Emulator, res *resource) *armError {
+		return blockingChildren(e, res, pools)
+	}
+
+	pools.prepare = preparePool
+	pools.deletable = func(e *Emulator, res *resource) *armError {
+		return blockingChildren(e, res, volumes)
+	}
+
+	snapshotPolicies.prepare = func(e *Emulator, res, existing, parent *resource) *armError { return nil }
+	snapshotPolicies.deletable = deletableSnapshotPolicy
+
+	volumes.prepare = prepareVolume
+	volumes.created = createdVolume
+	volumes.cascade = []*kind{snapshots}
+	volumes.deletable = deletableVolume
+	volumes.removed = func(e *Emulator, res *resource) {
+		updatePoolThroughput(e, parentID(res.id))
+		updateBackupPolicyVolumes(e)
+	}
+
+	snapshots.prepare = prepareSnapshot
+
+	backupPolicies.prepare = prepareBackupPolicy
+	backupPolicies.deletable = deletableBackupPolicy
+
+	// The vault of an account is created by the service, it cannot be created, changed or deleted through the API
+	vaults.prepare = func(e *Emulator, res, existing, parent *resource) *armError { return vaultNotSupported() }
+	vaults.deletable = func(e *Emulator, res *resource) *armError { return vaultNotSupported() }
+
+	// Backups are kept in the vault, they outlive the volume they were taken of
+	backups.prepare = prepareBackup
+}
+
+// kindOf returns the resource type of a collection path segment nested in parent
+func kindOf(collection string, parent *kind) *kind {
+	for _, k := range kinds {
+		if strings.EqualFold(k.collection, collection) && k.parent == parent {
+			return k
+		}
+	}
+	return nil
+}
+
+// blockingChildren fails when a resource still has children of a kind
+func blockingChildren(e *Emulator, res *resource, k *kind) *armError {
+	children := e.children(res.id, k)
+	if len(children) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(children))
+	for _, child := range children {
+		names = append(names, stringValue(child.document, "name"))
+	}
+	return &armError{http.StatusConflict, "CannotDeleteResource", fmt.Sprintf("Cannot delete resource while nested resources exist. Some existing nested resource IDs include: %v. Please delete all nested resources before deleting this resource.", strings.Join(names, ", "))}
+}
+
+func prepareAccount(e *Emulator, res, existing, parent *resource) *armError {
+	properties := mapValue(res.document, "properties")
+
+	// Passwords are write only
+	if directories, ok := properties["activeDirectories"].([]interface{}); ok {
+		for _, directory := range directories {
+			if fields, ok := directory.(map[string]interface{}); ok {
+				delete(fields, "password")
+				if stringValue(fields, "activeDirectoryId") == "" {
+					fields["activeDirectoryId"] = newUUID()
+				}
+				if stringValue(fields, "status") == "" {
+					fields["status"] = "Created"
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// createdAccount adds the backup vault the service creates in every account
+func createdAccount(e *Emulator, res *resource) {
+	if len(e.children(res.id, vaults)) > 0 {
+		return
+	}
+
+	id := fmt.Sprintf("%v/%v/%v", res.id, vaults.collection, vaultName)
+	e.resources[strings.ToLower(id)] = &resource{
+		kind: vaults,
+		id:   id,
+		document: map[string]interface{}{
+			"id":       id,
+			"name":     fmt.Sprintf("%v/%v", stringValue(res.document, "name"), vaultName),
+			"type":     vaults.typeName,
+			"location": res.document["location"],
+			"properties": map[string]interface{}{
+				"vaultName": vaultName,
+			},
+		},
+		created: time.Now(),
+	}
+}
+
+func preparePool(e *Emulator, res, existing, parent *resource) *armError {
+	properties := mapValue(res.document, "properties")
+
+	serviceLevel := stringValue(properties, "serviceLevel")
+	perTiB, found := throughputPerTiB[serviceLevel]
+	if !found {
+		return badRequest("InvalidServiceLevel", fmt.Sprintf("Service level '%v' is invalid, supported values are Standard, Premium, Ultra and StandardZRS.", serviceLevel))
+	}
+	if existing != nil && serviceLevel != stringValue(mapValue(existing.document, "properties"), "serviceLevel") {
+		return badRequest("PoolServiceLevelChangeNotSupported", "The service level of a capacity pool cannot be changed.")
+	}
+
+	size := numberValue(properties, "size")
+	if size < minPoolSize || size > maxPoolSize || int64(size)%int64(tib) != 0 {
+		return badRequest("InvalidPoolSize", fmt.Sprintf("Pool size %v is invalid, it must be a multiple of 1 TiB between 2 TiB and 500 TiB.", size))
+	}
+	if used := usedQuota(e, res.id, ""); used > size {
+		return badRequest("PoolSizeTooSmall", fmt.Sprintf("The pool size %v is smaller than the %v bytes provisioned by its volumes.", size, used))
+	}
+
+	if stringValue(properties, "qosType") == "" {
+		properties["qosType"] = "Auto"
+	}
+	if stringValue(properties, "poolId") == "" {
+		properties["poolId"] = newUUID()
+	}
+	if stringValue(properties, "encryptionType") == "" {
+		properties["encryptionType"] = "Single"
+	}
+	properties["totalThroughputMibps"] = perTiB * size / tib
+	properties["utilizedThroughputMibps"] = utilizedThroughput(e, res.id)
+
+	return nil
+}
+
+func prepareVolume(e *Emulator, res, existing, parent *resource) *armError {
+	properties := mapValue(res.document, "properties")
+	poolProperties := mapValue(parent.document, "properties")
+	problems := []string{}
+
+	if stringValue(properties, "creationToken") == "" {
+		problems = append(problems, "creationToken is required")
+	}
+	subnetID := stringValue(properties, "subnetId")
+	if !strings.Contains(strings.ToLower(subnetID), "/subnets/") {
+		return badRequest("InvalidSubnet", fmt.Sprintf("The subnetId '%v' is not a subnet resource ID.", subnetID))
+	}
+	quota := numberValue(properties, "usageThreshold")
+	if quota < minVolumeSize || quota > maxVolumeSize {
+		problems = append(problems, fmt.Sprintf("usageThreshold %v must be between 100 GiB and 100 TiB", quota))
+	}
+
+	protocols, _ := properties["protocolTypes"].([]interface{})
+	if len(protocols) == 0 {
+		protocols = []interface{}{"NFSv3"}
+		properties["protocolTypes"] = protocols
+	}
+	if existing != nil {
+		current := mapValue(existing.document, "properties")
+		for _, field := range []string{"creationToken", "subnetId"} {
+			if stringValue(properties, field) != stringValue(current, field) {
+				problems = append(problems, fmt.Sprintf("%v cannot be changed", field))
+			}
+		}
+		if fmt.Sprint(properties["protocolTypes"]) != fmt.Sprint(current["protocolTypes"]) {
+			problems = append(problems, "protocolTypes cannot be changed")
+		}
+	}
+
+	if snapshotID := stringValue(properties, "snapshotId"); snapshotID != "" && existing == nil {
+		if findSnapshot(e, "", snapshotID) == nil {
+			problems = append(problems, fmt.Sprintf("snapshot '%v' was not found", snapshotID))
+		}
+	}
+
+	dataProtection, _ := properties["dataProtection"].(map[string]interface{})
+	if policy, ok := dataProtection["snapshot"].(map[string]interface{}); ok {
+		if policyID := stringValue(policy, "snapshotPolicyId"); policyID != "" && e.live(policyID) == nil {
+			problems = append(problems, fmt.Sprintf("snapshot policy '%v' was not found", policyID))
+		}
+	}
+	if backup, ok := dataProtection["backup"].(map[string]interface{}); ok {
+		if policyID := stringValue(backup, "backupPolicyId"); policyID != "" && e.live(policyID) == nil {
+			problems = append(problems, fmt.Sprintf("backup policy '%v' was not found", policyID))
+		}
+		if enabled, _ := backup["backupEnabled"].(bool); enabled && e.live(stringValue(backup, "vaultId")) == nil {
+			problems = append(problems, fmt.Sprintf("backup vault '%v' was not found", stringValue(backup, "vaultId")))
+		}
+	}
+	if backupID := stringValue(properties, "backupId"); backupID != "" && existing == nil {
+		if findBackup(e, backupID) == nil {
+			problems = append(problems, fmt.Sprintf("backup '%v' was not found", backupID))
+		}
+	}
+	if replicationObject, ok := dataProtection["replication"].(map[string]interface{}); ok && existing == nil {
+		if !strings.EqualFold(stringValue(replicationObject, "endpointType"), "dst") {
+			problems = append(problems, "replication endpointType must be dst, replications are created on the destination volume")
+		}
+		if e.live(stringValue(replicationObject, "remoteVolumeResourceId")) == nil {
+			problems = append(problems, fmt.Sprintf("remote volume '%v' was not found", stringValue(replicationObject, "remoteVolumeResourceId")))
+		}
+	}
+
+	if len(problems) > 0 {
+		return badRequest("InvalidVolumeDefinition", fmt.Sprintf("The volume definition is invalid: %v.", strings.Join(problems, "; ")))
+	}
+
+	if used := usedQuota(e, parent.id, res.id) + quota; used > numberValue(poolProperties, "size") {
+		return badRequest("PoolSizeTooSmall", fmt.Sprintf("The capacity pool does not have enough space for a volume of %v bytes.", quota))
+	}
+
+	serviceLevel := stringValue(properties, "serviceLevel")
+	if serviceLevel == "" {
+		serviceLevel = stringValue(poolProperties, "serviceLevel")
+		properties["serviceLevel"] = serviceLevel
+	}
+
+	nfs, cifs := false, false
+	for _, protocol := range protocols {
+		switch protocol {
+		case "NFSv3", "NFSv4.1":
+			nfs = true
+		case "CIFS":
+			cifs = true
+		}
+	}
+	if stringValue(properties, "securityStyle") == "" {
+		properties["securityStyle"] = "unix"
+		if cifs && !nfs {
+			properties["securityStyle"] = "ntfs"
+		}
+	}
+	if _, found := properties["exportPolicy"]; !found && nfs {
+		properties["exportPolicy"] = map[string]interface{}{
+			"rules": []interface{}{map[string]interface{}{
+				"ruleIndex":      1,
+				"allowedClients": "0.0.0.0/0",
+				"unixReadOnly":   false,
+				"unixReadWrite":  true,
+				"nfsv3":          true,
+				"nfsv41":         false,
+				"cifs":           false,
+				"hasRootAccess":  true,
+			}},
+		}
+	}
+
+	if existing == nil {
+		fileSystemID := newUUID()
+		properties["fileSystemId"] = fileSystemID
+		e.ipAddresses++
+		properties["mountTargets"] = []interface{}{map[string]interface{}{
+			"mountTargetId": newUUID(),
+			"fileSystemId":  fileSystemID,
+			"ipAddress":     fmt.Sprintf("10.0.%v.%v", 1+e.ipAddresses/250, 4+e.ipAddresses%250),
+		}}
+		if stringValue(properties, "networkFeatures") == "" {
+			properties["networkFeatures"] = "Basic"
+		}
+		if replicationObject, ok := dataProtection["replication"].(map[string]interface{}); ok {
+			properties["volumeType"] = "DataProtection"
+			replicationObject["endpointType"] = "dst"
+		}
+	}
+
+	if strings.EqualFold(stringValue(poolProperties, "qosType"), "Auto") {
+		properties["throughputMibps"] = throughputPerTiB[serviceLevel] * quota / tib
+	}
+
+	return nil
+}
+
+// createdVolume refreshes the throughput of the pool and the volume counts of the backup policies,
+// and registers the replication of a new destination volume
+func createdVolume(e *Emulator, res *resource) {
+	updatePoolThroughput(e, parentID(res.id))
+	updateBackupPolicyVolumes(e)
+
+	properties := mapValue(res.document, "properties")
+	dataProtection, _ := properties["dataProtection"].(map[string]interface{})
+	replicationObject, ok := dataProtection["replication"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	key := strings.ToLower(res.id)
+	if _, found := e.replications[key]; !found {
+		e.replications[key] = &replication{
+			sourceID:           stringValue(replicationObject, "remoteVolumeResourceId"),
+			destinationID:      res.id,
+			mirrorState:        "Uninitialized",
+			relationshipStatus: "Idle",
+		}
+	}
+}
+
+func deletableVolume(e *Emulator, res *resource) *armError {
+	if e.replicationOf(res.id) != nil {
+		return &armError{http.StatusConflict, "VolumeReplicationExists", fmt.Sprintf("Volume '%v' is part of a replication, delete the replication first.", stringValue(res.document, "name"))}
+	}
+	return nil
+}
+
+func deletableSnapshotPolicy(e *Emulator, res *resource) *armError {
+	for _, volume := range e.resources {
+		if volume.kind != volumes || !volume.deleted.IsZero() {
+			continue
+		}
+		dataProtection, _ := mapValue(volume.document, "properties")["dataProtection"].(map[string]interface{})
+		policy, _ := dataProtection["snapshot"].(map[string]interface{})
+		if strings.EqualFold(stringValue(policy, "snapshotPolicyId"), res.id) {
+			return &armError{http.StatusConflict, "SnapshotPolicyInUse", fmt.Sprintf("Snapshot policy '%v' is assigned to volume '%v'.", stringValue(res.document, "name"), stringValue(volume.document, "name"))}
+		}
+	}
+	return nil
+}
+
+func prepareSnapshot(e *Emulator, res, existing, parent *resource) *armError {
+	if existing == nil && len(e.children(parent.id, snapshots)) >= maxSnapshotsPerVolume {
+		return badRequest("MaxSnapshotsReached", fmt.Sprintf("Volume '%v' already has the maximum of %v snapshots.", stringValue(parent.document, "name"), maxSnapshotsPerVolume))
+	}
+
+	properties := mapValue(res.document, "properties")
+	if existing != nil {
+		return nil
+	}
+	properties["snapshotId"] = newUUID()
+	properties["created"] = res.created.UTC().Format(time.RFC3339Nano)
+	return nil
+}
+
+func prepareBackupPolicy(e *Emulator, res, existing, parent *resource) *armError {
+	properties := mapValue(res.document, "properties")
+
+	daily := numberValue(properties, "dailyBackupsToKeep")
+	total := daily + numberValue(properties, "weeklyBackupsToKeep") + numberValue(properties, "monthlyBackupsToKeep")
+	if daily < minDailyBackups {
+		return badRequest("InvalidBackupPolicy", fmt.Sprintf("dailyBackupsToKeep must be at least %v.", minDailyBackups))
+	}
+	if total > maxBackupsPerVolume {
+		return badRequest("InvalidBackupPolicy", fmt.Sprintf("A backup policy can keep at most %v backups.", maxBackupsPerVolume))
+	}
+
+	if stringValue(properties, "backupPolicyId") == "" {
+		properties["backupPolicyId"] = newUUID()
+	}
+	properties["volumesAssigned"] = len(volumesWithBackupPolicy(e, res.id))
+	return nil
+}
+
+func deletableBackupPolicy(e *Emulator, res *resource) *armError {
+	if assigned := volumesWithBackupPolicy(e, res.id); len(assigned) > 0 {
+		return &armError{http.StatusConflict, "BackupPolicyInUse", fmt.Sprintf("Backup policy '%v' is assigned to volume '%v'.", stringValue(res.document, "name"), stringValue(assigned[0].document, "name"))}
+	}
+	return nil
+}
+
+// volumesWithBackupPolicy returns the live volumes a backup policy is assigned to
+func volumesWithBackupPolicy(e *Emulator, policyID string) []*resource {
+	assigned := []*resource{}
+	for _, volume := range e.resources {
+		if volume.kind != volumes || !volume.deleted.IsZero() {
+			continue
+		}
+		dataProtection, _ := mapValue(volume.document, "properties")["dataProtection"].(map[string]interface{})
+		backup, _ := dataProtection["backup"].(map[string]interface{})
+		if strings.EqualFold(stringValue(backup, "backupPolicyId"), policyID) {
+			assigned = append(assigned, volume)
+		}
+	}
+	return assigned
+}
+
+// updateBackupPolicyVolumes refreshes the number of volumes every backup policy is assigned to
+func updateBackupPolicyVolumes(e *Emulator) {
+	for _, policy := range e.resources {
+		if policy.kind == backupPolicies && policy.deleted.IsZero() {
+			mapValue(policy.document, "properties")["volumesAssigned"] = len(volumesWithBackupPolicy(e, policy.id))
+		}
+	}
+}
+
+func prepareBackup(e *Emulator, res, existing, parent *resource) *armError {
+	properties := mapValue(res.document, "properties")
+	if existing != nil {
+		return nil
+	}
+
+	volumeProperties := mapValue(parent.document, "properties")
+	dataProtection, _ := volumeProperties["dataProtection"].(map[string]interface{})
+	backup, _ := dataProtection["backup"].(map[string]interface{})
+	if enabled, _ := backup["backupEnabled"].(bool); !enabled {
+		return badRequest("BackupNotEnabled", fmt.Sprintf("Backup is not enabled on volume '%v'.", stringValue(parent.document, "name")))
+	}
+	if len(e.children(parent.id, backups)) >= maxBackupsPerVolume {
+		return badRequest("MaxBackupsReached", fmt.Sprintf("Volume '%v' already has the maximum of %v backups.", stringValue(parent.document, "name"), maxBackupsPerVolume))
+	}
+
+	properties["backupId"] = newUUID()
+	properties["backupType"] = "Manual"
+	properties["creationDate"] = res.created.UTC().Format(time.RFC3339Nano)
+	properties["volumeName"] = stringValue(parent.document, "name")
+	properties["size"] = numberValue(volumeProperties, "usageThreshold") / 100
+	return nil
+}
+
+func vaultNotSupported() *armError {
+	return badRequest("OperationNotSupported", "Backup vaults are created and managed by the service.")
+}
+
+// volumeAction runs a POST action, or the replicationStatus GET, on a volume
+func (e *Emulator) volumeAction(w http.ResponseWriter, r *http.Request, route *route) *armError {
+	volume := e.live(route.id)
+	if volume == nil {
+		return resourceNotFound(route)
+	}
+
+	if strings.EqualFold(route.action, "replicationStatus") {
+		if r.Method != http.MethodGet {
+			return methodNotAllowed(r.Method)
+		}
+		rep := e.replicationOf(volume.id)
+		if rep == nil {
+			return replicationNotFound(volume)
+		}
+		// Scheduled transfers are caught up when the status is read
+		if rep.mirrorState == "Mirrored" && time.Since(rep.lastTransfer) >= e.replicationInterval(rep) {
+			e.transfer(rep)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"healthy":            rep.authorized,
+			"relationshipStatus": rep.relationshipStatus,
+			"mirrorState":        rep.mirrorState,
+			"totalProgress":      fmt.Sprint(int64(rep.transferred)),
+		})
+		return nil
+	}
+
+	if r.Method != http.MethodPost {
+		return methodNotAllowed(r.Method)
+	}
+	if volume.operation != nil {
+		return anotherOperation(volume)
+	}
+	body, apiErr := readBody(r)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	var complete func()
+	switch strings.ToLower(route.action) {
+	case "revert":
+		snapshot := findSnapshot(e, volume.id, stringValue(body, "snapshotId"))
+		if snapshot == nil {
+			return badRequest("SnapshotNotFound", fmt.Sprintf("Snapshot '%v' was not found on volume '%v'.", stringValue(body, "snapshotId"), stringValue(volume.document, "name")))
+		}
+		complete = func() {
+			// Reverting deletes the snapshots taken after the one reverted to
+			for _, newer := range e.children(volume.id, snapshots) {
+				if newer.created.After(snapshot.created) {
+					newer.deleted = time.Now()
+				}
+			}
+		}
+
+	case "authorizereplication":
+		destinationID := stringValue(body, "remoteVolumeResourceId")
+		rep := e.replications[strings.ToLower(destinationID)]
+		if rep == nil || !strings.EqualFold(rep.sourceID, volume.id) {
+			return badRequest("ReplicationNotFound", fmt.Sprintf("Volume '%v' is not the destination of a replication from '%v'.", destinationID, volume.id))
+		}
+		complete = func() {
+			rep.authorized = true
+			rep.mirrorState = "Mirrored"
+			rep.relationshipStatus = "Idle"
+			rep.transferred = 0
+			e.setReplicationEndpoints(rep)
+			e.transfer(rep)
+		}
+
+	case "breakreplication":
+		rep := e.replicationOf(volume.id)
+		if rep == nil {
+			return replicationNotFound(volume)
+		}
+		if !strings.EqualFold(rep.destinationID, volume.id) {
+			return badRequest("VolumeNotReplicationDestination", fmt.Sprintf("Volume '%v' is not the destination of its replication, break the replication on the destination volume.", stringValue(volume.document, "name")))
+		}
+		if rep.mirrorState != "Mirrored" {
+			return badRequest("ReplicationNotMirrored", fmt.Sprintf("The replication of volume '%v' is %v, only a mirrored replication can be broken.", stringValue(volume.document, "name"), rep.mirrorState))
+		}
+		if force, _ := body["forceBreakReplication"].(bool); rep.relationshipStatus == "Transferring" && !force {
+			return &armError{http.StatusConflict, "ReplicationTransferInProgress", fmt.Sprintf("A transfer of the replication of volume '%v' is in progress, force the break to interrupt it.", stringValue(volume.document, "name"))}
+		}
+		complete = func() {
+			rep.mirrorState = "Broken"
+			rep.relationshipStatus = "Idle"
+		}
+
+	case "resyncreplication":
+		rep := e.replicationOf(volume.id)
+		if rep == nil {
+			return replicationNotFound(volume)
+		}
+		if rep.mirrorState != "Broken" {
+			return badRequest("ReplicationNotBroken", fmt.Sprintf("The replication of volume '%v' is %v, only a broken replication can be resynced.", stringValue(volume.document, "name"), rep.mirrorState))
+		}
+		complete = func() {
+			// Resyncing from the source reverses the replication, the source receives the changes of the destination
+			if strings.EqualFold(rep.sourceID, volume.id) {
+				delete(e.replications, strings.ToLower(rep.destinationID))
+				rep.sourceID, rep.destinationID = rep.destinationID, rep.sourceID
+				e.replications[strings.ToLower(rep.destinationID)] = rep
+			}
+			rep.mirrorState = "Mirrored"
+			rep.relationshipStatus = "Idle"
+			e.setReplicationEndpoints(rep)
+			e.transfer(rep)
+		}
+
+	case "reinitializereplication":
+		rep := e.replicationOf(volume.id)
+		if rep == nil {
+			return replicationNotFound(volume)
+		}
+		if !strings.EqualFold(rep.destinationID, volume.id) {
+			return badRequest("VolumeNotReplicationDestination", fmt.Sprintf("Volume '%v' is not the destination of its replication, reinitialize the replication on the destination volume.", stringValue(volume.document, "name")))
+		}
+		if !rep.authorized {
+			return badRequest("ReplicationNotAuthorized", fmt.Sprintf("The replication of volume '%v' was not authorized on its source volume.", stringValue(volume.document, "name")))
+		}
+		if rep.mirrorState == "Mirrored" {
+			return badRequest("ReplicationAlreadyMirrored", fmt.Sprintf("The replication of volume '%v' is already mirrored.", stringValue(volume.document, "name")))
+		}
+		complete = func() {
+			rep.mirrorState = "Mirrored"
+			rep.relationshipStatus = "Idle"
+			rep.transferred = 0
+			e.transfer(rep)
+		}
+
+	case "deletereplication":
+		rep := e.replicationOf(volume.id)
+		if rep == nil {
+			return replicationNotFound(volume)
+		}
+		complete = func() {
+			delete(e.replications, strings.ToLower(rep.destinationID))
+			for _, id := range []string{rep.sourceID, rep.destinationID} {
+				if endpoint := e.live(id); endpoint != nil {
+					properties := mapValue(endpoint.document, "properties")
+					delete(mapValue(properties, "dataProtection"), "replication")
+					delete(properties, "volumeType")
+				}
+			}
+		}
+
+	default:
+		return &armError{http.StatusBadRequest, "InvalidResourceType", fmt.Sprintf("The action '%v' is not supported on volumes.", route.action)}
+	}
+
+	e.start(w, r, volume, complete)
+	w.WriteHeader(http.StatusAccepted)
+	return nil
+}
+
+// replicationOf returns the replication a volume is the source or the destination of
+func (e *Emulator) replicationOf(volumeID string) *replication {
+	if rep := e.replications[strings.ToLower(volumeID)]; rep != nil {
+		return rep
+	}
+	for _, rep := range e.replications {
+		if strings.EqualFold(rep.sourceID, volumeID) {
+			return rep
+		}
+	}
+	return nil
+}
+
+// setReplicationEndpoints writes the replication settings of both volumes of a replication: the destination is
+// a DataProtection volume referencing the source, the source references the destination
+func (e *Emulator) setReplicationEndpoints(rep *replication) {
+	source := e.live(rep.sourceID)
+	destination := e.live(rep.destinationID)
+	if source == nil || destination == nil {
+		return
+	}
+
+	schedule := e.replicationSchedule(rep)
+	for _, endpoint := range []struct {
+		volume       *resource
+		endpointType string
+		remote       *resource
+	}{
+		{source, "src", destination},
+		{destination, "dst", source},
+	} {
+		properties := mapValue(endpoint.volume.document, "properties")
+		dataProtection, _ := properties["dataProtection"].(map[string]interface{})
+		if dataProtection == nil {
+			dataProtection = map[string]interface{}{}
+			properties["dataProtection"] = dataProtection
+		}
+		dataProtection["replication"] = map[string]interface{}{
+			"endpointType":           endpoint.endpointType,
+			"remoteVolumeResourceId": endpoint.remote.id,
+			"remoteVolumeRegion":     endpoint.remote.document["location"],
+			"replicationSchedule":    schedule,
+		}
+		if endpoint.endpointType == "dst" {
+			properties["volumeType"] = "DataProtection"
+		} else {
+			delete(properties, "volumeType")
+		}
+	}
+}
+
+// replicationSchedule returns the schedule of a replication, set on its destination volume
+func (e *Emulator) replicationSchedule(rep *replication) string {
+	for _, id := range []string{rep.destinationID, rep.sourceID} {
+		if volume := e.live(id); volume != nil {
+			dataProtection, _ := mapValue(volume.document, "properties")["dataProtection"].(map[string]interface{})
+			replicationObject, _ := dataProtection["replication"].(map[string]interface{})
+			if schedule := stringValue(replicationObject, "replicationSchedule"); schedule != "" {
+				return schedule
+			}
+		}
+	}
+	return "hourly"
+}
+
+// replicationInterval returns the time between two scheduled transfers of a replication
+func (e *Emulator) replicationInterval(rep *replication) time.Duration {
+	if interval, found := replicationIntervals[strings.ToLower(e.replicationSchedule(rep))]; found {
+		return interval
+	}
+	return time.Hour
+}
+
+// transfer runs a replication transfer: the destination volume gets a new transfer snapshot replacing the previous ones,
+// a first transfer copies the whole source volume and the following ones a part of it
+func (e *Emulator) transfer(rep *replication) {
+	source := e.live(rep.sourceID)
+	destination := e.live(rep.destinationID)
+	if source == nil || destination == nil {
+		return
+	}
+
+	for _, snapshot := range e.children(destination.id, snapshots) {
+		if strings.HasPrefix(snapshotName(snapshot), transferSnapshotPrefix) {
+			snapshot.deleted = time.Now()
+		}
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%v%v_%v", transferSnapshotPrefix, newUUID(), now.Unix())
+	id := fmt.Sprintf("%v/%v/%v", destination.id, snapshots.collection, name)
+	e.resources[strings.ToLower(id)] = &resource{
+		kind: snapshots,
+		id:   id,
+		document: map[string]interface{}{
+			"id":       id,
+			"name":     fmt.Sprintf("%v/%v", stringValue(destination.document, "name"), name),
+			"type":     snapshots.typeName,
+			"location": destination.document["location"],
+			"properties": map[string]interface{}{
+				"snapshotId":        newUUID(),
+				"created":           now.UTC().Format(time.RFC3339Nano),
+				"provisioningState": stateSucceeded,
+			},
+		},
+		created: now,
+	}
+
+	usage := numberValue(mapValue(source.document, "properties"), "usageThreshold") / 100
+	if rep.transferred > 0 {
+		usage /= 10
+	}
+	rep.transferred += usage
+	rep.lastTransfer = now
+}
+
+// snapshotName returns the name of a snapshot without the names of its account, pool and volume
+func snapshotName(snapshot *resource) string {
+	return snapshot.id[strings.LastIndex(snapshot.id, "/")+1:]
+}
+
+// findSnapshot finds a live snapshot by resource ID or snapshot ID, limited to a volume unless volumeID is empty
+func findSnapshot(e *Emulator, volumeID, snapshotID string) *resource {
+	if snapshotID == "" {
+		return nil
+	}
+	for _, res := range e.resources {
+		if res.kind != snapshots || !res.deleted.IsZero() || (volumeID != "" && !strings.EqualFold(parentID(res.id), volumeID)) {
+			continue
+		}
+		if strings.EqualFold(res.id, snapshotID) || strings.EqualFold(stringValue(mapValue(res.document, "properties"), "snapshotId"), snapshotID) {
+			return res
+		}
+	}
+	return nil
+}
+
+// findBackup finds a backup by resource ID or backup ID, the backups of deleted volumes included
+func findBackup(e *Emulator, backupID string) *resource {
+	for _, res := range e.resources {
+		if res.kind != backups || !res.deleted.IsZero() {
+			continue
+		}
+		if strings.EqualFold(res.id, backupID) || strings.EqualFold(stringValue(mapValue(res.document, "properties"), "backupId"), backupID) {
+			return res
+		}
+	}
+	return nil
+}
+
+// usedQuota sums the quota of the volumes of a pool, leaving out one volume
+func usedQuota(e *Emulator, poolID, exceptVolumeID string) float64 {
+	used := 0.0
+	for _, volume := range e.children(poolID, volumes) {
+		if !strings.EqualFold(volume.id, exceptVolumeID) {
+			used += numberValue(mapValue(volume.document, "properties"), "usageThreshold")
+		}
+	}
+	return used
+}
+
+// utilizedThroughput sums the throughput of the volumes of a pool
+func utilizedThroughput(e *Emulator, poolID string) float64 {
+	used := 0.0
+	for _, volume := range e.children(poolID, volumes) {
+		used += numberValue(mapValue(volume.document, "properties"), "throughputMibps")
+	}
+	return used
+}
+
+// updatePoolThroughput refreshes the utilized throughput of a pool after its volumes changed
+func updatePoolThroughput(e *Emulator, poolID string) {
+	if pool := e.live(poolID); pool != nil {
+		mapValue(pool.document, "properties")["utilizedThroughputMibps"] = utilizedThroughput(e, poolID)
+	}
+}
+
+func badRequest(code, message string) *armError {
+	return &armError{http.StatusBadRequest, code, message}
+}
+
+func replicationNotFound(volume *resource) *armError {
+	return &armError{http.StatusNotFound, "VolumeReplicationMissing", fmt.Sprintf("Volume '%v' is not part of a replication.", stringValue(volume.document, "name"))}
+}
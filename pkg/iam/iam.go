@@ -23,14 +23,23 @@ import (
 	"github.com/patrikcze/go-anf/pkg/utils"
 )
 
-// GetAuthorizer gets an authorization token to be used within ANF client
-func GetAuthorizer() (azcore.TokenCredential, string, error) {
+// GetAuthorizer gets an authorization token to be used within ANF client, a non-empty
+// subscriptionID or tenantID replaces the one of the authentication file
+func GetAuthorizer(subscriptionID, tenantID string) (azcore.TokenCredential, string, error) {
 	// Getting information from authentication file
 	info, err := readAuthJSON(os.Getenv("AZURE_AUTH_LOCATION"))
 	if err != nil {
 		return nil, "", err
 	}
 
+	// Subscription and tenant can be overridden, e.g. by the active go-anf profile
+	if subscriptionID != "" {
+		info.SubscriptionID = &subscriptionID
+	}
+	if tenantID != "" {
+		info.TenantID = &tenantID
+	}
+
 	authorizer, err := azidentity.NewClientSecretCredential(*info.TenantID, *info.ClientID, *info.ClientSecret, nil)
 	if err != nil {
 		utils.ConsoleOutput(fmt.Sprintf("%v", err))
@@ -40,6 +49,26 @@ func GetAuthorizer() (azcore.TokenCredential, string, error) {
 	return authorizer, *info.SubscriptionID, nil
 }
 
+// WriteAuthJSON writes an Azure Authentication file in the format created by
+// az ad sp create-for-rbac --sdk-auth, readable by the current user only
+func WriteAuthJSON(path string, info *models.AzureAuthInfo) error {
+	authJSON, err := json.MarshalIndent(map[string]*string{
+		"clientId":       info.ClientID,
+		"clientSecret":   info.ClientSecret,
+		"subscriptionId": info.SubscriptionID,
+		"tenantId":       info.TenantID,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, authJSON, 0600); err != nil {
+		return fmt.Errorf("failed to write file: %v", err)
+	}
+
+	return nil
+}
+
 // readAuthJSON reads the Azure Authentication json file json file and unmarshals it.
 func readAuthJSON(path string) (*models.AzureAuthInfo, error) {
 	infoJSON, err := ioutil.ReadFile(path)
@@ -0,0 +1,152 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// This package keeps the journal of the long-running operations started
+// by go-anf, one JSON file per operation, so that operations interrupted
+// by a crash or Ctrl-C can be resumed by a later process.
+
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+)
+
+const (
+	// retention is how long finished operations are kept in the journal
+	retention = 7 * 24 * time.Hour
+
+	fileExtension = ".json"
+)
+
+// Journal object definition, implements sdkutils.Journal
+type Journal struct {
+	dir string
+}
+
+// DefaultDir returns the directory of the journal, $HOME/.go-anf/operations
+func DefaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot find home directory: %v", err)
+	}
+
+	return filepath.Join(home, ".go-anf", "operations"), nil
+}
+
+// Open opens the journal in dir, creating the directory when missing
+func Open(dir string) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("cannot create operations journal: %v", err)
+	}
+
+	return &Journal{dir: dir}, nil
+}
+
+// Save writes an operation, finished operations older than the retention are removed along the way
+func (j *Journal) Save(operation *sdkutils.Operation) error {
+	content, err := json.MarshalIndent(operation, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot serialize operation: %v", err)
+	}
+
+	// Write and rename so a reader never sees a partial file
+	path := j.path(operation.ID)
+	if err := os.WriteFile(path+".tmp", content, 0600); err != nil {
+		return fmt.Errorf("cannot write operation %v: %v", operation.ID, err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("cannot write operation %v: %v", operation.ID, err)
+	}
+
+	if operation.FinishedAt != nil {
+		j.prune()
+	}
+
+	return nil
+}
+
+// Load reads an operation by its ID or a unique prefix of it
+func (j *Journal) Load(id string) (*sdkutils.Operation, error) {
+	operations, err := j.List()
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []*sdkutils.Operation{}
+	for _, operation := range operations {
+		if operation.ID == id {
+			return operation, nil
+		}
+		if strings.HasPrefix(operation.ID, id) {
+			matches = append(matches, operation)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("operation %v not found", id)
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("operation ID %v is ambiguous, it matches %v operations", id, len(matches))
+}
+
+// List reads all operations, the most recently started first
+func (j *Journal) List() ([]*sdkutils.Operation, error) {
+	entries, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read operations journal: %v", err)
+	}
+
+	operations := []*sdkutils.Operation{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != fileExtension {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(j.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read operation: %v", err)
+		}
+		operation := &sdkutils.Operation{}
+		if err := json.Unmarshal(content, operation); err != nil {
+			return nil, fmt.Errorf("cannot parse operation file %v: %v", entry.Name(), err)
+		}
+		operations = append(operations, operation)
+	}
+
+	sort.Slice(operations, func(a, b int) bool {
+		return operations[a].StartedAt.After(operations[b].StartedAt)
+	})
+
+	return operations, nil
+}
+
+// prune removes the operations that finished before the retention
+func (j *Journal) prune() {
+	operations, err := j.List()
+	if err != nil {
+		return
+	}
+
+	for _, operation := range operations {
+		if operation.FinishedAt != nil && time.Since(*operation.FinishedAt) > retention {
+			os.Remove(j.path(operation.ID))
+		}
+	}
+}
+
+// path returns the file of an operation
+func (j *Journal) path(id string) string {
+	return filepath.Join(j.dir, id+fileExtension)
+}
@@ -21,6 +21,30 @@ type AzureAuthInfo struct {
 	ManagementEndpointURL          *string
 }
 
+// SnapshotPolicySchedules object definition, a schedule with zero snapshots to keep is not configured
+type SnapshotPolicySchedules struct {
+	HourlySnapshotsToKeep  int32
+	HourlyMinute           int32
+	DailySnapshotsToKeep   int32
+	DailyHour              int32
+	DailyMinute            int32
+	WeeklySnapshotsToKeep  int32
+	WeeklyDays             []string
+	WeeklyHour             int32
+	WeeklyMinute           int32
+	MonthlySnapshotsToKeep int32
+	MonthlyDays            []int32
+	MonthlyHour            int32
+	MonthlyMinute          int32
+}
+
+// BackupPolicyRetention object definition, the number of daily, weekly and monthly backups a backup policy keeps
+type BackupPolicyRetention struct {
+	DailyBackupsToKeep   int32
+	WeeklyBackupsToKeep  int32
+	MonthlyBackupsToKeep int32
+}
+
 // AzureBasicInfo object definition
 type AzureBasicInfo struct {
 	SubscriptionID             *string
@@ -0,0 +1,244 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// This package renders the objects returned by the SDK in the
+// machine readable formats selected with --output, after
+// filtering them with an optional JMESPath --query expression.
+
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jmespath/go-jmespath"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported output formats
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+	FormatTSV   = "tsv"
+)
+
+// Formats lists the supported output formats
+var Formats = []string{FormatTable, FormatJSON, FormatYAML, FormatTSV}
+
+// ValidateFormat checks the output format is supported
+func ValidateFormat(format string) error {
+	for _, supported := range Formats {
+		if format == supported {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid output format %v, supported formats are: %v", format, strings.Join(Formats, ", "))
+}
+
+// ValidateQuery checks the JMESPath expression can be compiled
+func ValidateQuery(query string) error {
+	if query == "" {
+		return nil
+	}
+
+	if _, err := jmespath.Compile(query); err != nil {
+		return fmt.Errorf("invalid query %v: %v", query, err)
+	}
+
+	return nil
+}
+
+// Print writes data to w in the given format, applying the query expression first
+func Print(w io.Writer, format, query string, data interface{}) error {
+	value, err := normalize(data)
+	if err != nil {
+		return err
+	}
+
+	if query != "" {
+		value, err = jmespath.Search(query, value)
+		if err != nil {
+			return fmt.Errorf("cannot apply query %v: %v", query, err)
+		}
+	}
+	value = integers(value)
+
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case FormatTSV:
+		return printTSV(w, value)
+	case FormatTable:
+		return printTable(w, value)
+	}
+
+	return ValidateFormat(format)
+}
+
+// normalize converts SDK objects into the generic maps and slices a JMESPath expression works on
+func normalize(data interface{}) (interface{}, error) {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot serialize output: %v", err)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(content, &value); err != nil {
+		return nil, fmt.Errorf("cannot serialize output: %v", err)
+	}
+
+	return value, nil
+}
+
+// integers turns whole numbers back into integers so sizes are not printed in exponent notation
+func integers(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case float64:
+		if typed == math.Trunc(typed) && math.Abs(typed) < 1<<53 {
+			return int64(typed)
+		}
+	case []interface{}:
+		for i := range typed {
+			typed[i] = integers(typed[i])
+		}
+	case map[string]interface{}:
+		for key := range typed {
+			typed[key] = integers(typed[key])
+		}
+	}
+
+	return value
+}
+
+// printTSV writes one line per list item with the scalar values separated by tabs
+func printTSV(w io.Writer, value interface{}) error {
+	items, isList := value.([]interface{})
+	if !isList {
+		items = []interface{}{value}
+	}
+
+	for _, item := range items {
+		if _, err := fmt.Fprintln(w, strings.Join(scalarValues(item), "\t")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printTable writes a list of objects as columns of their scalar fields,
+// a single object as key value pairs and anything else one value per line
+func printTable(w io.Writer, value interface{}) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for _, key := range scalarKeys(typed) {
+			fmt.Fprintf(writer, "%v:\t%v\n", key, scalar(typed[key]))
+		}
+	case []interface{}:
+		columns := map[string]bool{}
+		for _, item := range typed {
+			if object, isObject := item.(map[string]interface{}); isObject {
+				for _, key := range scalarKeys(object) {
+					columns[key] = true
+				}
+			}
+		}
+
+		if len(columns) == 0 {
+			for _, item := range typed {
+				fmt.Fprintln(writer, strings.Join(scalarValues(item), "\t"))
+			}
+			break
+		}
+
+		headers := make([]string, 0, len(columns))
+		for key := range columns {
+			headers = append(headers, key)
+		}
+		sort.Strings(headers)
+		fmt.Fprintln(writer, strings.Join(headers, "\t"))
+
+		for _, item := range typed {
+			object, _ := item.(map[string]interface{})
+			row := make([]string, len(headers))
+			for i, key := range headers {
+				row[i] = scalar(object[key])
+			}
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+	default:
+		fmt.Fprintln(writer, scalar(typed))
+	}
+
+	return writer.Flush()
+}
+
+// scalarKeys returns the sorted keys of an object holding scalar values
+func scalarKeys(object map[string]interface{}) []string {
+	keys := []string{}
+	for key, value := range object {
+		if isScalar(value) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// scalarValues returns the scalar values of an object in key order, or the value itself
+func scalarValues(value interface{}) []string {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		values := []string{}
+		for _, key := range scalarKeys(typed) {
+			values = append(values, scalar(typed[key]))
+		}
+		return values
+	case []interface{}:
+		values := []string{}
+		for _, item := range typed {
+			if isScalar(item) {
+				values = append(values, scalar(item))
+			}
+		}
+		return values
+	}
+
+	return []string{scalar(value)}
+}
+
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return true
+}
+
+func scalar(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,257 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// This package runs the disaster recovery runbooks of a replicated
+// volume: failover, failback and drill. A run checks its pre-flight
+// conditions, then executes its steps one by one and saves itself after
+// every change, so that the saved run is both the checkpoint an
+// interrupted run resumes from and the timestamped report of what was done.
+
+package runbook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+	"github.com/patrikcze/go-anf/pkg/uri"
+	"github.com/patrikcze/go-anf/pkg/utils"
+)
+
+// Runbooks
+const (
+	// Failover breaks the replication, the destination volume takes over from the source volume
+	Failover = "failover"
+	// Failback replicates the changes made on the destination volume back to the source volume
+	// and restores the original direction of the replication
+	Failback = "failback"
+	// Drill breaks the replication, verifies the destination volume and resyncs it again
+	Drill = "drill"
+)
+
+// Statuses of runs and steps
+const (
+	StatusPending    = "Pending"
+	StatusInProgress = "InProgress"
+	StatusSucceeded  = "Succeeded"
+	StatusFailed     = "Failed"
+	// StatusSkipped is a step of a dry run
+	StatusSkipped = "Skipped"
+)
+
+// Runbooks lists the supported runbooks
+var Runbooks = []string{Failover, Failback, Drill}
+
+// Run object definition, a run of a runbook on the destination volume of a replication
+type Run struct {
+	ID      string `json:"id"`
+	Runbook string `json:"runbook"`
+	// DestinationVolumeID is the volume the runbook was started on, the destination of the replication
+	DestinationVolumeID string `json:"destinationVolumeId"`
+	// SourceVolumeID is read from the replication by the pre-flight checks
+	SourceVolumeID string `json:"sourceVolumeId,omitempty"`
+	// DryRun runs the pre-flight checks only, the steps are reported as skipped
+	DryRun bool `json:"dryRun"`
+	// Force runs the steps even though pre-flight checks failed
+	Force bool `json:"force"`
+	// MaxLagSeconds is the largest replication lag accepted by the pre-flight checks
+	MaxLagSeconds int64      `json:"maxLagSeconds,omitempty"`
+	Status        string     `json:"status"`
+	Error         string     `json:"error,omitempty"`
+	StartedAt     time.Time  `json:"startedAt"`
+	FinishedAt    *time.Time `json:"finishedAt,omitempty"`
+	Checks        []*Check   `json:"checks"`
+	Steps         []*Step    `json:"steps"`
+}
+
+// Check object definition, the result of a pre-flight check
+type Check struct {
+	Name      string    `json:"name"`
+	Passed    bool      `json:"passed"`
+	Detail    string    `json:"detail,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Step object definition, a step of a run and its outcome
+type Step struct {
+	Name       string     `json:"name"`
+	Status     string     `json:"status"`
+	Detail     string     `json:"detail,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Runner executes runs and saves them in its store after every check and step
+type Runner struct {
+	Client *sdkutils.Client
+	Store  *Store
+	// OnProgress is called with a message before every check and step, e.g. to log it
+	OnProgress func(message string)
+}
+
+// stepDefinition is a step of a runbook, run returns a detail reported with the step.
+// Steps are run again when a run resumes, so they first check whether their work is already done.
+type stepDefinition struct {
+	name string
+	run  func(ctx context.Context) (string, error)
+}
+
+// NewRun returns a new run of a runbook on the destination volume of a replication,
+// a zero maxLag accepts a lag of up to two replication intervals
+func NewRun(runbook, destinationVolumeID string, maxLag time.Duration, force, dryRun bool) (*Run, error) {
+	if !utils.Contains(Runbooks, runbook) {
+		return nil, fmt.Errorf("invalid runbook %v, valid runbooks are: %v", runbook, Runbooks)
+	}
+	if !uri.IsANFVolume(destinationVolumeID) {
+		return nil, fmt.Errorf("%v is not a volume resource ID", destinationVolumeID)
+	}
+
+	return &Run{
+		ID:                  newRunID(runbook),
+		Runbook:             runbook,
+		DestinationVolumeID: destinationVolumeID,
+		DryRun:              dryRun,
+		Force:               force,
+		MaxLagSeconds:       int64(maxLag.Seconds()),
+		Status:              StatusPending,
+		StartedAt:           time.Now().UTC(),
+		Checks:              []*Check{},
+		Steps:               []*Step{},
+	}, nil
+}
+
+// Execute runs the pre-flight checks of a new run, then its steps. A run that was interrupted or failed during
+// its steps resumes from its first step that did not succeed, the pre-flight checks are not repeated.
+func (r *Runner) Execute(ctx context.Context, run *Run) error {
+	switch {
+	case run.Status == StatusSucceeded:
+		return fmt.Errorf("run %v already succeeded", run.ID)
+	case run.DryRun && run.Status != StatusPending:
+		return fmt.Errorf("run %v is a dry run, start a new run instead", run.ID)
+	}
+
+	definitions := r.steps(run)
+
+	if len(run.Steps) == 0 {
+		run.Status = StatusInProgress
+		failed := r.preflight(ctx, run)
+		if len(failed) > 0 && !run.Force && !run.DryRun {
+			return r.fail(run, fmt.Errorf("pre-flight checks failed: %v", strings.Join(failed, "; ")))
+		}
+
+		definitions = r.steps(run)
+		for _, definition := range definitions {
+			run.Steps = append(run.Steps, &Step{Name: definition.name, Status: StatusPending})
+		}
+
+		if run.DryRun {
+			for _, step := range run.Steps {
+				step.Status = StatusSkipped
+				step.Detail = "dry run"
+			}
+			if len(failed) > 0 {
+				return r.fail(run, fmt.Errorf("pre-flight checks failed: %v", strings.Join(failed, "; ")))
+			}
+			return r.finish(run)
+		}
+		if err := r.save(run); err != nil {
+			return err
+		}
+	}
+
+	if len(run.Steps) != len(definitions) {
+		return fmt.Errorf("run %v has %v steps, runbook %v has %v", run.ID, len(run.Steps), run.Runbook, len(definitions))
+	}
+
+	run.Status = StatusInProgress
+	run.Error = ""
+	run.FinishedAt = nil
+	for i, step := range run.Steps {
+		if step.Status == StatusSucceeded {
+			continue
+		}
+
+		r.progress(fmt.Sprintf("%v...", step.Name))
+		startedAt := time.Now().UTC()
+		step.Status = StatusInProgress
+		step.StartedAt = &startedAt
+		step.FinishedAt = nil
+		step.Error = ""
+		if err := r.save(run); err != nil {
+			return err
+		}
+
+		detail, err := definitions[i].run(ctx)
+		finishedAt := time.Now().UTC()
+		step.FinishedAt = &finishedAt
+		step.Detail = detail
+		if err != nil {
+			step.Status = StatusFailed
+			step.Error = err.Error()
+			return r.fail(run, fmt.Errorf("step %q failed: %w", step.Name, err))
+		}
+		step.Status = StatusSucceeded
+		if err := r.save(run); err != nil {
+			return err
+		}
+	}
+
+	return r.finish(run)
+}
+
+// finish records the success of a run
+func (r *Runner) finish(run *Run) error {
+	finishedAt := time.Now().UTC()
+	run.Status = StatusSucceeded
+	run.FinishedAt = &finishedAt
+	return r.save(run)
+}
+
+// fail records the failure of a run and returns err
+func (r *Runner) fail(run *Run, err error) error {
+	finishedAt := time.Now().UTC()
+	run.Status = StatusFailed
+	run.Error = err.Error()
+	run.FinishedAt = &finishedAt
+	if saveErr := r.save(run); saveErr != nil {
+		return fmt.Errorf("%w, and %v", err, saveErr)
+	}
+	return err
+}
+
+// save stores the run, a runner without a store keeps it in memory only
+func (r *Runner) save(run *Run) error {
+	if r.Store == nil {
+		return nil
+	}
+	return r.Store.Save(run)
+}
+
+// progress reports a message through OnProgress
+func (r *Runner) progress(message string) {
+	if r.OnProgress != nil {
+		r.OnProgress(message)
+	}
+}
+
+// Duration returns how long the run took, or has been running
+func (run *Run) Duration() time.Duration {
+	if run.FinishedAt != nil {
+		return run.FinishedAt.Sub(run.StartedAt)
+	}
+	return time.Since(run.StartedAt)
+}
+
+// newRunID returns a unique run ID starting with the runbook and the start time, e.g. failover-20230102T150405Z-1a2b
+func newRunID(runbook string) string {
+	suffix := make([]byte, 2)
+	_, _ = rand.Read(suffix)
+	return fmt.Sprintf("%v-%v-%v", runbook, time.Now().UTC().Format("20060102T150405Z"), hex.EncodeToString(suffix))
+}
@@ -0,0 +1,150 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+package runbook
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrikcze/go-anf/pkg/emulator"
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+	"github.com/patrikcze/go-anf/pkg/uri"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+)
+
+const testSubnetID = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/anf"
+
+// newTestReplication returns a client talking to a new emulator and the ID of a destination volume mirrored from eastus to westus
+func newTestReplication(t *testing.T) (*sdkutils.Client, string) {
+	t.Helper()
+	ctx := context.Background()
+
+	server := emulator.NewServer(emulator.Options{})
+	t.Cleanup(server.Close)
+
+	client, err := sdkutils.NewClient(emulator.Credential{}, emulator.SubscriptionID, server.ClientOptions())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	client.SetWaitOptions(&sdkutils.WaitOptions{Interval: 10 * time.Millisecond, MaxInterval: 100 * time.Millisecond, Timeout: 30 * time.Second})
+
+	if _, err := client.CreateANFAccount(ctx, "eastus", "rg", "account1", nil, nil); err != nil {
+		t.Fatalf("CreateANFAccount() error = %v", err)
+	}
+	if _, err := client.CreateANFCapacityPool(ctx, "eastus", "rg", "account1", "pool1", "Premium", 4<<40, nil); err != nil {
+		t.Fatalf("CreateANFCapacityPool() error = %v", err)
+	}
+	source, err := client.CreateANFVolume(ctx, sdkutils.VolumeSpec{
+		Location:       "eastus",
+		ResourceGroup:  "rg",
+		Account:        "account1",
+		Pool:           "pool1",
+		Name:           "volume1",
+		ServiceLevel:   "Premium",
+		SubnetID:       testSubnetID,
+		ProtocolTypes:  []string{"NFSv3"},
+		UsageThreshold: 100 * sdkutils.VolumeQuotaIncrement,
+	})
+	if err != nil {
+		t.Fatalf("CreateANFVolume() error = %v", err)
+	}
+
+	destination, err := client.CreateANFReplication(ctx, sdkutils.ReplicationSpec{
+		SourceVolumeID: *source.ID,
+		Location:       "westus",
+		SubnetID:       testSubnetID,
+		Schedule:       "hourly",
+	})
+	if err != nil {
+		t.Fatalf("CreateANFReplication() error = %v", err)
+	}
+	return client, *destination.ID
+}
+
+// mirrorState returns the mirror state of the replication of a volume
+func mirrorState(t *testing.T, client *sdkutils.Client, volumeID string) string {
+	t.Helper()
+
+	status, err := client.GetANFVolumeReplicationStatus(context.Background(), uri.GetResourceGroup(volumeID), uri.GetANFAccount(volumeID), uri.GetANFCapacityPool(volumeID), uri.GetANFVolume(volumeID))
+	if err != nil {
+		t.Fatalf("GetANFVolumeReplicationStatus() error = %v", err)
+	}
+	return status.MirrorState
+}
+
+func TestExecuteResumesInterruptedRun(t *testing.T) {
+	client, destinationID := newTestReplication(t)
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	run, err := NewRun(Drill, destinationID, 0, false, false)
+	if err != nil {
+		t.Fatalf("NewRun() error = %v", err)
+	}
+
+	// The run is interrupted once the replication is broken, when the verification step starts
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupted := &Runner{Client: client, Store: store, OnProgress: func(message string) {
+		if strings.HasPrefix(message, "Verify") {
+			cancel()
+		}
+	}}
+	if err := interrupted.Execute(ctx, run); err == nil {
+		t.Fatalf("Execute() of the interrupted run error = nil, want an error")
+	}
+	if got := mirrorState(t, client, destinationID); got != string(armnetapp.MirrorStateBroken) {
+		t.Fatalf("mirror state after the interruption = %v, want Broken", got)
+	}
+
+	// Another process resumes the run as saved in the store
+	saved, err := store.Load(run.ID)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if saved.Status != StatusFailed || len(saved.Steps) != 3 || saved.Steps[0].Status != StatusSucceeded || saved.Steps[1].Status != StatusFailed {
+		t.Fatalf("saved run = %+v, want a failed run whose first step succeeded and second step failed", saved)
+	}
+	checks := len(saved.Checks)
+	breakStartedAt := *saved.Steps[0].StartedAt
+
+	resumed := &Runner{Client: client, Store: store}
+	if err := resumed.Execute(context.Background(), saved); err != nil {
+		t.Fatalf("Execute() of the resumed run error = %v", err)
+	}
+
+	saved, err = store.Load(run.ID)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if saved.Status != StatusSucceeded || saved.Error != "" || saved.FinishedAt == nil {
+		t.Errorf("resumed run = %+v, want a finished run that succeeded", saved)
+	}
+	for _, step := range saved.Steps {
+		if step.Status != StatusSucceeded {
+			t.Errorf("step %q status = %v, want Succeeded", step.Name, step.Status)
+		}
+	}
+	// Neither the pre-flight checks nor the steps that succeeded are run again
+	if len(saved.Checks) != checks {
+		t.Errorf("resumed run has %v checks, want the %v checks of the interrupted run", len(saved.Checks), checks)
+	}
+	if !saved.Steps[0].StartedAt.Equal(breakStartedAt) {
+		t.Errorf("step %q started again at %v, want it kept from %v", saved.Steps[0].Name, saved.Steps[0].StartedAt, breakStartedAt)
+	}
+	if got := mirrorState(t, client, destinationID); got != string(armnetapp.MirrorStateMirrored) {
+		t.Errorf("mirror state after the drill = %v, want Mirrored", got)
+	}
+
+	if err := resumed.Execute(context.Background(), saved); err == nil {
+		t.Errorf("Execute() of a run that succeeded error = nil, want an error")
+	}
+}
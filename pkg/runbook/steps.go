@@ -0,0 +1,221 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// This file defines the pre-flight checks and the steps of the runbooks.
+// The replication is always looked up through its original destination
+// volume, so the checks keep working while the source region is down.
+
+package runbook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+	"github.com/patrikcze/go-anf/pkg/uri"
+)
+
+// defaultMaxLagIntervals is the lag accepted by default, in replication intervals
+const defaultMaxLagIntervals = 2
+
+// preflight runs the pre-flight checks of a new run and returns the failed ones
+func (r *Runner) preflight(ctx context.Context, run *Run) []string {
+	failed := []string{}
+	run.Checks = []*Check{}
+	check := func(name string, passed bool, detail string) {
+		run.Checks = append(run.Checks, &Check{Name: name, Passed: passed, Detail: detail, CheckedAt: time.Now().UTC()})
+		if !passed {
+			failed = append(failed, fmt.Sprintf("%v: %v", name, detail))
+		}
+	}
+
+	r.progress("Running pre-flight checks...")
+	status, err := r.status(ctx, run.DestinationVolumeID)
+	if err != nil {
+		check("Replication exists", false, err.Error())
+		return failed
+	}
+	run.SourceVolumeID = status.SourceVolumeID
+	check("Replication exists", true, fmt.Sprintf("source %v, schedule %v", status.SourceVolumeID, status.Schedule))
+	check("Volume is the replication destination", strings.EqualFold(status.DestinationVolumeID, run.DestinationVolumeID), fmt.Sprintf("destination %v", status.DestinationVolumeID))
+
+	switch run.Runbook {
+	case Failover, Drill:
+		check("Mirror state is Mirrored", status.MirrorState == string(armnetapp.MirrorStateMirrored), fmt.Sprintf("mirror state %v", status.MirrorState))
+		check("No transfer in progress", status.RelationshipStatus != string(armnetapp.RelationshipStatusTransferring), fmt.Sprintf("relationship status %v", status.RelationshipStatus))
+
+		maxLag := time.Duration(run.MaxLagSeconds) * time.Second
+		if maxLag == 0 {
+			maxLag = defaultMaxLagIntervals * sdkutils.ReplicationInterval(status.Schedule)
+			run.MaxLagSeconds = int64(maxLag.Seconds())
+		}
+		if status.LastTransfer == nil {
+			check("Lag within limit", false, "no transfer completed yet")
+		} else {
+			check("Lag within limit", status.Lag <= maxLag, fmt.Sprintf("lag %v, limit %v, last transfer %v", status.Lag, maxLag, status.LastTransfer.UTC().Format(time.RFC3339)))
+		}
+	case Failback:
+		check("Mirror state is Broken", status.MirrorState == string(armnetapp.MirrorStateBroken), fmt.Sprintf("mirror state %v", status.MirrorState))
+		if _, err := r.volume(ctx, status.SourceVolumeID); err != nil {
+			check("Source volume is reachable", false, err.Error())
+		} else {
+			check("Source volume is reachable", true, status.SourceVolumeID)
+		}
+	}
+
+	return failed
+}
+
+// steps returns the steps of the runbook of a run, the source volume is known once the pre-flight checks ran
+func (r *Runner) steps(run *Run) []stepDefinition {
+	source := uri.GetANFVolume(run.SourceVolumeID)
+	destination := uri.GetANFVolume(run.DestinationVolumeID)
+
+	breakDestination := stepDefinition{
+		name: fmt.Sprintf("Break replication of %v", destination),
+		run: func(ctx context.Context) (string, error) {
+			return r.breakReplication(ctx, run.DestinationVolumeID, run.Force)
+		},
+	}
+	verifyDestination := stepDefinition{
+		name: fmt.Sprintf("Verify volume %v is writable", destination),
+		run: func(ctx context.Context) (string, error) {
+			return r.verifyWritable(ctx, run.DestinationVolumeID)
+		},
+	}
+
+	switch run.Runbook {
+	case Failover:
+		return []stepDefinition{breakDestination, verifyDestination}
+	case Failback:
+		return []stepDefinition{
+			{
+				// Resyncing the source volume reverses the replication, the changes made on the destination volume are replicated back
+				name: fmt.Sprintf("Resync replication from %v back to %v", destination, source),
+				run: func(ctx context.Context) (string, error) {
+					return r.resync(ctx, run.SourceVolumeID, run.SourceVolumeID)
+				},
+			},
+			{
+				name: fmt.Sprintf("Break reversed replication of %v", source),
+				run: func(ctx context.Context) (string, error) {
+					return r.breakReplication(ctx, run.SourceVolumeID, false)
+				},
+			},
+			{
+				// The destination volume is now the source of the broken replication, resyncing it restores the original direction
+				name: fmt.Sprintf("Resync replication from %v to %v", source, destination),
+				run: func(ctx context.Context) (string, error) {
+					return r.resync(ctx, run.DestinationVolumeID, run.DestinationVolumeID)
+				},
+			},
+		}
+	case Drill:
+		return []stepDefinition{
+			breakDestination,
+			verifyDestination,
+			{
+				// Resyncing the destination volume discards the changes made during the drill
+				name: fmt.Sprintf("Resync replication of %v", destination),
+				run: func(ctx context.Context) (string, error) {
+					return r.resync(ctx, run.DestinationVolumeID, run.DestinationVolumeID)
+				},
+			},
+		}
+	}
+
+	return nil
+}
+
+// breakReplication breaks the replication of a destination volume, unless it is already broken
+func (r *Runner) breakReplication(ctx context.Context, volumeID string, force bool) (string, error) {
+	status, err := r.status(ctx, volumeID)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(status.DestinationVolumeID, volumeID) {
+		return "", fmt.Errorf("volume %v is not the destination of the replication, %v is", volumeID, status.DestinationVolumeID)
+	}
+
+	dataAsOf := "no transfer completed"
+	if status.LastTransfer != nil {
+		dataAsOf = fmt.Sprintf("data as of %v", status.LastTransfer.UTC().Format(time.RFC3339))
+	}
+	if status.MirrorState == string(armnetapp.MirrorStateBroken) {
+		return fmt.Sprintf("already broken, %v", dataAsOf), nil
+	}
+
+	if err := r.Client.BreakANFVolumeReplication(ctx, uri.GetResourceGroup(volumeID), uri.GetANFAccount(volumeID), uri.GetANFCapacityPool(volumeID), uri.GetANFVolume(volumeID), force); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("broken, %v", dataAsOf), nil
+}
+
+// verifyWritable checks that the replication of a destination volume is broken and the volume is available
+func (r *Runner) verifyWritable(ctx context.Context, volumeID string) (string, error) {
+	status, err := r.status(ctx, volumeID)
+	if err != nil {
+		return "", err
+	}
+	if status.MirrorState != string(armnetapp.MirrorStateBroken) {
+		return "", fmt.Errorf("volume %v is read-only, mirror state is %v", uri.GetANFVolume(volumeID), status.MirrorState)
+	}
+
+	volume, err := r.volume(ctx, volumeID)
+	if err != nil {
+		return "", err
+	}
+	if volume.Properties == nil || volume.Properties.ProvisioningState == nil || *volume.Properties.ProvisioningState != "Succeeded" {
+		return "", fmt.Errorf("volume %v is not available", uri.GetANFVolume(volumeID))
+	}
+
+	mountPaths := []string{}
+	for _, mountTarget := range volume.Properties.MountTargets {
+		if mountTarget.IPAddress != nil && volume.Properties.CreationToken != nil {
+			mountPaths = append(mountPaths, fmt.Sprintf("%v:/%v", *mountTarget.IPAddress, *volume.Properties.CreationToken))
+		}
+	}
+	if len(mountPaths) == 0 {
+		return "writable", nil
+	}
+	return fmt.Sprintf("writable, mount %v", strings.Join(mountPaths, ", ")), nil
+}
+
+// resync resyncs the replication of a volume, unless it is already mirrored to destinationID
+func (r *Runner) resync(ctx context.Context, volumeID, destinationID string) (string, error) {
+	status, err := r.status(ctx, volumeID)
+	if err != nil {
+		return "", err
+	}
+	detail := fmt.Sprintf("mirrored from %v to %v", uri.GetANFVolume(status.SourceVolumeID), uri.GetANFVolume(destinationID))
+	if status.MirrorState == string(armnetapp.MirrorStateMirrored) && strings.EqualFold(status.DestinationVolumeID, destinationID) {
+		return detail, nil
+	}
+
+	if err := r.Client.ResyncANFVolumeReplication(ctx, uri.GetResourceGroup(volumeID), uri.GetANFAccount(volumeID), uri.GetANFCapacityPool(volumeID), uri.GetANFVolume(volumeID)); err != nil {
+		return "", err
+	}
+
+	if status, err = r.status(ctx, volumeID); err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(status.DestinationVolumeID, destinationID) {
+		return "", fmt.Errorf("replication was resynced to %v instead of %v", status.DestinationVolumeID, destinationID)
+	}
+	return fmt.Sprintf("mirrored from %v to %v", uri.GetANFVolume(status.SourceVolumeID), uri.GetANFVolume(destinationID)), nil
+}
+
+// status reads the replication status of a volume
+func (r *Runner) status(ctx context.Context, volumeID string) (*sdkutils.ReplicationStatus, error) {
+	return r.Client.GetANFVolumeReplicationStatus(ctx, uri.GetResourceGroup(volumeID), uri.GetANFAccount(volumeID), uri.GetANFCapacityPool(volumeID), uri.GetANFVolume(volumeID))
+}
+
+// volume reads a volume by its resource ID
+func (r *Runner) volume(ctx context.Context, volumeID string) (*armnetapp.Volume, error) {
+	return r.Client.GetANFVolume(ctx, uri.GetResourceGroup(volumeID), uri.GetANFAccount(volumeID), uri.GetANFCapacityPool(volumeID), uri.GetANFVolume(volumeID))
+}
@@ -0,0 +1,125 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// This file keeps the runs of the runbooks, one JSON file per run. Runs
+// are never removed since they are the report of what was done.
+
+package runbook
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const fileExtension = ".json"
+
+// Store object definition, the directory the runs are saved in
+type Store struct {
+	dir string
+}
+
+// DefaultDir returns the directory of the runs, $HOME/.go-anf/runbooks
+func DefaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot find home directory: %v", err)
+	}
+
+	return filepath.Join(home, ".go-anf", "runbooks"), nil
+}
+
+// Open opens the store in dir, creating the directory when missing
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("cannot create runbook store: %v", err)
+	}
+
+	return &Store{dir: dir}, nil
+}
+
+// Save writes a run
+func (s *Store) Save(run *Run) error {
+	content, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot serialize run: %v", err)
+	}
+
+	// Write and rename so an interrupted save never corrupts the checkpoint
+	path := s.path(run.ID)
+	if err := os.WriteFile(path+".tmp", content, 0600); err != nil {
+		return fmt.Errorf("cannot write run %v: %v", run.ID, err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("cannot write run %v: %v", run.ID, err)
+	}
+
+	return nil
+}
+
+// Load reads a run by its ID or a unique prefix of it
+func (s *Store) Load(id string) (*Run, error) {
+	runs, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []*Run{}
+	for _, run := range runs {
+		if run.ID == id {
+			return run, nil
+		}
+		if strings.HasPrefix(run.ID, id) {
+			matches = append(matches, run)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("run %v not found", id)
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("run ID %v is ambiguous, it matches %v runs", id, len(matches))
+}
+
+// List reads all runs, the most recently started first
+func (s *Store) List() ([]*Run, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read runbook store: %v", err)
+	}
+
+	runs := []*Run{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != fileExtension {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read run: %v", err)
+		}
+		run := &Run{}
+		if err := json.Unmarshal(content, run); err != nil {
+			return nil, fmt.Errorf("cannot parse run file %v: %v", entry.Name(), err)
+		}
+		runs = append(runs, run)
+	}
+
+	sort.Slice(runs, func(a, b int) bool {
+		return runs[a].StartedAt.After(runs[b].StartedAt)
+	})
+
+	return runs, nil
+}
+
+// path returns the file of a run
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+fileExtension)
+}
@@ -0,0 +1,437 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// Backups of ANF volumes. A backup policy of an account keeps a number of
+// daily, weekly and monthly backups of the volumes it is assigned to and
+// on-demand backups are taken with CreateANFBackup. Backups are stored in
+// the backup vault of the account, which is created and managed by the
+// service: API 2022-05-01 only lists vaults. A backup is restored by
+// creating a new volume with VolumeSpec.BackupID.
+
+package sdkutils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/patrikcze/go-anf/pkg/models"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+)
+
+const (
+	minDailyBackupsToKeep int32 = 2
+	maxBackupsPerVolume   int32 = 1019
+)
+
+// NewANFBackupPolicyProperties validates the retention and builds the properties of a Backup Policy,
+// all validation problems are reported at once
+func NewANFBackupPolicyProperties(retention models.BackupPolicyRetention, enabled bool) (*armnetapp.BackupPolicyProperties, error) {
+	problems := []string{}
+
+	if retention.DailyBackupsToKeep < minDailyBackupsToKeep {
+		problems = append(problems, fmt.Sprintf("daily backups to keep must be at least %v, got %v", minDailyBackupsToKeep, retention.DailyBackupsToKeep))
+	}
+	if retention.WeeklyBackupsToKeep < 0 {
+		problems = append(problems, fmt.Sprintf("weekly backups to keep must not be negative, got %v", retention.WeeklyBackupsToKeep))
+	}
+	if retention.MonthlyBackupsToKeep < 0 {
+		problems = append(problems, fmt.Sprintf("monthly backups to keep must not be negative, got %v", retention.MonthlyBackupsToKeep))
+	}
+	if total := retention.DailyBackupsToKeep + retention.WeeklyBackupsToKeep + retention.MonthlyBackupsToKeep; total > maxBackupsPerVolume {
+		problems = append(problems, fmt.Sprintf("a volume can keep at most %v backups, the policy keeps %v", maxBackupsPerVolume, total))
+	}
+
+	if len(problems) > 0 {
+		return nil, invalidArgument("invalid backup policy: %v", strings.Join(problems, "; "))
+	}
+
+	return &armnetapp.BackupPolicyProperties{
+		Enabled:              to.Ptr(enabled),
+		DailyBackupsToKeep:   to.Ptr(retention.DailyBackupsToKeep),
+		WeeklyBackupsToKeep:  to.Ptr(retention.WeeklyBackupsToKeep),
+		MonthlyBackupsToKeep: to.Ptr(retention.MonthlyBackupsToKeep),
+	}, nil
+}
+
+// CreateANFBackupPolicy creates a Backup Policy to be assigned to volumes
+func (c *Client) CreateANFBackupPolicy(ctx context.Context, resourceGroupName, accountName, policyName string, policy armnetapp.BackupPolicy) (*armnetapp.BackupPolicy, error) {
+	future, err := c.beginCreateANFBackupPolicy(ctx, resourceGroupName, accountName, policyName, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := pollUntilDone(ctx, c, future, OperationBackupPolicyCreate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "backupPolicies", policyName))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the backup policy create future response: %w", newError(err))
+	}
+
+	return &resp.BackupPolicy, nil
+}
+
+// BeginCreateANFBackupPolicy starts the creation of a Backup Policy and returns its operation without waiting for it
+func (c *Client) BeginCreateANFBackupPolicy(ctx context.Context, resourceGroupName, accountName, policyName string, policy armnetapp.BackupPolicy) (*Operation, error) {
+	future, err := c.beginCreateANFBackupPolicy(ctx, resourceGroupName, accountName, policyName, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return startOperation(ctx, c, future, OperationBackupPolicyCreate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "backupPolicies", policyName))
+}
+
+// beginCreateANFBackupPolicy sends the request of CreateANFBackupPolicy
+func (c *Client) beginCreateANFBackupPolicy(ctx context.Context, resourceGroupName, accountName, policyName string, policy armnetapp.BackupPolicy) (*runtime.Poller[armnetapp.BackupPoliciesClientCreateResponse], error) {
+	backupPolicyClient := c.backupPolicies
+
+	future, err := backupPolicyClient.BeginCreate(
+		ctx,
+		resourceGroupName,
+		accountName,
+		policyName,
+		policy,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create backup policy: %w", newError(err))
+	}
+
+	return future, nil
+}
+
+// UpdateANFBackupPolicy updates a Backup Policy
+func (c *Client) UpdateANFBackupPolicy(ctx context.Context, resourceGroupName, accountName, policyName string, backupPolicyPatch armnetapp.BackupPolicyPatch) (*armnetapp.BackupPolicy, error) {
+	future, err := c.beginUpdateANFBackupPolicy(ctx, resourceGroupName, accountName, policyName, backupPolicyPatch)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := pollUntilDone(ctx, c, future, OperationBackupPolicyUpdate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "backupPolicies", policyName))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the backup policy update future response: %w", newError(err))
+	}
+
+	return &resp.BackupPolicy, nil
+}
+
+// BeginUpdateANFBackupPolicy starts the update of a Backup Policy and returns its operation without waiting for it
+func (c *Client) BeginUpdateANFBackupPolicy(ctx context.Context, resourceGroupName, accountName, policyName string, backupPolicyPatch armnetapp.BackupPolicyPatch) (*Operation, error) {
+	future, err := c.beginUpdateANFBackupPolicy(ctx, resourceGroupName, accountName, policyName, backupPolicyPatch)
+	if err != nil {
+		return nil, err
+	}
+
+	return startOperation(ctx, c, future, OperationBackupPolicyUpdate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "backupPolicies", policyName))
+}
+
+// beginUpdateANFBackupPolicy sends the request of UpdateANFBackupPolicy
+func (c *Client) beginUpdateANFBackupPolicy(ctx context.Context, resourceGroupName, accountName, policyName string, backupPolicyPatch armnetapp.BackupPolicyPatch) (*runtime.Poller[armnetapp.BackupPoliciesClientUpdateResponse], error) {
+	backupPolicyClient := c.backupPolicies
+
+	future, err := backupPolicyClient.BeginUpdate(
+		ctx,
+		resourceGroupName,
+		accountName,
+		policyName,
+		backupPolicyPatch,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot update backup policy: %w", newError(err))
+	}
+
+	return future, nil
+}
+
+// GetANFBackupPolicy gets a Backup Policy
+func (c *Client) GetANFBackupPolicy(ctx context.Context, resourceGroupName, accountName, policyName string) (*armnetapp.BackupPolicy, error) {
+	backupPolicyClient := c.backupPolicies
+
+	resp, err := backupPolicyClient.Get(
+		ctx,
+		resourceGroupName,
+		accountName,
+		policyName,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get backup policy: %w", newError(err))
+	}
+
+	return &resp.BackupPolicy, nil
+}
+
+// ListANFBackupPolicies lists all Backup Policies within an ANF Account
+func (c *Client) ListANFBackupPolicies(ctx context.Context, resourceGroupName, accountName string) ([]*armnetapp.BackupPolicy, error) {
+	backupPolicyClient := c.backupPolicies
+
+	policies := []*armnetapp.BackupPolicy{}
+
+	pager := backupPolicyClient.NewListPager(resourceGroupName, accountName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list backup policies: %w", newError(err))
+		}
+		policies = append(policies, page.Value...)
+	}
+
+	return policies, nil
+}
+
+// DeleteANFBackupPolicy deletes a Backup Policy, it must not be assigned to any volume
+func (c *Client) DeleteANFBackupPolicy(ctx context.Context, resourceGroupName, accountName, policyName string) error {
+	future, err := c.beginDeleteANFBackupPolicy(ctx, resourceGroupName, accountName, policyName)
+	if err != nil {
+		return err
+	}
+
+	_, err = pollUntilDone(ctx, c, future, OperationBackupPolicyDelete, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "backupPolicies", policyName))
+	if err != nil {
+		return fmt.Errorf("cannot get the backup policy delete future response: %w", newError(err))
+	}
+
+	return nil
+}
+
+// BeginDeleteANFBackupPolicy starts the deletion of a Backup Policy and returns its operation without waiting for it
+func (c *Client) BeginDeleteANFBackupPolicy(ctx context.Context, resourceGroupName, accountName, policyName string) (*Operation, error) {
+	future, err := c.beginDeleteANFBackupPolicy(ctx, resourceGroupName, accountName, policyName)
+	if err != nil {
+		return nil, err
+	}
+
+	return startOperation(ctx, c, future, OperationBackupPolicyDelete, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "backupPolicies", policyName))
+}
+
+// beginDeleteANFBackupPolicy sends the request of DeleteANFBackupPolicy
+func (c *Client) beginDeleteANFBackupPolicy(ctx context.Context, resourceGroupName, accountName, policyName string) (*runtime.Poller[armnetapp.BackupPoliciesClientDeleteResponse], error) {
+	backupPolicyClient := c.backupPolicies
+
+	future, err := backupPolicyClient.BeginDelete(
+		ctx,
+		resourceGroupName,
+		accountName,
+		policyName,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot delete backup policy: %w", newError(err))
+	}
+
+	return future, nil
+}
+
+// ListANFBackupVaults lists the Backup Vaults of an ANF Account
+func (c *Client) ListANFBackupVaults(ctx context.Context, resourceGroupName, accountName string) ([]*armnetapp.Vault, error) {
+	vaultClient := c.vaults
+
+	vaults := []*armnetapp.Vault{}
+
+	pager := vaultClient.NewListPager(resourceGroupName, accountName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list backup vaults: %w", newError(err))
+		}
+		vaults = append(vaults, page.Value...)
+	}
+
+	return vaults, nil
+}
+
+// GetANFBackupVault gets a Backup Vault of an ANF Account by name, an empty name selects
+// the vault of the account when it has a single one
+func (c *Client) GetANFBackupVault(ctx context.Context, resourceGroupName, accountName, vaultName string) (*armnetapp.Vault, error) {
+	vaults, err := c.ListANFBackupVaults(ctx, resourceGroupName, accountName)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, vault := range vaults {
+		name := ""
+		if vault.Properties != nil && vault.Properties.VaultName != nil {
+			name = *vault.Properties.VaultName
+		} else if vault.Name != nil {
+			name = *vault.Name
+		}
+		if vaultName != "" && strings.EqualFold(name, vaultName) {
+			return vault, nil
+		}
+		names = append(names, name)
+	}
+
+	switch {
+	case vaultName != "":
+		return nil, fmt.Errorf("cannot get backup vault: account %v has no backup vault %v: %w", accountName, vaultName, ErrNotFound)
+	case len(vaults) == 0:
+		return nil, fmt.Errorf("cannot get backup vault: account %v has no backup vault: %w", accountName, ErrNotFound)
+	case len(vaults) > 1:
+		return nil, invalidArgument("account %v has several backup vaults, select one of: %v", accountName, strings.Join(names, ", "))
+	}
+	return vaults[0], nil
+}
+
+// CreateANFBackup takes an on-demand Backup of an ANF volume, the volume must have backups enabled.
+// useExistingSnapshot backs up the most recent snapshot instead of taking a new one.
+func (c *Client) CreateANFBackup(ctx context.Context, location, resourceGroupName, accountName, poolName, volumeName, backupName, label string, useExistingSnapshot bool) (*armnetapp.Backup, error) {
+	future, err := c.beginCreateANFBackup(ctx, location, resourceGroupName, accountName, poolName, volumeName, backupName, label, useExistingSnapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := pollUntilDone(ctx, c, future, OperationBackupCreate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName, "backups", backupName))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the backup create future response: %w", newError(err))
+	}
+
+	return &resp.Backup, nil
+}
+
+// BeginCreateANFBackup starts an on-demand Backup of an ANF volume and returns its operation without waiting for it
+func (c *Client) BeginCreateANFBackup(ctx context.Context, location, resourceGroupName, accountName, poolName, volumeName, backupName, label string, useExistingSnapshot bool) (*Operation, error) {
+	future, err := c.beginCreateANFBackup(ctx, location, resourceGroupName, accountName, poolName, volumeName, backupName, label, useExistingSnapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return startOperation(ctx, c, future, OperationBackupCreate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName, "backups", backupName))
+}
+
+// beginCreateANFBackup sends the request of CreateANFBackup
+func (c *Client) beginCreateANFBackup(ctx context.Context, location, resourceGroupName, accountName, poolName, volumeName, backupName, label string, useExistingSnapshot bool) (*runtime.Poller[armnetapp.BackupsClientCreateResponse], error) {
+	backupClient := c.backups
+
+	properties := &armnetapp.BackupProperties{
+		UseExistingSnapshot: to.Ptr(useExistingSnapshot),
+	}
+	if label != "" {
+		properties.Label = to.Ptr(label)
+	}
+
+	future, err := backupClient.BeginCreate(
+		ctx,
+		resourceGroupName,
+		accountName,
+		poolName,
+		volumeName,
+		backupName,
+		armnetapp.Backup{
+			Location:   to.Ptr(location),
+			Properties: properties,
+		},
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create backup: %w", newError(err))
+	}
+
+	return future, nil
+}
+
+// GetANFBackup gets a Backup of an ANF volume
+func (c *Client) GetANFBackup(ctx context.Context, resourceGroupName, accountName, poolName, volumeName, backupName string) (*armnetapp.Backup, error) {
+	backupClient := c.backups
+
+	resp, err := backupClient.Get(
+		ctx,
+		resourceGroupName,
+		accountName,
+		poolName,
+		volumeName,
+		backupName,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get backup: %w", newError(err))
+	}
+
+	return &resp.Backup, nil
+}
+
+// ListANFBackups lists all Backups of an ANF volume, the scheduled and the on-demand ones
+func (c *Client) ListANFBackups(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string) ([]*armnetapp.Backup, error) {
+	backupClient := c.backups
+
+	backups := []*armnetapp.Backup{}
+
+	pager := backupClient.NewListPager(resourceGroupName, accountName, poolName, volumeName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list backups: %w", newError(err))
+		}
+		backups = append(backups, page.Value...)
+	}
+
+	return backups, nil
+}
+
+// DeleteANFBackup deletes a Backup of an ANF volume
+func (c *Client) DeleteANFBackup(ctx context.Context, resourceGroupName, accountName, poolName, volumeName, backupName string) error {
+	future, err := c.beginDeleteANFBackup(ctx, resourceGroupName, accountName, poolName, volumeName, backupName)
+	if err != nil {
+		return err
+	}
+
+	_, err = pollUntilDone(ctx, c, future, OperationBackupDelete, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName, "backups", backupName))
+	if err != nil {
+		return fmt.Errorf("cannot get the backup delete future response: %w", newError(err))
+	}
+
+	return nil
+}
+
+// BeginDeleteANFBackup starts the deletion of a Backup and returns its operation without waiting for it
+func (c *Client) BeginDeleteANFBackup(ctx context.Context, resourceGroupName, accountName, poolName, volumeName, backupName string) (*Operation, error) {
+	future, err := c.beginDeleteANFBackup(ctx, resourceGroupName, accountName, poolName, volumeName, backupName)
+	if err != nil {
+		return nil, err
+	}
+
+	return startOperation(ctx, c, future, OperationBackupDelete, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName, "backups", backupName))
+}
+
+// beginDeleteANFBackup sends the request of DeleteANFBackup
+func (c *Client) beginDeleteANFBackup(ctx context.Context, resourceGroupName, accountName, poolName, volumeName, backupName string) (*runtime.Poller[armnetapp.BackupsClientDeleteResponse], error) {
+	backupClient := c.backups
+
+	future, err := backupClient.BeginDelete(
+		ctx,
+		resourceGroupName,
+		accountName,
+		poolName,
+		volumeName,
+		backupName,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot delete backup: %w", newError(err))
+	}
+
+	return future, nil
+}
+
+// NewANFVolumeBackupPatch builds the patch assigning a Backup Policy and a Backup Vault to a volume,
+// an empty policy ID only enables the on-demand backups
+func NewANFVolumeBackupPatch(vaultID, policyID string, policyEnforced bool) armnetapp.VolumePatchProperties {
+	backup := &armnetapp.VolumeBackupProperties{
+		BackupEnabled:  to.Ptr(true),
+		VaultID:        to.Ptr(vaultID),
+		PolicyEnforced: to.Ptr(policyEnforced),
+	}
+	if policyID != "" {
+		backup.BackupPolicyID = to.Ptr(policyID)
+	}
+
+	return armnetapp.VolumePatchProperties{
+		DataProtection: &armnetapp.VolumePatchPropertiesDataProtection{
+			Backup: backup,
+		},
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// Errors returned by the Client. Failed Azure requests are turned into an
+// *Error keeping the ARM error code, target and request ID, classified in
+// kinds matched with errors.Is, e.g. errors.Is(err, sdkutils.ErrNotFound).
+
+package sdkutils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/patrikcze/go-anf/pkg/utils"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// Error kinds
+var (
+	// ErrInvalidArgument is a request rejected because of its content, including client side validations
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrInvalidSubnet is a request rejected because of its subnet, it also matches ErrInvalidArgument
+	ErrInvalidSubnet = errors.New("invalid subnet")
+	// ErrUnauthorized is a failed authentication or a missing permission
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrNotFound is a missing resource or parent resource
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is a request conflicting with the state of a resource, e.g. another operation in progress
+	ErrConflict = errors.New("conflict")
+	// ErrQuotaExceeded is a request exceeding a quota, a limit or the capacity of a pool
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrThrottled is a request rejected by ARM throttling
+	ErrThrottled = errors.New("throttled")
+	// ErrTimeout is a wait that did not complete in time
+	ErrTimeout = errors.New("timed out")
+	// ErrServiceUnavailable is a server side failure of Azure
+	ErrServiceUnavailable = errors.New("service unavailable")
+)
+
+const (
+	requestIDHeader     = "x-ms-request-id"
+	correlationIDHeader = "x-ms-correlation-request-id"
+)
+
+// quotaErrorCodes are the ARM error codes of ErrQuotaExceeded not following the *QuotaExceeded or *LimitExceeded pattern
+var quotaErrorCodes = []string{"MaxSnapshotsReached", "PoolSizeTooSmall", "InsufficientCapacity", "InsufficientPoolSize"}
+
+// Error object definition, a failed Azure request or client side validation.
+// errors.Is matches it against its kind, errors.As reaches the *azcore.ResponseError it was built from.
+type Error struct {
+	// Kind is one of the Err* kinds, nil when the error matches none of them
+	Kind error `json:"-"`
+	// StatusCode is the HTTP status code of the response, 0 for client side errors
+	StatusCode int `json:"statusCode,omitempty"`
+	// Code is the ARM error code, e.g. ResourceNotFound
+	Code string `json:"code,omitempty"`
+	// Message is the ARM error message
+	Message string `json:"message"`
+	// Target is the property or resource the error is about, when ARM reports it
+	Target string `json:"target,omitempty"`
+	// RequestID and CorrelationID identify the request for Azure support
+	RequestID     string `json:"requestId,omitempty"`
+	CorrelationID string `json:"correlationId,omitempty"`
+	// Details are the nested ARM errors
+	Details []ErrorDetail `json:"details,omitempty"`
+
+	err error
+}
+
+// ErrorDetail object definition, a nested ARM error
+type ErrorDetail struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+	Target  string `json:"target,omitempty"`
+}
+
+// armErrorBody is the body of a failed ARM request or of a failed long-running operation
+type armErrorBody struct {
+	Error *struct {
+		Code    string        `json:"code"`
+		Message string        `json:"message"`
+		Target  string        `json:"target"`
+		Details []ErrorDetail `json:"details"`
+	} `json:"error"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements error
+func (e *Error) Error() string {
+	if e.StatusCode == 0 && e.Code == "" {
+		return e.Message
+	}
+
+	message := e.Message
+	if e.Code != "" {
+		message = fmt.Sprintf("%v: %v", e.Code, message)
+	}
+	for _, detail := range e.Details {
+		if detail.Message != "" && detail.Message != e.Message {
+			message = fmt.Sprintf("%v; %v: %v", message, detail.Code, detail.Message)
+		}
+	}
+	if e.RequestID != "" {
+		message = fmt.Sprintf("%v (request ID %v)", message, e.RequestID)
+	}
+	return message
+}
+
+// Is matches the kind of the error, an ErrInvalidSubnet error is also an ErrInvalidArgument one
+func (e *Error) Is(target error) bool {
+	if e.Kind == nil {
+		return false
+	}
+	return target == e.Kind || (e.Kind == ErrInvalidSubnet && target == ErrInvalidArgument)
+}
+
+// Unwrap returns the error the Error was built from
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// newError turns an *azcore.ResponseError into an *Error, other errors are returned unchanged
+func newError(err error) error {
+	responseErr, ok := err.(*azcore.ResponseError)
+	if !ok {
+		return err
+	}
+
+	e := &Error{
+		StatusCode: responseErr.StatusCode,
+		Code:       responseErr.ErrorCode,
+		err:        err,
+	}
+	if resp := responseErr.RawResponse; resp != nil {
+		e.RequestID = resp.Header.Get(requestIDHeader)
+		e.CorrelationID = resp.Header.Get(correlationIDHeader)
+
+		body := armErrorBody{}
+		if payload, readErr := runtime.Payload(resp); readErr == nil && json.Unmarshal(payload, &body) == nil {
+			if body.Error != nil {
+				e.Message = body.Error.Message
+				e.Target = body.Error.Target
+				e.Details = body.Error.Details
+				if e.Code == "" {
+					e.Code = body.Error.Code
+				}
+			} else {
+				e.Message = body.Message
+				if e.Code == "" {
+					e.Code = body.Code
+				}
+			}
+		}
+	}
+	if e.Message == "" {
+		e.Message = http.StatusText(e.StatusCode)
+	}
+	e.Kind = errorKind(e)
+
+	return e
+}
+
+// invalidArgument returns an ErrInvalidArgument error for a client side validation
+func invalidArgument(format string, a ...interface{}) error {
+	return &Error{Kind: ErrInvalidArgument, Message: fmt.Sprintf(format, a...)}
+}
+
+// errorKind classifies an error by its ARM error code first, then by its HTTP status code.
+// Failed long-running operations are reported with the status code of the polling request, 200.
+func errorKind(e *Error) error {
+	codes := []string{e.Code}
+	for _, detail := range e.Details {
+		codes = append(codes, detail.Code)
+	}
+
+	for _, code := range codes {
+		switch {
+		case code == "":
+			continue
+		case strings.Contains(strings.ToLower(code), "subnet") || strings.EqualFold(e.Target, "subnetId") || strings.HasSuffix(e.Target, ".subnetId"):
+			return ErrInvalidSubnet
+		case strings.HasSuffix(code, "QuotaExceeded") || strings.HasSuffix(code, "LimitExceeded") || isQuotaErrorCode(code):
+			return ErrQuotaExceeded
+		case strings.HasSuffix(code, "NotFound"):
+			return ErrNotFound
+		case code == "AnotherOperationInProgress" || code == "Conflict":
+			return ErrConflict
+		case code == "AuthorizationFailed" || code == "AuthenticationFailed" || code == "InvalidAuthenticationToken":
+			return ErrUnauthorized
+		}
+	}
+
+	switch {
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrThrottled
+	case e.StatusCode >= http.StatusInternalServerError:
+		return ErrServiceUnavailable
+	case e.StatusCode >= http.StatusBadRequest:
+		return ErrInvalidArgument
+	}
+	return nil
+}
+
+// isQuotaErrorCode reports whether an ARM error code is one of quotaErrorCodes
+func isQuotaErrorCode(code string) bool {
+	_, found := utils.FindInSlice(quotaErrorCodes, code)
+	return found
+}
@@ -0,0 +1,432 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// Export policies of the NFS volumes. ExportRule describes a rule in the
+// terms of an NFS export: the allowed clients, the protocols, the access,
+// the root squashing and the security flavors, and ExportPolicyBuilder
+// adds, removes and replaces the rules of a volume while keeping them
+// valid and ordered by their rule index.
+
+package sdkutils
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/patrikcze/go-anf/pkg/utils"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+)
+
+// Security flavors of an export rule, as in the sec= option of an NFS export
+const (
+	SecuritySys   = "sys"
+	SecurityKrb5  = "krb5"
+	SecurityKrb5i = "krb5i"
+	SecurityKrb5p = "krb5p"
+)
+
+const (
+	// maxExportRules is the number of rules an export policy can hold
+	maxExportRules = 5
+
+	// allClients is the allowed clients of the default export rule
+	allClients = "0.0.0.0/0"
+)
+
+var (
+	validSecurityFlavors = []string{SecuritySys, SecurityKrb5, SecurityKrb5i, SecurityKrb5p}
+	validChownModes      = []string{string(armnetapp.ChownModeRestricted), string(armnetapp.ChownModeUnrestricted)}
+
+	// hostnamePattern matches a RFC 1123 host name, e.g. client01 or client01.contoso.com
+	hostnamePattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+)
+
+// ExportRule object definition, a rule of the export policy of a volume
+type ExportRule struct {
+	// RuleIndex is the position of the rule, rules are evaluated from index 1 and the first match applies
+	RuleIndex int32 `json:"ruleIndex,omitempty" yaml:"ruleIndex,omitempty"`
+	// AllowedClients is a comma separated list of IP addresses, CIDR ranges and host names
+	AllowedClients string `json:"allowedClients" yaml:"allowedClients"`
+	NFSv3          bool   `json:"nfsv3,omitempty" yaml:"nfsv3,omitempty"`
+	NFSv41         bool   `json:"nfsv41,omitempty" yaml:"nfsv41,omitempty"`
+	CIFS           bool   `json:"cifs,omitempty" yaml:"cifs,omitempty"`
+	ReadOnly       bool   `json:"readOnly,omitempty" yaml:"readOnly,omitempty"`
+	// NoAccess denies the clients any access, e.g. to exclude them from a broader rule with a higher index
+	NoAccess bool `json:"noAccess,omitempty" yaml:"noAccess,omitempty"`
+	// RootAccess lets the root user of the clients act as root on the volume, it defaults to true
+	RootAccess *bool `json:"rootAccess,omitempty" yaml:"rootAccess,omitempty"`
+	// ChownMode is Restricted, only root changes file ownership, or Unrestricted, file owners can too
+	ChownMode string `json:"chownMode,omitempty" yaml:"chownMode,omitempty"`
+	// Security holds the security flavors: sys, krb5, krb5i or krb5p, it defaults to sys
+	Security []string `json:"security,omitempty" yaml:"security,omitempty"`
+}
+
+// HasRootAccess reports whether the rule grants root access, which it does unless it is disabled
+func (r ExportRule) HasRootAccess() bool {
+	return r.RootAccess == nil || *r.RootAccess
+}
+
+// SecurityFlavors returns the security flavors of the rule, sys when none are listed
+func (r ExportRule) SecurityFlavors() []string {
+	if len(r.Security) == 0 {
+		return []string{SecuritySys}
+	}
+	return r.Security
+}
+
+// Problems returns every problem of the rule, empty when it is valid
+func (r ExportRule) Problems() []string {
+	problems := []string{}
+	problem := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+
+	if r.RuleIndex < 0 {
+		problem("rule index must be positive, got %v", r.RuleIndex)
+	}
+
+	if strings.TrimSpace(r.AllowedClients) == "" {
+		problem("allowed clients are required")
+	}
+	for _, client := range strings.Split(r.AllowedClients, ",") {
+		if client = strings.TrimSpace(client); client != "" {
+			if err := validateAllowedClient(client); err != nil {
+				problem("%v", err)
+			}
+		}
+	}
+
+	if !r.NFSv3 && !r.NFSv41 {
+		problem("the rule must allow NFSv3 or NFSv4.1")
+	}
+	if r.ChownMode != "" && !containsFold(validChownModes, r.ChownMode) {
+		problem("invalid chown mode %v, valid chown modes are: %v", r.ChownMode, validChownModes)
+	}
+
+	for _, flavor := range r.Security {
+		if !containsFold(validSecurityFlavors, flavor) {
+			problem("invalid security flavor %v, valid security flavors are: %v", flavor, validSecurityFlavors)
+		}
+	}
+	if r.usesKerberos() && !r.NFSv41 {
+		problem("Kerberos security flavors require NFSv4.1")
+	}
+	if r.NoAccess && (r.ReadOnly || len(r.Security) > 0) {
+		problem("a rule without access cannot be read only or list security flavors")
+	}
+
+	return problems
+}
+
+// usesKerberos reports whether the rule lists a Kerberos security flavor, invalid flavors are not Kerberos
+func (r ExportRule) usesKerberos() bool {
+	for _, flavor := range r.Security {
+		if containsFold([]string{SecurityKrb5, SecurityKrb5i, SecurityKrb5p}, flavor) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateAllowedClient checks a single allowed client: an IP address, a CIDR range or a host name
+func validateAllowedClient(client string) error {
+	if strings.Contains(client, "/") {
+		ip, network, err := net.ParseCIDR(client)
+		if err != nil {
+			return invalidArgument("invalid CIDR range %v", client)
+		}
+		if !ip.Equal(network.IP) {
+			return invalidArgument("CIDR range %v has host bits set, the range is %v", client, network)
+		}
+		return nil
+	}
+
+	if net.ParseIP(client) != nil {
+		return nil
+	}
+	// Dotted numbers are a mistyped address rather than a host name
+	if strings.Trim(client, "0123456789.") == "" || len(client) > 253 || !hostnamePattern.MatchString(client) {
+		return invalidArgument("invalid allowed client %v, expected an IP address, a CIDR range or a host name", client)
+	}
+	return nil
+}
+
+// ExportPolicyRule converts the rule into an SDK rule
+func (r ExportRule) ExportPolicyRule() *armnetapp.ExportPolicyRule {
+	rule := &armnetapp.ExportPolicyRule{
+		RuleIndex:           to.Ptr(r.RuleIndex),
+		AllowedClients:      to.Ptr(normalizeAllowedClients(r.AllowedClients)),
+		Nfsv3:               to.Ptr(r.NFSv3),
+		Nfsv41:              to.Ptr(r.NFSv41),
+		Cifs:                to.Ptr(r.CIFS),
+		HasRootAccess:       to.Ptr(r.HasRootAccess()),
+		UnixReadOnly:        to.Ptr(false),
+		UnixReadWrite:       to.Ptr(false),
+		Kerberos5ReadOnly:   to.Ptr(false),
+		Kerberos5ReadWrite:  to.Ptr(false),
+		Kerberos5IReadOnly:  to.Ptr(false),
+		Kerberos5IReadWrite: to.Ptr(false),
+		Kerberos5PReadOnly:  to.Ptr(false),
+		Kerberos5PReadWrite: to.Ptr(false),
+	}
+	if r.ChownMode != "" {
+		rule.ChownMode = to.Ptr(armnetapp.ChownMode(matchFold(validChownModes, r.ChownMode)))
+	}
+	if r.NoAccess {
+		return rule
+	}
+
+	for _, flavor := range r.SecurityFlavors() {
+		readOnly, readWrite := rule.UnixReadOnly, rule.UnixReadWrite
+		switch strings.ToLower(flavor) {
+		case SecurityKrb5:
+			readOnly, readWrite = rule.Kerberos5ReadOnly, rule.Kerberos5ReadWrite
+		case SecurityKrb5i:
+			readOnly, readWrite = rule.Kerberos5IReadOnly, rule.Kerberos5IReadWrite
+		case SecurityKrb5p:
+			readOnly, readWrite = rule.Kerberos5PReadOnly, rule.Kerberos5PReadWrite
+		}
+		*readOnly = r.ReadOnly
+		*readWrite = !r.ReadOnly
+	}
+
+	return rule
+}
+
+// NewExportRule converts an SDK rule into an ExportRule
+func NewExportRule(rule *armnetapp.ExportPolicyRule) ExportRule {
+	exportRule := ExportRule{
+		AllowedClients: valueOf(rule.AllowedClients),
+		NFSv3:          boolValue(rule.Nfsv3),
+		NFSv41:         boolValue(rule.Nfsv41),
+		CIFS:           boolValue(rule.Cifs),
+		RootAccess:     rule.HasRootAccess,
+		Security:       []string{},
+	}
+	if rule.RuleIndex != nil {
+		exportRule.RuleIndex = *rule.RuleIndex
+	}
+	if rule.ChownMode != nil {
+		exportRule.ChownMode = string(*rule.ChownMode)
+	}
+
+	readWrite := false
+	for _, flavor := range []struct {
+		name                string
+		readOnly, readWrite *bool
+	}{
+		{SecuritySys, rule.UnixReadOnly, rule.UnixReadWrite},
+		{SecurityKrb5, rule.Kerberos5ReadOnly, rule.Kerberos5ReadWrite},
+		{SecurityKrb5i, rule.Kerberos5IReadOnly, rule.Kerberos5IReadWrite},
+		{SecurityKrb5p, rule.Kerberos5PReadOnly, rule.Kerberos5PReadWrite},
+	} {
+		if boolValue(flavor.readOnly) || boolValue(flavor.readWrite) {
+			exportRule.Security = append(exportRule.Security, flavor.name)
+			readWrite = readWrite || boolValue(flavor.readWrite)
+		}
+	}
+	// A rule granting no flavor any access denies its clients, it is not a sys read only rule
+	exportRule.NoAccess = len(exportRule.Security) == 0
+	exportRule.ReadOnly = !readWrite && !exportRule.NoAccess
+
+	return exportRule
+}
+
+// DefaultExportRule returns the rule of a new NFS volume without export rules: every client may
+// mount it over its NFS protocols, with Kerberos when it is enabled on the volume
+func DefaultExportRule(protocolTypes []string, readOnly, kerberos bool) ExportRule {
+	rule := ExportRule{
+		RuleIndex:      1,
+		AllowedClients: allClients,
+		NFSv3:          utils.Contains(protocolTypes, nfsv3),
+		NFSv41:         utils.Contains(protocolTypes, nfsv41),
+		CIFS:           utils.Contains(protocolTypes, cifs),
+		ReadOnly:       readOnly,
+	}
+	if kerberos {
+		rule.Security = []string{SecurityKrb5, SecurityKrb5i, SecurityKrb5p}
+	}
+	return rule
+}
+
+// ExportPolicyBuilder object definition, edits the rules of an export policy. Every change is
+// validated, and the rules are renumbered from 1 in their order after each change.
+type ExportPolicyBuilder struct {
+	rules []ExportRule
+	// protocolTypes and kerberosEnabled are the settings of the volume the rules are checked against
+	protocolTypes   []string
+	kerberosEnabled bool
+}
+
+// NewExportPolicyBuilder returns a builder starting from the rules of a volume, the rules added later
+// must match the protocols and the Kerberos setting of the volume. The volume may be nil.
+func NewExportPolicyBuilder(volume *armnetapp.Volume) *ExportPolicyBuilder {
+	b := &ExportPolicyBuilder{rules: []ExportRule{}}
+	if volume != nil && volume.Properties != nil {
+		for _, protocolType := range volume.Properties.ProtocolTypes {
+			b.protocolTypes = append(b.protocolTypes, valueOf(protocolType))
+		}
+		b.kerberosEnabled = boolValue(volume.Properties.KerberosEnabled)
+		if volume.Properties.ExportPolicy != nil {
+			for _, rule := range volume.Properties.ExportPolicy.Rules {
+				b.rules = append(b.rules, NewExportRule(rule))
+			}
+		}
+	}
+	sort.SliceStable(b.rules, func(i, j int) bool {
+		return b.rules[i].RuleIndex < b.rules[j].RuleIndex
+	})
+	b.renumber()
+
+	return b
+}
+
+// Rules returns a copy of the rules in index order
+func (b *ExportPolicyBuilder) Rules() []ExportRule {
+	return append([]ExportRule{}, b.rules...)
+}
+
+// Add inserts a rule at its rule index, moving the following rules down,
+// a rule without index is appended after the last rule
+func (b *ExportPolicyBuilder) Add(rule ExportRule) error {
+	if err := b.validate([]ExportRule{rule}); err != nil {
+		return err
+	}
+	if len(b.rules) >= maxExportRules {
+		return invalidArgument("an export policy holds at most %v rules", maxExportRules)
+	}
+
+	position := len(b.rules)
+	if rule.RuleIndex > 0 && int(rule.RuleIndex) <= len(b.rules) {
+		position = int(rule.RuleIndex) - 1
+	}
+	b.rules = append(b.rules[:position], append([]ExportRule{rule}, b.rules[position:]...)...)
+	b.renumber()
+
+	return nil
+}
+
+// Remove deletes the rule at a rule index, moving the following rules up
+func (b *ExportPolicyBuilder) Remove(ruleIndex int32) error {
+	if ruleIndex < 1 || int(ruleIndex) > len(b.rules) {
+		return invalidArgument("export rule %v not found, the export policy has %v rules", ruleIndex, len(b.rules))
+	}
+
+	b.rules = append(b.rules[:ruleIndex-1], b.rules[ruleIndex:]...)
+	b.renumber()
+
+	return nil
+}
+
+// Replace replaces all rules, rules with a rule index are ordered by it, the others keep their order
+func (b *ExportPolicyBuilder) Replace(rules []ExportRule) error {
+	if err := b.validate(rules); err != nil {
+		return err
+	}
+
+	b.rules = append([]ExportRule{}, rules...)
+	sort.SliceStable(b.rules, func(i, j int) bool {
+		return b.rules[i].RuleIndex != 0 && (b.rules[j].RuleIndex == 0 || b.rules[i].RuleIndex < b.rules[j].RuleIndex)
+	})
+	b.renumber()
+
+	return nil
+}
+
+// ExportPolicyRules returns the SDK rules of the export policy
+func (b *ExportPolicyBuilder) ExportPolicyRules() []*armnetapp.ExportPolicyRule {
+	rules := make([]*armnetapp.ExportPolicyRule, 0, len(b.rules))
+	for _, rule := range b.rules {
+		rules = append(rules, rule.ExportPolicyRule())
+	}
+	return rules
+}
+
+// Patch returns the volume patch applying the export policy with UpdateANFVolume
+func (b *ExportPolicyBuilder) Patch() armnetapp.VolumePatchProperties {
+	return armnetapp.VolumePatchProperties{
+		ExportPolicy: &armnetapp.VolumePatchPropertiesExportPolicy{
+			Rules: b.ExportPolicyRules(),
+		},
+	}
+}
+
+// renumber sets the rule indexes to the positions of the rules
+func (b *ExportPolicyBuilder) renumber() {
+	for i := range b.rules {
+		b.rules[i].RuleIndex = int32(i + 1)
+	}
+}
+
+// validate checks rules against the volume of the builder and reports all problems at once
+func (b *ExportPolicyBuilder) validate(rules []ExportRule) error {
+	problems := exportRulesProblems(rules, b.protocolTypes, b.kerberosEnabled)
+	if len(problems) > 0 {
+		return invalidArgument("invalid export policy: %v", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// exportRulesProblems returns the problems of a set of export rules, each prefixed with the rule it is about.
+// Rules are checked against the protocols and the Kerberos setting of their volume, unless protocolTypes is empty.
+func exportRulesProblems(rules []ExportRule, protocolTypes []string, kerberosEnabled bool) []string {
+	problems := []string{}
+	if len(rules) > maxExportRules {
+		problems = append(problems, fmt.Sprintf("an export policy holds at most %v rules, got %v", maxExportRules, len(rules)))
+	}
+
+	indexes := map[int32]bool{}
+	for i, rule := range rules {
+		name := fmt.Sprintf("export rule %v", i+1)
+		if rule.RuleIndex > 0 {
+			name = fmt.Sprintf("export rule %v", rule.RuleIndex)
+			if indexes[rule.RuleIndex] {
+				problems = append(problems, fmt.Sprintf("rule index %v is used more than once", rule.RuleIndex))
+			}
+			indexes[rule.RuleIndex] = true
+		}
+		for _, problem := range rule.Problems() {
+			problems = append(problems, fmt.Sprintf("%v: %v", name, problem))
+		}
+
+		if len(protocolTypes) == 0 {
+			continue
+		}
+		if (rule.NFSv3 && !utils.Contains(protocolTypes, nfsv3)) || (rule.NFSv41 && !utils.Contains(protocolTypes, nfsv41)) {
+			problems = append(problems, fmt.Sprintf("%v: the volume is not served over every NFS version the rule allows, its protocols are %v", name, protocolTypes))
+		}
+		if !kerberosEnabled && rule.usesKerberos() {
+			problems = append(problems, fmt.Sprintf("%v: Kerberos is not enabled on the volume", name))
+		}
+	}
+	return problems
+}
+
+// normalizeAllowedClients removes the spaces around the entries of a comma separated list of clients
+func normalizeAllowedClients(allowedClients string) string {
+	clients := []string{}
+	for _, client := range strings.Split(allowedClients, ",") {
+		if client = strings.TrimSpace(client); client != "" {
+			clients = append(clients, client)
+		}
+	}
+	return strings.Join(clients, ",")
+}
+
+func valueOf(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func boolValue(value *bool) bool {
+	return value != nil && *value
+}
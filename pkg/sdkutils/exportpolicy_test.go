@@ -0,0 +1,333 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+package sdkutils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+)
+
+// testExportRule returns a valid NFSv3 rule
+func testExportRule(ruleIndex int32, allowedClients string) ExportRule {
+	return ExportRule{RuleIndex: ruleIndex, AllowedClients: allowedClients, NFSv3: true}
+}
+
+// testExportVolume returns an NFSv3 and NFSv4.1 volume with export rules, kerberos enables Kerberos on it
+func testExportVolume(kerberos bool, rules ...ExportRule) *armnetapp.Volume {
+	sdkRules := []*armnetapp.ExportPolicyRule{}
+	for _, rule := range rules {
+		sdkRules = append(sdkRules, rule.ExportPolicyRule())
+	}
+	return &armnetapp.Volume{
+		Properties: &armnetapp.VolumeProperties{
+			ProtocolTypes:   []*string{to.Ptr(nfsv3), to.Ptr(nfsv41)},
+			KerberosEnabled: to.Ptr(kerberos),
+			ExportPolicy:    &armnetapp.VolumePropertiesExportPolicy{Rules: sdkRules},
+		},
+	}
+}
+
+func TestExportPolicyBuilder(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(b *ExportPolicyBuilder) error
+		// clients are the allowed clients of the rules in index order
+		clients []string
+		// problem is a part of the expected error, empty when the edit succeeds
+		problem string
+	}{
+		{
+			name:    "rules of the volume ordered by index",
+			edit:    func(b *ExportPolicyBuilder) error { return nil },
+			clients: []string{"10.0.1.0/24", "10.0.2.0/24"},
+		},
+		{
+			name:    "add at the first index",
+			edit:    func(b *ExportPolicyBuilder) error { return b.Add(testExportRule(1, "10.0.3.0/24")) },
+			clients: []string{"10.0.3.0/24", "10.0.1.0/24", "10.0.2.0/24"},
+		},
+		{
+			name:    "add between rules",
+			edit:    func(b *ExportPolicyBuilder) error { return b.Add(testExportRule(2, "10.0.3.0/24")) },
+			clients: []string{"10.0.1.0/24", "10.0.3.0/24", "10.0.2.0/24"},
+		},
+		{
+			name:    "add without index",
+			edit:    func(b *ExportPolicyBuilder) error { return b.Add(testExportRule(0, "10.0.3.0/24")) },
+			clients: []string{"10.0.1.0/24", "10.0.2.0/24", "10.0.3.0/24"},
+		},
+		{
+			name:    "add past the end",
+			edit:    func(b *ExportPolicyBuilder) error { return b.Add(testExportRule(9, "10.0.3.0/24")) },
+			clients: []string{"10.0.1.0/24", "10.0.2.0/24", "10.0.3.0/24"},
+		},
+		{
+			name: "add a sixth rule",
+			edit: func(b *ExportPolicyBuilder) error {
+				for _, clients := range []string{"10.0.3.0/24", "10.0.4.0/24", "10.0.5.0/24", "10.0.6.0/24"} {
+					if err := b.Add(testExportRule(0, clients)); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			clients: []string{"10.0.1.0/24", "10.0.2.0/24", "10.0.3.0/24", "10.0.4.0/24", "10.0.5.0/24"},
+			problem: "at most 5 rules",
+		},
+		{
+			name:    "add an invalid rule",
+			edit:    func(b *ExportPolicyBuilder) error { return b.Add(testExportRule(1, "10.0.0.1/24")) },
+			clients: []string{"10.0.1.0/24", "10.0.2.0/24"},
+			problem: "has host bits set",
+		},
+		{
+			name:    "remove the first rule",
+			edit:    func(b *ExportPolicyBuilder) error { return b.Remove(1) },
+			clients: []string{"10.0.2.0/24"},
+		},
+		{
+			name:    "remove a missing rule",
+			edit:    func(b *ExportPolicyBuilder) error { return b.Remove(3) },
+			clients: []string{"10.0.1.0/24", "10.0.2.0/24"},
+			problem: "export rule 3 not found",
+		},
+		{
+			name:    "remove index 0",
+			edit:    func(b *ExportPolicyBuilder) error { return b.Remove(0) },
+			clients: []string{"10.0.1.0/24", "10.0.2.0/24"},
+			problem: "export rule 0 not found",
+		},
+		{
+			name: "replace orders indexed rules first",
+			edit: func(b *ExportPolicyBuilder) error {
+				return b.Replace([]ExportRule{
+					testExportRule(0, "10.0.5.0/24"),
+					testExportRule(7, "10.0.4.0/24"),
+					testExportRule(0, "10.0.6.0/24"),
+					testExportRule(3, "10.0.3.0/24"),
+				})
+			},
+			clients: []string{"10.0.3.0/24", "10.0.4.0/24", "10.0.5.0/24", "10.0.6.0/24"},
+		},
+		{
+			name: "replace with six rules",
+			edit: func(b *ExportPolicyBuilder) error {
+				rules := []ExportRule{}
+				for i := 1; i <= 6; i++ {
+					rules = append(rules, testExportRule(0, fmt.Sprintf("10.0.%v.0/24", i)))
+				}
+				return b.Replace(rules)
+			},
+			clients: []string{"10.0.1.0/24", "10.0.2.0/24"},
+			problem: "at most 5 rules, got 6",
+		},
+		{
+			name: "replace with a duplicate index",
+			edit: func(b *ExportPolicyBuilder) error {
+				return b.Replace([]ExportRule{testExportRule(1, "10.0.3.0/24"), testExportRule(1, "10.0.4.0/24")})
+			},
+			clients: []string{"10.0.1.0/24", "10.0.2.0/24"},
+			problem: "rule index 1 is used more than once",
+		},
+		{
+			name: "Kerberos on a volume without Kerberos",
+			edit: func(b *ExportPolicyBuilder) error {
+				return b.Add(ExportRule{AllowedClients: "10.0.3.0/24", NFSv41: true, Security: []string{SecurityKrb5}})
+			},
+			clients: []string{"10.0.1.0/24", "10.0.2.0/24"},
+			problem: "Kerberos is not enabled on the volume",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The rules of the volume are listed out of order
+			builder := NewExportPolicyBuilder(testExportVolume(false, testExportRule(2, "10.0.2.0/24"), testExportRule(1, "10.0.1.0/24")))
+
+			err := tt.edit(builder)
+			switch {
+			case tt.problem == "" && err != nil:
+				t.Fatalf("error = %v, want nil", err)
+			case tt.problem != "" && (!errors.Is(err, ErrInvalidArgument) || !strings.Contains(err.Error(), tt.problem)):
+				t.Fatalf("error = %v, want ErrInvalidArgument reporting %q", err, tt.problem)
+			}
+
+			rules := builder.Rules()
+			clients := []string{}
+			for i, rule := range rules {
+				clients = append(clients, rule.AllowedClients)
+				if rule.RuleIndex != int32(i+1) {
+					t.Errorf("rule %v has index %v, want the rules numbered from 1", rule.AllowedClients, rule.RuleIndex)
+				}
+			}
+			if !reflect.DeepEqual(clients, tt.clients) {
+				t.Errorf("rules = %v, want %v", clients, tt.clients)
+			}
+		})
+	}
+}
+
+func TestExportPolicyBuilderKerberosVolume(t *testing.T) {
+	builder := NewExportPolicyBuilder(testExportVolume(true))
+	if err := builder.Add(ExportRule{AllowedClients: "10.0.0.0/24", NFSv41: true, Security: []string{SecurityKrb5p}}); err != nil {
+		t.Errorf("Add() of a Kerberos rule error = %v, want nil", err)
+	}
+
+	err := builder.Add(ExportRule{AllowedClients: "10.0.1.0/24", NFSv3: true, Security: []string{SecurityKrb5}})
+	if !errors.Is(err, ErrInvalidArgument) || !strings.Contains(err.Error(), "Kerberos security flavors require NFSv4.1") {
+		t.Errorf("Add() of a Kerberos NFSv3 rule error = %v, want ErrInvalidArgument", err)
+	}
+}
+
+func TestExportRuleProblems(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(r *ExportRule)
+		// problems are parts of the expected problems, none for a valid rule
+		problems []string
+	}{
+		{
+			name: "valid",
+			edit: func(r *ExportRule) {},
+		},
+		{
+			name: "addresses, ranges and host names",
+			edit: func(r *ExportRule) { r.AllowedClients = "10.0.0.4, 10.1.0.0/16,client01,client02.contoso.com,fd00::/8" },
+		},
+		{
+			name:     "no allowed clients",
+			edit:     func(r *ExportRule) { r.AllowedClients = " " },
+			problems: []string{"allowed clients are required"},
+		},
+		{
+			name:     "CIDR range with host bits set",
+			edit:     func(r *ExportRule) { r.AllowedClients = "10.0.0.1/24" },
+			problems: []string{"CIDR range 10.0.0.1/24 has host bits set, the range is 10.0.0.0/24"},
+		},
+		{
+			name:     "invalid CIDR range",
+			edit:     func(r *ExportRule) { r.AllowedClients = "10.0.0.0/33" },
+			problems: []string{"invalid CIDR range 10.0.0.0/33"},
+		},
+		{
+			name:     "dotted numbers that are not an address",
+			edit:     func(r *ExportRule) { r.AllowedClients = "10.0.0.256,10.0.1" },
+			problems: []string{"invalid allowed client 10.0.0.256", "invalid allowed client 10.0.1"},
+		},
+		{
+			name:     "invalid host name",
+			edit:     func(r *ExportRule) { r.AllowedClients = "client_01" },
+			problems: []string{"invalid allowed client client_01"},
+		},
+		{
+			name:     "negative rule index",
+			edit:     func(r *ExportRule) { r.RuleIndex = -1 },
+			problems: []string{"rule index must be positive"},
+		},
+		{
+			name:     "no NFS version",
+			edit:     func(r *ExportRule) { r.NFSv3, r.CIFS = false, true },
+			problems: []string{"the rule must allow NFSv3 or NFSv4.1"},
+		},
+		{
+			name:     "invalid chown mode",
+			edit:     func(r *ExportRule) { r.ChownMode = "Open" },
+			problems: []string{"invalid chown mode Open"},
+		},
+		{
+			name:     "invalid security flavor",
+			edit:     func(r *ExportRule) { r.Security = []string{"ntlm"} },
+			problems: []string{"invalid security flavor ntlm"},
+		},
+		{
+			name:     "Kerberos on NFSv3",
+			edit:     func(r *ExportRule) { r.Security = []string{SecuritySys, SecurityKrb5i} },
+			problems: []string{"Kerberos security flavors require NFSv4.1"},
+		},
+		{
+			name: "Kerberos on NFSv4.1",
+			edit: func(r *ExportRule) {
+				r.NFSv3, r.NFSv41 = false, true
+				r.Security = []string{SecurityKrb5, SecurityKrb5p}
+			},
+		},
+		{
+			name:     "read only without access",
+			edit:     func(r *ExportRule) { r.NoAccess, r.ReadOnly = true, true },
+			problems: []string{"a rule without access cannot be read only"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := testExportRule(1, "10.0.0.0/24")
+			tt.edit(&rule)
+
+			problems := rule.Problems()
+			if len(problems) != len(tt.problems) {
+				t.Fatalf("Problems() = %q, want %v problems", problems, len(tt.problems))
+			}
+			for i, problem := range tt.problems {
+				if !strings.Contains(problems[i], problem) {
+					t.Errorf("problem %v = %q, want it to report %q", i+1, problems[i], problem)
+				}
+			}
+		})
+	}
+}
+
+func TestExportRuleRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		rule ExportRule
+		// want is the rule read back from the SDK rule
+		want ExportRule
+	}{
+		{
+			name: "read write sys",
+			rule: ExportRule{RuleIndex: 1, AllowedClients: "10.0.0.0/24, client01", NFSv3: true},
+			want: ExportRule{RuleIndex: 1, AllowedClients: "10.0.0.0/24,client01", NFSv3: true, RootAccess: to.Ptr(true), Security: []string{SecuritySys}},
+		},
+		{
+			name: "read only Kerberos without root access",
+			rule: ExportRule{RuleIndex: 2, AllowedClients: "10.0.1.0/24", NFSv41: true, ReadOnly: true, RootAccess: to.Ptr(false), ChownMode: "unrestricted", Security: []string{SecurityKrb5p, SecurityKrb5}},
+			want: ExportRule{RuleIndex: 2, AllowedClients: "10.0.1.0/24", NFSv41: true, ReadOnly: true, RootAccess: to.Ptr(false), ChownMode: "Unrestricted", Security: []string{SecurityKrb5, SecurityKrb5p}},
+		},
+		{
+			name: "dual protocol",
+			rule: ExportRule{RuleIndex: 3, AllowedClients: "10.0.2.0/24", NFSv3: true, CIFS: true, ChownMode: "Restricted"},
+			want: ExportRule{RuleIndex: 3, AllowedClients: "10.0.2.0/24", NFSv3: true, CIFS: true, RootAccess: to.Ptr(true), ChownMode: "Restricted", Security: []string{SecuritySys}},
+		},
+		{
+			name: "no access",
+			rule: ExportRule{RuleIndex: 4, AllowedClients: "10.0.3.4", NFSv3: true, NoAccess: true},
+			want: ExportRule{RuleIndex: 4, AllowedClients: "10.0.3.4", NFSv3: true, NoAccess: true, RootAccess: to.Ptr(true), Security: []string{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewExportRule(tt.rule.ExportPolicyRule()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewExportRule(ExportPolicyRule()) = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// The service omits the access flags of a rule without access
+	rule := NewExportRule(&armnetapp.ExportPolicyRule{RuleIndex: to.Ptr[int32](1), AllowedClients: to.Ptr("10.0.0.0/24"), Nfsv3: to.Ptr(true)})
+	if !rule.NoAccess || rule.ReadOnly {
+		t.Errorf("NewExportRule() of a rule without access flags = %+v, want a rule without access", rule)
+	}
+	if sdkRule := rule.ExportPolicyRule(); boolValue(sdkRule.UnixReadOnly) || boolValue(sdkRule.UnixReadWrite) {
+		t.Errorf("ExportPolicyRule() of a rule without access grants sys access: %+v", sdkRule)
+	}
+}
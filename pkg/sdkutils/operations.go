@@ -0,0 +1,349 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// Long-running operations. Every operation started by a Client is
+// recorded in its Journal together with the resume token of its poller,
+// so that a later process can re-attach to it after a crash or Ctrl-C.
+// The Begin methods of the Client return the Operation without waiting.
+
+package sdkutils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+)
+
+// Operation types
+const (
+	OperationAccountCreate           = "account create"
+	OperationAccountUpdate           = "account update"
+	OperationAccountDelete           = "account delete"
+	OperationPoolCreate              = "pool create"
+	OperationPoolUpdate              = "pool update"
+	OperationPoolDelete              = "pool delete"
+	OperationVolumeCreate            = "volume create"
+	OperationVolumeUpdate            = "volume update"
+	OperationVolumeDelete            = "volume delete"
+	OperationVolumeRevert            = "volume revert"
+	OperationReplicationAuthorize    = "replication authorize"
+	OperationReplicationBreak        = "replication break"
+	OperationReplicationResync       = "replication resync"
+	OperationReplicationReInitialize = "replication reinitialize"
+	OperationReplicationDelete       = "replication delete"
+	OperationSnapshotCreate          = "snapshot create"
+	OperationSnapshotDelete          = "snapshot delete"
+	OperationSnapshotPolicyUpdate    = "snapshot policy update"
+	OperationSnapshotPolicyDelete    = "snapshot policy delete"
+	OperationBackupPolicyCreate      = "backup policy create"
+	OperationBackupPolicyUpdate      = "backup policy update"
+	OperationBackupPolicyDelete      = "backup policy delete"
+	OperationBackupCreate            = "backup create"
+	OperationBackupDelete            = "backup delete"
+)
+
+// Operation statuses
+const (
+	OperationInProgress = "InProgress"
+	OperationSucceeded  = "Succeeded"
+	OperationFailed     = "Failed"
+)
+
+// Operation object definition, a long-running operation started by a Client
+type Operation struct {
+	ID          string     `json:"id"`
+	Type        string     `json:"type"`
+	ResourceID  string     `json:"resourceId"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"startedAt"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+	ResumeToken string     `json:"resumeToken"`
+
+	// poller is set on the operations started by this process, waiting on them needs no resume token
+	poller operationPoller
+}
+
+// Journal stores the operations of a Client, Save is called when an operation starts and when it finishes
+type Journal interface {
+	Save(operation *Operation) error
+}
+
+// SetJournal makes the client record its long-running operations in journal, nil disables the recording
+func (c *Client) SetJournal(journal Journal) {
+	c.journal = journal
+}
+
+// operationPoller is the part of a runtime.Poller used to re-attach to an operation, whatever its result type
+type operationPoller interface {
+	Poll(ctx context.Context) (*http.Response, error)
+	Done() bool
+	wait(ctx context.Context) error
+	result(ctx context.Context) error
+}
+
+// typedPoller adapts a runtime.Poller to operationPoller
+type typedPoller[T any] struct {
+	*runtime.Poller[T]
+}
+
+func (p typedPoller[T]) wait(ctx context.Context) error {
+	_, err := p.PollUntilDone(ctx, nil)
+	return err
+}
+
+func (p typedPoller[T]) result(ctx context.Context) error {
+	_, err := p.Result(ctx)
+	return err
+}
+
+// asOperationPoller wraps the result of a Begin call resuming an operation
+func asOperationPoller[T any](poller *runtime.Poller[T], err error) (operationPoller, error) {
+	if err != nil {
+		return nil, err
+	}
+	return typedPoller[T]{poller}, nil
+}
+
+// startOperation records an operation started by a Begin call in the journal and returns it without waiting.
+// An operation that completed synchronously is returned finished, with its outcome.
+func startOperation[T any](ctx context.Context, c *Client, poller *runtime.Poller[T], operationType, resourceID string) (*Operation, error) {
+	operation := &Operation{
+		ID:         newOperationID(),
+		Type:       operationType,
+		ResourceID: resourceID,
+		Status:     OperationInProgress,
+		StartedAt:  time.Now().UTC(),
+		poller:     typedPoller[T]{poller},
+	}
+
+	// A poller that completed synchronously has no resume token
+	if poller.Done() {
+		_, err := poller.Result(ctx)
+		err = newError(err)
+		c.finishOperation(operation, err)
+		return operation, err
+	}
+
+	token, err := poller.ResumeToken()
+	if err != nil {
+		return nil, fmt.Errorf("cannot get resume token of %v operation: %w", operationType, err)
+	}
+	operation.ResumeToken = token
+
+	if c.journal != nil {
+		if err := c.journal.Save(operation); err != nil {
+			return nil, fmt.Errorf("cannot record %v operation: %w", operationType, err)
+		}
+	}
+
+	return operation, nil
+}
+
+// pollUntilDone records an operation in the journal, waits for it and records its outcome.
+// An operation interrupted by the end of ctx stays in progress in the journal, so it can be resumed.
+func pollUntilDone[T any](ctx context.Context, c *Client, poller *runtime.Poller[T], operationType, resourceID string) (T, error) {
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
+
+	if c.journal == nil || poller.Done() {
+		resp, err := poller.PollUntilDone(ctx, nil)
+		return resp, newError(err)
+	}
+
+	operation, err := startOperation(ctx, c, poller, operationType, resourceID)
+	if err != nil {
+		return *new(T), err
+	}
+
+	resp, err := poller.PollUntilDone(ctx, nil)
+	err = newError(err)
+	if err != nil && ctx.Err() != nil {
+		return resp, fmt.Errorf("%w, operation %v is still in progress", err, operation.ID)
+	}
+
+	c.finishOperation(operation, err)
+	return resp, err
+}
+
+// finishOperation records the outcome of an operation in the journal
+func (c *Client) finishOperation(operation *Operation, err error) {
+	finishedAt := time.Now().UTC()
+	operation.FinishedAt = &finishedAt
+	operation.Status = OperationSucceeded
+	operation.Error = ""
+	if err != nil {
+		operation.Status = OperationFailed
+		operation.Error = err.Error()
+	}
+
+	if c.journal != nil {
+		// The operation itself is over, failing to record it must not fail the caller
+		_ = c.journal.Save(operation)
+	}
+}
+
+// RefreshOperation checks an operation in progress once and records its status when it finished
+func (c *Client) RefreshOperation(ctx context.Context, operation *Operation) error {
+	if operation.Status != OperationInProgress {
+		return nil
+	}
+
+	poller, err := c.resumePoller(ctx, operation)
+	if err != nil {
+		return err
+	}
+	if _, err := poller.Poll(ctx); err != nil {
+		if IsNotFound(err) {
+			// The operation status is kept by Azure for a limited time only
+			c.finishOperation(operation, fmt.Errorf("the operation status is no longer available: %w", newError(err)))
+			return nil
+		}
+		return fmt.Errorf("cannot get status of operation %v: %w", operation.ID, newError(err))
+	}
+	if poller.Done() {
+		c.finishOperation(operation, newError(poller.result(ctx)))
+	}
+
+	return nil
+}
+
+// WaitForOperation waits until an operation in progress finished, re-attaching to it through
+// its resume token when it was started by another process
+func (c *Client) WaitForOperation(ctx context.Context, operation *Operation) error {
+	if operation.Status != OperationInProgress {
+		return nil
+	}
+
+	poller, err := c.resumePoller(ctx, operation)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
+
+	err = newError(poller.wait(ctx))
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("%w, operation %v is still in progress", err, operation.ID)
+	}
+
+	c.finishOperation(operation, err)
+	return err
+}
+
+// ResumeOperation re-attaches to an operation and, once it finished, waits for its resource to reach the
+// state the operation leads to, e.g. a created volume to be ready or a deleted one to be gone
+func (c *Client) ResumeOperation(ctx context.Context, operation *Operation, options *WaitOptions) error {
+	if err := c.WaitForOperation(ctx, operation); err != nil {
+		return err
+	}
+	if operation.Status == OperationFailed {
+		return fmt.Errorf("operation %v failed: %v", operation.ID, operation.Error)
+	}
+
+	switch operation.Type {
+	case OperationAccountDelete, OperationPoolDelete, OperationVolumeDelete, OperationSnapshotDelete, OperationSnapshotPolicyDelete, OperationBackupPolicyDelete, OperationBackupDelete:
+		return c.WaitForNoANFResource(ctx, operation.ResourceID, options)
+	case OperationReplicationDelete:
+		return c.WaitForNoANFReplication(ctx, operation.ResourceID, options)
+	case OperationReplicationBreak:
+		return c.WaitForANFReplication(ctx, operation.ResourceID, armnetapp.MirrorStateBroken, options)
+	case OperationReplicationAuthorize, OperationReplicationResync, OperationReplicationReInitialize:
+		return c.WaitForANFReplication(ctx, operation.ResourceID, armnetapp.MirrorStateMirrored, options)
+	}
+	return c.WaitForANFResource(ctx, operation.ResourceID, options)
+}
+
+// resumePoller rehydrates the poller of an operation from its resume token
+func (c *Client) resumePoller(ctx context.Context, operation *Operation) (operationPoller, error) {
+	if operation.poller != nil {
+		return operation.poller, nil
+	}
+
+	token := operation.ResumeToken
+
+	var poller operationPoller
+	var err error
+	switch operation.Type {
+	case OperationAccountCreate:
+		poller, err = asOperationPoller(c.accounts.BeginCreateOrUpdate(ctx, "", "", armnetapp.Account{}, &armnetapp.AccountsClientBeginCreateOrUpdateOptions{ResumeToken: token}))
+	case OperationAccountUpdate:
+		poller, err = asOperationPoller(c.accounts.BeginUpdate(ctx, "", "", armnetapp.AccountPatch{}, &armnetapp.AccountsClientBeginUpdateOptions{ResumeToken: token}))
+	case OperationAccountDelete:
+		poller, err = asOperationPoller(c.accounts.BeginDelete(ctx, "", "", &armnetapp.AccountsClientBeginDeleteOptions{ResumeToken: token}))
+	case OperationPoolCreate:
+		poller, err = asOperationPoller(c.pools.BeginCreateOrUpdate(ctx, "", "", "", armnetapp.CapacityPool{}, &armnetapp.PoolsClientBeginCreateOrUpdateOptions{ResumeToken: token}))
+	case OperationPoolUpdate:
+		poller, err = asOperationPoller(c.pools.BeginUpdate(ctx, "", "", "", armnetapp.CapacityPoolPatch{}, &armnetapp.PoolsClientBeginUpdateOptions{ResumeToken: token}))
+	case OperationPoolDelete:
+		poller, err = asOperationPoller(c.pools.BeginDelete(ctx, "", "", "", &armnetapp.PoolsClientBeginDeleteOptions{ResumeToken: token}))
+	case OperationVolumeCreate:
+		poller, err = asOperationPoller(c.volumes.BeginCreateOrUpdate(ctx, "", "", "", "", armnetapp.Volume{}, &armnetapp.VolumesClientBeginCreateOrUpdateOptions{ResumeToken: token}))
+	case OperationVolumeUpdate:
+		poller, err = asOperationPoller(c.volumes.BeginUpdate(ctx, "", "", "", "", armnetapp.VolumePatch{}, &armnetapp.VolumesClientBeginUpdateOptions{ResumeToken: token}))
+	case OperationVolumeDelete:
+		poller, err = asOperationPoller(c.volumes.BeginDelete(ctx, "", "", "", "", &armnetapp.VolumesClientBeginDeleteOptions{ResumeToken: token}))
+	case OperationVolumeRevert:
+		poller, err = asOperationPoller(c.volumes.BeginRevert(ctx, "", "", "", "", armnetapp.VolumeRevert{}, &armnetapp.VolumesClientBeginRevertOptions{ResumeToken: token}))
+	case OperationReplicationAuthorize:
+		poller, err = asOperationPoller(c.volumes.BeginAuthorizeReplication(ctx, "", "", "", "", armnetapp.AuthorizeRequest{}, &armnetapp.VolumesClientBeginAuthorizeReplicationOptions{ResumeToken: token}))
+	case OperationReplicationBreak:
+		poller, err = asOperationPoller(c.volumes.BeginBreakReplication(ctx, "", "", "", "", &armnetapp.VolumesClientBeginBreakReplicationOptions{ResumeToken: token}))
+	case OperationReplicationResync:
+		poller, err = asOperationPoller(c.volumes.BeginResyncReplication(ctx, "", "", "", "", &armnetapp.VolumesClientBeginResyncReplicationOptions{ResumeToken: token}))
+	case OperationReplicationReInitialize:
+		poller, err = asOperationPoller(c.volumes.BeginReInitializeReplication(ctx, "", "", "", "", &armnetapp.VolumesClientBeginReInitializeReplicationOptions{ResumeToken: token}))
+	case OperationReplicationDelete:
+		poller, err = asOperationPoller(c.volumes.BeginDeleteReplication(ctx, "", "", "", "", &armnetapp.VolumesClientBeginDeleteReplicationOptions{ResumeToken: token}))
+	case OperationSnapshotCreate:
+		poller, err = asOperationPoller(c.snapshots.BeginCreate(ctx, "", "", "", "", "", armnetapp.Snapshot{}, &armnetapp.SnapshotsClientBeginCreateOptions{ResumeToken: token}))
+	case OperationSnapshotDelete:
+		poller, err = asOperationPoller(c.snapshots.BeginDelete(ctx, "", "", "", "", "", &armnetapp.SnapshotsClientBeginDeleteOptions{ResumeToken: token}))
+	case OperationSnapshotPolicyUpdate:
+		poller, err = asOperationPoller(c.snapshotPolicies.BeginUpdate(ctx, "", "", "", armnetapp.SnapshotPolicyPatch{}, &armnetapp.SnapshotPoliciesClientBeginUpdateOptions{ResumeToken: token}))
+	case OperationSnapshotPolicyDelete:
+		poller, err = asOperationPoller(c.snapshotPolicies.BeginDelete(ctx, "", "", "", &armnetapp.SnapshotPoliciesClientBeginDeleteOptions{ResumeToken: token}))
+	case OperationBackupPolicyCreate:
+		poller, err = asOperationPoller(c.backupPolicies.BeginCreate(ctx, "", "", "", armnetapp.BackupPolicy{}, &armnetapp.BackupPoliciesClientBeginCreateOptions{ResumeToken: token}))
+	case OperationBackupPolicyUpdate:
+		poller, err = asOperationPoller(c.backupPolicies.BeginUpdate(ctx, "", "", "", armnetapp.BackupPolicyPatch{}, &armnetapp.BackupPoliciesClientBeginUpdateOptions{ResumeToken: token}))
+	case OperationBackupPolicyDelete:
+		poller, err = asOperationPoller(c.backupPolicies.BeginDelete(ctx, "", "", "", &armnetapp.BackupPoliciesClientBeginDeleteOptions{ResumeToken: token}))
+	case OperationBackupCreate:
+		poller, err = asOperationPoller(c.backups.BeginCreate(ctx, "", "", "", "", "", armnetapp.Backup{}, &armnetapp.BackupsClientBeginCreateOptions{ResumeToken: token}))
+	case OperationBackupDelete:
+		poller, err = asOperationPoller(c.backups.BeginDelete(ctx, "", "", "", "", "", &armnetapp.BackupsClientBeginDeleteOptions{ResumeToken: token}))
+	default:
+		return nil, fmt.Errorf("operation %v has unknown type %q", operation.ID, operation.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot resume operation %v: %w", operation.ID, newError(err))
+	}
+
+	return poller, nil
+}
+
+// anfResourceID builds the ID of a Microsoft.NetApp resource of the client subscription from type and name pairs
+func (c *Client) anfResourceID(resourceGroupName string, typesAndNames ...string) string {
+	resourceID := fmt.Sprintf("/subscriptions/%v/resourceGroups/%v/providers/Microsoft.NetApp", c.subscriptionID, resourceGroupName)
+	for _, segment := range typesAndNames {
+		resourceID += "/" + segment
+	}
+	return resourceID
+}
+
+// newOperationID returns a short random operation ID
+func newOperationID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
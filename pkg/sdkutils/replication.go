@@ -0,0 +1,632 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// Cross-region replication of volumes. A replication is created on the
+// destination volume, a DataProtection volume referencing its source,
+// then authorized on the source volume; the baseline transfer runs until
+// the mirror state of the relationship becomes Mirrored. Breaking the
+// replication makes the destination writable, resyncing it from the
+// destination resumes it and from the source reverses its direction.
+
+package sdkutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/patrikcze/go-anf/pkg/uri"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+)
+
+const (
+	// poolSizeIncrement is the granularity capacity pools are provisioned in
+	poolSizeIncrement int64 = 1 << 40
+
+	minPoolSize = 2 * poolSizeIncrement
+
+	// dataProtectionVolumeType is the volume type of replication destinations
+	dataProtectionVolumeType = "DataProtection"
+
+	// transferSnapshotPrefix starts the name of the snapshots taken on the destination by every replication transfer
+	transferSnapshotPrefix = "snapmirror."
+)
+
+// replicationIntervals are the times between two transfers of each replication schedule
+var replicationIntervals = map[armnetapp.ReplicationSchedule]time.Duration{
+	armnetapp.ReplicationSchedule10Minutely: 10 * time.Minute,
+	armnetapp.ReplicationScheduleHourly:     time.Hour,
+	armnetapp.ReplicationScheduleDaily:      24 * time.Hour,
+}
+
+// ReplicationSpec object definition, the destination of the cross-region replication of a volume.
+// The empty destination names are derived from the source volume ID.
+type ReplicationSpec struct {
+	// SourceVolumeID is the resource ID of the replicated volume
+	SourceVolumeID string
+	// Location is the region of the destination, it must differ from the region of the source
+	Location string
+	// ResourceGroup of the destination, defaults to the resource group of the source
+	ResourceGroup string
+	// Account of the destination, defaults to the source account name suffixed with the location
+	Account string
+	// Pool of the destination, defaults to the name of the source capacity pool
+	Pool string
+	// Name of the destination volume, defaults to the name of the source volume
+	Name string
+	// SubnetID is the resource ID of a delegated subnet in the destination region
+	SubnetID string
+	// Schedule is 10minutely, hourly or daily
+	Schedule string
+	// ServiceLevel of a created destination pool, defaults to the service level of the source volume
+	ServiceLevel string
+	// PoolSize of a created destination pool in bytes, defaults to the smallest pool the volume fits in
+	PoolSize int64
+	Tags     map[string]*string
+	// OnProgress is called with a message before every step, e.g. to log it
+	OnProgress func(message string)
+}
+
+// ReplicationStatus object definition, the state of the replication between a source and a destination volume
+type ReplicationStatus struct {
+	SourceVolumeID      string `json:"sourceVolumeId"`
+	DestinationVolumeID string `json:"destinationVolumeId"`
+	// Schedule is 10minutely, hourly or daily
+	Schedule string `json:"schedule,omitempty"`
+	Healthy  bool   `json:"healthy"`
+	// MirrorState is Uninitialized, Mirrored or Broken
+	MirrorState string `json:"mirrorState"`
+	// RelationshipStatus is Idle or Transferring
+	RelationshipStatus string `json:"relationshipStatus"`
+	// TotalTransferBytes is the amount of data transferred by the replication
+	TotalTransferBytes int64 `json:"totalTransferBytes"`
+	// LastTransfer is the time of the newest transfer snapshot on the destination, nil before the first transfer
+	LastTransfer *time.Time `json:"lastTransfer,omitempty"`
+	// Lag is the time since the last transfer, the age of the data of the destination
+	Lag          time.Duration `json:"-"`
+	LagSeconds   int64         `json:"lagSeconds"`
+	ErrorMessage string        `json:"errorMessage,omitempty"`
+	// NotMonitored tells why the state of the replication was not read, e.g. a destination volume in another subscription
+	NotMonitored string `json:"notMonitored,omitempty"`
+}
+
+// ValidateANFReplicationSchedule converts a case insensitive replication schedule into its SDK value
+func ValidateANFReplicationSchedule(schedule string) (armnetapp.ReplicationSchedule, error) {
+	switch strings.ToLower(schedule) {
+	case "10minutely", "_10minutely":
+		return armnetapp.ReplicationSchedule10Minutely, nil
+	case "hourly":
+		return armnetapp.ReplicationScheduleHourly, nil
+	case "daily":
+		return armnetapp.ReplicationScheduleDaily, nil
+	}
+
+	return "", invalidArgument("invalid replication schedule %v, valid schedules are: 10minutely, hourly, daily", schedule)
+}
+
+// ReplicationInterval returns the time between two transfers of a replication schedule, 0 for an unknown schedule
+func ReplicationInterval(schedule string) time.Duration {
+	replicationSchedule, err := ValidateANFReplicationSchedule(schedule)
+	if err != nil {
+		return 0
+	}
+	return replicationIntervals[replicationSchedule]
+}
+
+// withDefaults validates the spec and returns it with the destination names derived from the source volume ID
+func (s ReplicationSpec) withDefaults() (ReplicationSpec, error) {
+	problems := []string{}
+	if !uri.IsANFVolume(s.SourceVolumeID) {
+		problems = append(problems, fmt.Sprintf("source %v is not a volume resource ID", s.SourceVolumeID))
+	}
+	if s.Location == "" {
+		problems = append(problems, "destination location is required")
+	}
+	if s.SubnetID == "" {
+		problems = append(problems, "destination subnet ID is required")
+	}
+	if _, err := ValidateANFReplicationSchedule(s.Schedule); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if s.PoolSize != 0 && (s.PoolSize < minPoolSize || s.PoolSize%poolSizeIncrement != 0) {
+		problems = append(problems, "destination pool size must be a whole number of TiB, at least 2 TiB")
+	}
+	if len(problems) > 0 {
+		return s, invalidArgument("invalid replication: %v", strings.Join(problems, "; "))
+	}
+
+	if s.ResourceGroup == "" {
+		s.ResourceGroup = uri.GetResourceGroup(s.SourceVolumeID)
+	}
+	if s.Account == "" {
+		s.Account = fmt.Sprintf("%v-%v", uri.GetANFAccount(s.SourceVolumeID), normalizeLocation(s.Location))
+	}
+	if s.Pool == "" {
+		s.Pool = uri.GetANFCapacityPool(s.SourceVolumeID)
+	}
+	if s.Name == "" {
+		s.Name = uri.GetANFVolume(s.SourceVolumeID)
+	}
+	if s.OnProgress == nil {
+		s.OnProgress = func(string) {}
+	}
+
+	return s, nil
+}
+
+// CreateANFReplication replicates a volume into another region: the destination account, capacity pool
+// and volume are created when missing, the replication is authorized on the source volume and the
+// baseline transfer is awaited until the mirror state is Mirrored. It returns the destination volume.
+// Running it again resumes an interrupted setup, an existing destination must replicate the same source.
+func (c *Client) CreateANFReplication(ctx context.Context, spec ReplicationSpec) (*armnetapp.Volume, error) {
+	spec, err := spec.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	sourceID := spec.SourceVolumeID
+	source, err := c.GetANFVolume(ctx, uri.GetResourceGroup(sourceID), uri.GetANFAccount(sourceID), uri.GetANFCapacityPool(sourceID), uri.GetANFVolume(sourceID))
+	if err != nil {
+		return nil, err
+	}
+	destinationID := c.anfResourceID(spec.ResourceGroup, "netAppAccounts", spec.Account, "capacityPools", spec.Pool, "volumes", spec.Name)
+	if isReplicationDestination(source) {
+		return nil, invalidArgument("volume %v is a replication destination, it cannot be replicated", uri.GetANFVolume(sourceID))
+	}
+	if remoteID := replicationRemoteVolumeID(source); remoteID != "" && !strings.EqualFold(remoteID, destinationID) {
+		return nil, fmt.Errorf("volume %v is already replicated into %v: %w", uri.GetANFVolume(sourceID), remoteID, ErrConflict)
+	}
+	if normalizeLocation(valueOf(source.Location)) == normalizeLocation(spec.Location) {
+		return nil, invalidArgument("volume %v is in %v, a cross-region replication needs a destination in another region", uri.GetANFVolume(sourceID), valueOf(source.Location))
+	}
+	if spec.ServiceLevel == "" && source.Properties != nil && source.Properties.ServiceLevel != nil {
+		spec.ServiceLevel = string(*source.Properties.ServiceLevel)
+	}
+
+	if err := c.ensureReplicationAccount(ctx, spec); err != nil {
+		return nil, err
+	}
+	pool, err := c.ensureReplicationPool(ctx, spec, source)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.ensureReplicationVolume(ctx, spec, source, pool); err != nil {
+		return nil, err
+	}
+
+	status, err := c.getReplicationStatus(ctx, destinationID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get replication status: %w", newError(err))
+	}
+	switch {
+	case status.MirrorState != nil && *status.MirrorState == armnetapp.MirrorStateBroken:
+		return nil, fmt.Errorf("replication of volume %v is broken, resync it instead: %w", spec.Name, ErrConflict)
+	case status.MirrorState != nil && *status.MirrorState == armnetapp.MirrorStateMirrored:
+		// Authorized by a previous run, the baseline transfer completed
+	case status.RelationshipStatus != nil && *status.RelationshipStatus == armnetapp.RelationshipStatusTransferring:
+		// Authorized by a previous run, the baseline transfer is in progress
+	default:
+		spec.OnProgress(fmt.Sprintf("Authorizing the replication on source volume %v...", uri.GetANFVolume(sourceID)))
+		if err := c.AuthorizeReplication(ctx, uri.GetResourceGroup(sourceID), uri.GetANFAccount(sourceID), uri.GetANFCapacityPool(sourceID), uri.GetANFVolume(sourceID), destinationID); err != nil {
+			return nil, err
+		}
+	}
+
+	spec.OnProgress(fmt.Sprintf("Waiting for volume %v to be mirrored...", spec.Name))
+	if err := c.WaitForANFReplication(ctx, destinationID, armnetapp.MirrorStateMirrored, nil); err != nil {
+		return nil, err
+	}
+
+	return c.GetANFVolume(ctx, spec.ResourceGroup, spec.Account, spec.Pool, spec.Name)
+}
+
+// GetANFVolumeReplicationStatus reads the replication status of the source or the destination volume of a replication.
+// The lag is the time since the newest transfer snapshot on the destination volume. The state of a replication into
+// another subscription is read through its destination volume, which the client cannot reach: only the volume IDs
+// and the schedule are returned, with the reason in NotMonitored.
+func (c *Client) GetANFVolumeReplicationStatus(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string) (*ReplicationStatus, error) {
+	volume, err := c.GetANFVolume(ctx, resourceGroupName, accountName, poolName, volumeName)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &ReplicationStatus{}
+	if isReplicationDestination(volume) {
+		status.SourceVolumeID = replicationRemoteVolumeID(volume)
+		status.DestinationVolumeID = valueOf(volume.ID)
+	} else {
+		status.SourceVolumeID = valueOf(volume.ID)
+		status.DestinationVolumeID = replicationRemoteVolumeID(volume)
+	}
+	if status.DestinationVolumeID == "" {
+		return nil, fmt.Errorf("cannot get replication status: volume %v is not part of a replication: %w", volumeName, ErrNotFound)
+	}
+	if subscriptionID := uri.GetSubscription(status.DestinationVolumeID); !strings.EqualFold(subscriptionID, c.subscriptionID) {
+		if replication := replicationObject(volume); replication != nil && replication.ReplicationSchedule != nil {
+			status.Schedule = strings.TrimPrefix(string(*replication.ReplicationSchedule), "_")
+		}
+		status.NotMonitored = fmt.Sprintf("destination outside the subscription, in subscription %v, not monitored", subscriptionID)
+		return status, nil
+	}
+
+	replicationStatus, err := c.getReplicationStatus(ctx, status.DestinationVolumeID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get replication status: %w", newError(err))
+	}
+	if replicationStatus.Healthy != nil {
+		status.Healthy = *replicationStatus.Healthy
+	}
+	if replicationStatus.MirrorState != nil {
+		status.MirrorState = string(*replicationStatus.MirrorState)
+	}
+	if replicationStatus.RelationshipStatus != nil {
+		status.RelationshipStatus = string(*replicationStatus.RelationshipStatus)
+	}
+	status.TotalTransferBytes, _ = strconv.ParseInt(valueOf(replicationStatus.TotalProgress), 10, 64)
+	status.ErrorMessage = valueOf(replicationStatus.ErrorMessage)
+
+	destination := volume
+	if !strings.EqualFold(valueOf(volume.ID), status.DestinationVolumeID) {
+		destinationID := status.DestinationVolumeID
+		if destination, err = c.GetANFVolume(ctx, uri.GetResourceGroup(destinationID), uri.GetANFAccount(destinationID), uri.GetANFCapacityPool(destinationID), uri.GetANFVolume(destinationID)); err != nil {
+			return nil, err
+		}
+	}
+	if replication := replicationObject(destination); replication != nil && replication.ReplicationSchedule != nil {
+		status.Schedule = strings.TrimPrefix(string(*replication.ReplicationSchedule), "_")
+	}
+
+	lastTransfer, err := c.lastTransfer(ctx, status.DestinationVolumeID)
+	if err != nil {
+		return nil, err
+	}
+	if !lastTransfer.IsZero() {
+		status.LastTransfer = &lastTransfer
+		status.Lag = time.Since(lastTransfer).Truncate(time.Second)
+		status.LagSeconds = int64(status.Lag.Seconds())
+	}
+
+	return status, nil
+}
+
+// ListANFReplicatedVolumes returns the resource IDs of the volumes of the subscription that are part of a replication,
+// one per replication: its destination volume, or its source volume when the destination is not in the subscription.
+// The status of the latter is not monitored, see GetANFVolumeReplicationStatus.
+func (c *Client) ListANFReplicatedVolumes(ctx context.Context) ([]string, error) {
+	accounts, err := c.ListANFAccountsBySubscription(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	volumeIDs := []string{}
+	destinations := map[string]bool{}
+	sources := []*armnetapp.Volume{}
+	for _, account := range accounts {
+		accountID := valueOf(account.ID)
+		pools, err := c.ListANFCapacityPools(ctx, uri.GetResourceGroup(accountID), uri.GetANFAccount(accountID))
+		if err != nil {
+			return nil, err
+		}
+		for _, pool := range pools {
+			poolID := valueOf(pool.ID)
+			volumes, err := c.ListANFVolumes(ctx, uri.GetResourceGroup(poolID), uri.GetANFAccount(poolID), uri.GetANFCapacityPool(poolID))
+			if err != nil {
+				return nil, err
+			}
+			for _, volume := range volumes {
+				switch {
+				case isReplicationDestination(volume):
+					volumeIDs = append(volumeIDs, valueOf(volume.ID))
+					destinations[strings.ToLower(valueOf(volume.ID))] = true
+				case replicationRemoteVolumeID(volume) != "":
+					sources = append(sources, volume)
+				}
+			}
+		}
+	}
+
+	for _, source := range sources {
+		if !destinations[strings.ToLower(replicationRemoteVolumeID(source))] {
+			volumeIDs = append(volumeIDs, valueOf(source.ID))
+		}
+	}
+
+	return volumeIDs, nil
+}
+
+// BreakANFVolumeReplication breaks the replication of a destination volume, which becomes writable, and waits
+// for the Broken mirror state. force breaks the replication while a transfer is in progress.
+func (c *Client) BreakANFVolumeReplication(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string, force bool) error {
+	future, err := c.beginBreakANFVolumeReplication(ctx, resourceGroupName, accountName, poolName, volumeName, force)
+	if err != nil {
+		return err
+	}
+
+	volumeID := c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName)
+	_, err = pollUntilDone(ctx, c, future, OperationReplicationBreak, volumeID)
+	if err != nil {
+		return fmt.Errorf("cannot get break volume replication future response: %w", newError(err))
+	}
+
+	return c.WaitForANFReplication(ctx, volumeID, armnetapp.MirrorStateBroken, nil)
+}
+
+// BeginBreakANFVolumeReplication starts breaking the replication of a destination volume and returns its operation without waiting for it
+func (c *Client) BeginBreakANFVolumeReplication(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string, force bool) (*Operation, error) {
+	future, err := c.beginBreakANFVolumeReplication(ctx, resourceGroupName, accountName, poolName, volumeName, force)
+	if err != nil {
+		return nil, err
+	}
+
+	return startOperation(ctx, c, future, OperationReplicationBreak, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName))
+}
+
+// beginBreakANFVolumeReplication sends the request of BreakANFVolumeReplication
+func (c *Client) beginBreakANFVolumeReplication(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string, force bool) (*runtime.Poller[armnetapp.VolumesClientBreakReplicationResponse], error) {
+	volumeClient := c.volumes
+
+	future, err := volumeClient.BeginBreakReplication(
+		ctx,
+		resourceGroupName,
+		accountName,
+		poolName,
+		volumeName,
+		&armnetapp.VolumesClientBeginBreakReplicationOptions{
+			Body: &armnetapp.BreakReplicationRequest{
+				ForceBreakReplication: to.Ptr(force),
+			},
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot break volume replication: %w", newError(err))
+	}
+
+	return future, nil
+}
+
+// ResyncANFVolumeReplication resyncs a broken replication and waits for the Mirrored mirror state.
+// Called on the destination volume, the replication resumes and the changes made on the destination
+// since the break are discarded. Called on the source volume, the replication is reversed: the source
+// becomes the destination of the former destination and receives its changes.
+func (c *Client) ResyncANFVolumeReplication(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string) error {
+	future, err := c.beginResyncANFVolumeReplication(ctx, resourceGroupName, accountName, poolName, volumeName)
+	if err != nil {
+		return err
+	}
+
+	volumeID := c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName)
+	_, err = pollUntilDone(ctx, c, future, OperationReplicationResync, volumeID)
+	if err != nil {
+		return fmt.Errorf("cannot get resync volume replication future response: %w", newError(err))
+	}
+
+	return c.WaitForANFReplication(ctx, volumeID, armnetapp.MirrorStateMirrored, nil)
+}
+
+// BeginResyncANFVolumeReplication starts the resync of a broken replication and returns its operation without waiting for it
+func (c *Client) BeginResyncANFVolumeReplication(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string) (*Operation, error) {
+	future, err := c.beginResyncANFVolumeReplication(ctx, resourceGroupName, accountName, poolName, volumeName)
+	if err != nil {
+		return nil, err
+	}
+
+	return startOperation(ctx, c, future, OperationReplicationResync, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName))
+}
+
+// beginResyncANFVolumeReplication sends the request of ResyncANFVolumeReplication
+func (c *Client) beginResyncANFVolumeReplication(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string) (*runtime.Poller[armnetapp.VolumesClientResyncReplicationResponse], error) {
+	volumeClient := c.volumes
+
+	future, err := volumeClient.BeginResyncReplication(
+		ctx,
+		resourceGroupName,
+		accountName,
+		poolName,
+		volumeName,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resync volume replication: %w", newError(err))
+	}
+
+	return future, nil
+}
+
+// ReInitializeANFVolumeReplication restarts the baseline transfer of the replication of a destination volume,
+// e.g. after it failed, and waits for the Mirrored mirror state
+func (c *Client) ReInitializeANFVolumeReplication(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string) error {
+	future, err := c.beginReInitializeANFVolumeReplication(ctx, resourceGroupName, accountName, poolName, volumeName)
+	if err != nil {
+		return err
+	}
+
+	volumeID := c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName)
+	_, err = pollUntilDone(ctx, c, future, OperationReplicationReInitialize, volumeID)
+	if err != nil {
+		return fmt.Errorf("cannot get reinitialize volume replication future response: %w", newError(err))
+	}
+
+	return c.WaitForANFReplication(ctx, volumeID, armnetapp.MirrorStateMirrored, nil)
+}
+
+// BeginReInitializeANFVolumeReplication starts the reinitialization of a replication and returns its operation without waiting for it
+func (c *Client) BeginReInitializeANFVolumeReplication(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string) (*Operation, error) {
+	future, err := c.beginReInitializeANFVolumeReplication(ctx, resourceGroupName, accountName, poolName, volumeName)
+	if err != nil {
+		return nil, err
+	}
+
+	return startOperation(ctx, c, future, OperationReplicationReInitialize, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName))
+}
+
+// beginReInitializeANFVolumeReplication sends the request of ReInitializeANFVolumeReplication
+func (c *Client) beginReInitializeANFVolumeReplication(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string) (*runtime.Poller[armnetapp.VolumesClientReInitializeReplicationResponse], error) {
+	volumeClient := c.volumes
+
+	future, err := volumeClient.BeginReInitializeReplication(
+		ctx,
+		resourceGroupName,
+		accountName,
+		poolName,
+		volumeName,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reinitialize volume replication: %w", newError(err))
+	}
+
+	return future, nil
+}
+
+// lastTransfer returns the creation time of the newest transfer snapshot of a destination volume, zero when there is none
+func (c *Client) lastTransfer(ctx context.Context, destinationID string) (time.Time, error) {
+	snapshots, err := c.ListANFSnapshots(ctx, uri.GetResourceGroup(destinationID), uri.GetANFAccount(destinationID), uri.GetANFCapacityPool(destinationID), uri.GetANFVolume(destinationID))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var last time.Time
+	for _, snapshot := range snapshots {
+		if !strings.HasPrefix(uri.GetANFSnapshot(valueOf(snapshot.ID)), transferSnapshotPrefix) || snapshot.Properties == nil || snapshot.Properties.Created == nil {
+			continue
+		}
+		if created := *snapshot.Properties.Created; created.After(last) {
+			last = created
+		}
+	}
+	return last, nil
+}
+
+// ensureReplicationAccount creates the destination account when it does not exist
+func (c *Client) ensureReplicationAccount(ctx context.Context, spec ReplicationSpec) error {
+	account, err := c.GetANFAccount(ctx, spec.ResourceGroup, spec.Account)
+	if err == nil {
+		if normalizeLocation(valueOf(account.Location)) != normalizeLocation(spec.Location) {
+			return invalidArgument("account %v is in %v, not in %v", spec.Account, valueOf(account.Location), spec.Location)
+		}
+		return nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	spec.OnProgress(fmt.Sprintf("Creating account %v in %v...", spec.Account, spec.Location))
+	_, err = c.CreateANFAccount(ctx, spec.Location, spec.ResourceGroup, spec.Account, nil, spec.Tags)
+	return err
+}
+
+// ensureReplicationPool creates the destination capacity pool when it does not exist and returns it
+func (c *Client) ensureReplicationPool(ctx context.Context, spec ReplicationSpec, source *armnetapp.Volume) (*armnetapp.CapacityPool, error) {
+	pool, err := c.GetANFCapacityPool(ctx, spec.ResourceGroup, spec.Account, spec.Pool)
+	if err == nil || !errors.Is(err, ErrNotFound) {
+		return pool, err
+	}
+
+	size := spec.PoolSize
+	if size == 0 {
+		size = minPoolSize
+		if quota := volumeQuota(source); quota > size {
+			size = (quota + poolSizeIncrement - 1) / poolSizeIncrement * poolSizeIncrement
+		}
+	}
+
+	spec.OnProgress(fmt.Sprintf("Creating capacity pool %v...", spec.Pool))
+	return c.CreateANFCapacityPool(ctx, spec.Location, spec.ResourceGroup, spec.Account, spec.Pool, spec.ServiceLevel, size, spec.Tags)
+}
+
+// ensureReplicationVolume creates the destination volume when it does not exist,
+// an existing volume must already be the destination of the source volume
+func (c *Client) ensureReplicationVolume(ctx context.Context, spec ReplicationSpec, source *armnetapp.Volume, pool *armnetapp.CapacityPool) error {
+	volume, err := c.GetANFVolume(ctx, spec.ResourceGroup, spec.Account, spec.Pool, spec.Name)
+	if err == nil {
+		if !isReplicationDestination(volume) || !strings.EqualFold(replicationRemoteVolumeID(volume), spec.SourceVolumeID) {
+			return fmt.Errorf("volume %v exists and is not a replication destination of %v: %w", spec.Name, spec.SourceVolumeID, ErrConflict)
+		}
+		return nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	schedule, _ := ValidateANFReplicationSchedule(spec.Schedule)
+	volumeSpec := VolumeSpec{
+		Location:       spec.Location,
+		ResourceGroup:  spec.ResourceGroup,
+		Account:        spec.Account,
+		Pool:           spec.Pool,
+		Name:           spec.Name,
+		ServiceLevel:   spec.ServiceLevel,
+		SubnetID:       spec.SubnetID,
+		UsageThreshold: volumeQuota(source),
+		ExportRules:    NewExportPolicyBuilder(source).Rules(),
+		Tags:           spec.Tags,
+		DataProtection: &armnetapp.VolumePropertiesDataProtection{
+			Replication: &armnetapp.ReplicationObject{
+				EndpointType:           to.Ptr(armnetapp.EndpointTypeDst),
+				RemoteVolumeResourceID: to.Ptr(spec.SourceVolumeID),
+				RemoteVolumeRegion:     source.Location,
+				ReplicationSchedule:    to.Ptr(schedule),
+			},
+		},
+	}
+	if pool.Properties != nil && pool.Properties.ServiceLevel != nil {
+		volumeSpec.ServiceLevel = string(*pool.Properties.ServiceLevel)
+	}
+	if properties := source.Properties; properties != nil {
+		for _, protocolType := range properties.ProtocolTypes {
+			volumeSpec.ProtocolTypes = append(volumeSpec.ProtocolTypes, valueOf(protocolType))
+		}
+		if properties.SecurityStyle != nil {
+			volumeSpec.SecurityStyle = string(*properties.SecurityStyle)
+		}
+		volumeSpec.KerberosEnabled = properties.KerberosEnabled
+		volumeSpec.LdapEnabled = properties.LdapEnabled
+	}
+
+	spec.OnProgress(fmt.Sprintf("Creating destination volume %v...", spec.Name))
+	_, err = c.CreateANFVolume(ctx, volumeSpec)
+	return err
+}
+
+// isReplicationDestination reports whether a volume is the destination of a replication
+func isReplicationDestination(volume *armnetapp.Volume) bool {
+	replication := replicationObject(volume)
+	return replication != nil && (replication.EndpointType == nil || *replication.EndpointType == armnetapp.EndpointTypeDst)
+}
+
+// replicationRemoteVolumeID returns the volume at the other end of the replication of a volume, empty when there is none
+func replicationRemoteVolumeID(volume *armnetapp.Volume) string {
+	if replication := replicationObject(volume); replication != nil {
+		return valueOf(replication.RemoteVolumeResourceID)
+	}
+	return ""
+}
+
+// replicationObject returns the replication settings of a volume, nil when it is not part of a replication
+func replicationObject(volume *armnetapp.Volume) *armnetapp.ReplicationObject {
+	if volume.Properties == nil || volume.Properties.DataProtection == nil {
+		return nil
+	}
+	return volume.Properties.DataProtection.Replication
+}
+
+// volumeQuota returns the quota of a volume in bytes
+func volumeQuota(volume *armnetapp.Volume) int64 {
+	if volume.Properties == nil || volume.Properties.UsageThreshold == nil {
+		return 0
+	}
+	return *volume.Properties.UsageThreshold
+}
+
+// normalizeLocation turns a region display name, e.g. West Europe, into its name, westeurope
+func normalizeLocation(location string) string {
+	return strings.ReplaceAll(strings.ToLower(location), " ", "")
+}
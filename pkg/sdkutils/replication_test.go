@@ -0,0 +1,98 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+package sdkutils
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patrikcze/go-anf/pkg/emulator"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+)
+
+func TestReplicationStatusOtherSubscription(t *testing.T) {
+	ctx := context.Background()
+	server := emulator.NewServer(emulator.Options{})
+	t.Cleanup(server.Close)
+
+	clients := map[string]*Client{}
+	for _, subscriptionID := range []string{emulator.SubscriptionID, "11111111-1111-1111-1111-111111111111"} {
+		client, err := NewClient(emulator.Credential{}, subscriptionID, server.ClientOptions())
+		if err != nil {
+			t.Fatalf("NewClient() error = %v", err)
+		}
+		client.SetWaitOptions(&WaitOptions{Interval: 10 * time.Millisecond, MaxInterval: 100 * time.Millisecond, Timeout: 30 * time.Second})
+		clients[subscriptionID] = client
+	}
+	client, other := clients[emulator.SubscriptionID], clients["11111111-1111-1111-1111-111111111111"]
+
+	// The source volume is in the subscription of the client, the destination volume in the other one
+	for _, region := range []struct {
+		client   *Client
+		location string
+	}{{client, "eastus"}, {other, testLocation}} {
+		if _, err := region.client.CreateANFAccount(ctx, region.location, testResourceGroup, "account1", nil, nil); err != nil {
+			t.Fatalf("CreateANFAccount() error = %v", err)
+		}
+		if _, err := region.client.CreateANFCapacityPool(ctx, region.location, testResourceGroup, "account1", "pool1", "Premium", 4<<40, nil); err != nil {
+			t.Fatalf("CreateANFCapacityPool() error = %v", err)
+		}
+	}
+	sourceSpec := testVolumeSpec("account1", "pool1", "volume1")
+	sourceSpec.Location = "eastus"
+	source, err := client.CreateANFVolume(ctx, sourceSpec)
+	if err != nil {
+		t.Fatalf("CreateANFVolume() of the source error = %v", err)
+	}
+	destinationSpec := testVolumeSpec("account1", "pool1", "volume1")
+	destinationSpec.DataProtection = &armnetapp.VolumePropertiesDataProtection{
+		Replication: &armnetapp.ReplicationObject{
+			EndpointType:           to.Ptr(armnetapp.EndpointTypeDst),
+			RemoteVolumeResourceID: source.ID,
+			ReplicationSchedule:    to.Ptr(armnetapp.ReplicationScheduleHourly),
+		},
+	}
+	destination, err := other.CreateANFVolume(ctx, destinationSpec)
+	if err != nil {
+		t.Fatalf("CreateANFVolume() of the destination error = %v", err)
+	}
+	if err := client.AuthorizeReplication(ctx, testResourceGroup, "account1", "pool1", "volume1", *destination.ID); err != nil {
+		t.Fatalf("AuthorizeReplication() error = %v", err)
+	}
+
+	volumeIDs, err := client.ListANFReplicatedVolumes(ctx)
+	if err != nil {
+		t.Fatalf("ListANFReplicatedVolumes() error = %v", err)
+	}
+	if len(volumeIDs) != 1 || !strings.EqualFold(volumeIDs[0], *source.ID) {
+		t.Fatalf("ListANFReplicatedVolumes() = %v, want the source volume only", volumeIDs)
+	}
+
+	// The same-named volume of the subscription of the client is the source, it must not be read as the destination
+	status, err := client.GetANFVolumeReplicationStatus(ctx, testResourceGroup, "account1", "pool1", "volume1")
+	if err != nil {
+		t.Fatalf("GetANFVolumeReplicationStatus() of the source error = %v", err)
+	}
+	if status.NotMonitored == "" || status.MirrorState != "" || status.LastTransfer != nil {
+		t.Errorf("status = %+v, want a replication that is not monitored", status)
+	}
+	if !strings.EqualFold(status.DestinationVolumeID, *destination.ID) {
+		t.Errorf("destination = %v, want %v", status.DestinationVolumeID, *destination.ID)
+	}
+
+	// The client of the subscription of the destination reads the state of the replication
+	status, err = other.GetANFVolumeReplicationStatus(ctx, testResourceGroup, "account1", "pool1", "volume1")
+	if err != nil {
+		t.Fatalf("GetANFVolumeReplicationStatus() of the destination error = %v", err)
+	}
+	if status.NotMonitored != "" || status.MirrorState != string(armnetapp.MirrorStateMirrored) || status.Schedule != "hourly" {
+		t.Errorf("status = %+v, want a monitored hourly replication that is mirrored", status)
+	}
+}
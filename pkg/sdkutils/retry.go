@@ -0,0 +1,179 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// Retries of the ARM requests. Throttled (HTTP 429), timed out and failed
+// requests are retried by the azcore pipeline with exponential backoff,
+// RetryOptions tunes it and reports every retry before it happens.
+
+package sdkutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// Defaults of the azcore retry policy
+const (
+	DefaultMaxRetries    int32 = 3
+	DefaultRetryDelay          = 4 * time.Second
+	DefaultMaxRetryDelay       = time.Minute
+)
+
+// retryAfterHeaders are the headers ARM uses to tell when a request can be retried
+var retryAfterHeaders = []string{"Retry-After", "retry-after-ms", "x-ms-retry-after-ms"}
+
+// retryStatusCodes are the HTTP status codes retried by the azcore retry policy
+var retryStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryOptions controls how failed ARM requests are retried, zero values select the azcore defaults
+type RetryOptions struct {
+	// MaxRetries is the number of retries of a failed request, a negative value disables retries
+	MaxRetries int32
+	// RetryDelay is the delay before the first retry, it doubles after every retry up to MaxRetryDelay
+	RetryDelay time.Duration
+	// MaxRetryDelay caps the delay between two tries
+	MaxRetryDelay time.Duration
+	// IgnoreRetryAfter makes the retries, and the polling of long-running operations, ignore the
+	// delay requested by ARM in the Retry-After header and use RetryDelay and the default polling frequency
+	IgnoreRetryAfter bool
+	// OnRetry is called with a description of a failed request before it is retried, e.g. to log throttling
+	OnRetry func(message string)
+}
+
+// ClientOptions returns a copy of options, which may be nil, retrying the requests as set by the retry options
+func (o RetryOptions) ClientOptions(options *arm.ClientOptions) *arm.ClientOptions {
+	clientOptions := arm.ClientOptions{}
+	if options != nil {
+		clientOptions = *options
+	}
+
+	clientOptions.Retry.MaxRetries = o.MaxRetries
+	clientOptions.Retry.RetryDelay = o.RetryDelay
+	clientOptions.Retry.MaxRetryDelay = o.MaxRetryDelay
+
+	if o.OnRetry != nil || o.IgnoreRetryAfter {
+		// The slices are copied so the policies of the caller's value are not appended to
+		clientOptions.PerCallPolicies = append(append([]policy.Policy{}, clientOptions.PerCallPolicies...), tryCounterPolicy{})
+		clientOptions.PerRetryPolicies = append(append([]policy.Policy{}, clientOptions.PerRetryPolicies...), retryPolicy{options: o})
+	}
+
+	return &clientOptions
+}
+
+// tryCounter is the number of tries of a request, kept in the operation values of the request
+type tryCounter struct {
+	tries *int32
+}
+
+// tryCounterPolicy sets the try counter of every request, it runs once per request before the azcore retry policy
+type tryCounterPolicy struct{}
+
+func (tryCounterPolicy) Do(req *policy.Request) (*http.Response, error) {
+	req.SetOperationValue(tryCounter{tries: new(int32)})
+	return req.Next()
+}
+
+// retryPolicy runs on every try, after the azcore retry policy, so it sees each failed try before it is retried
+type retryPolicy struct {
+	options RetryOptions
+}
+
+func (p retryPolicy) Do(req *policy.Request) (*http.Response, error) {
+	resp, err := req.Next()
+
+	if p.options.IgnoreRetryAfter && resp != nil {
+		for _, header := range retryAfterHeaders {
+			resp.Header.Del(header)
+		}
+	}
+
+	if p.options.OnRetry != nil && isRetried(req.Raw().Context(), resp, err) {
+		try := int32(1)
+		counter := tryCounter{}
+		if req.OperationValue(&counter) {
+			try = atomic.AddInt32(counter.tries, 1)
+		}
+		if maxRetries := p.maxRetries(); try <= maxRetries {
+			p.options.OnRetry(fmt.Sprintf("%v %v: %v, retry %v of %v%v", req.Raw().Method, shortPath(req.Raw().URL.Path), retryReason(resp, err), try, maxRetries, retryDelay(resp)))
+		}
+	}
+
+	return resp, err
+}
+
+// maxRetries returns the number of retries the azcore retry policy makes
+func (p retryPolicy) maxRetries() int32 {
+	switch {
+	case p.options.MaxRetries < 0:
+		return 0
+	case p.options.MaxRetries == 0:
+		return DefaultMaxRetries
+	}
+	return p.options.MaxRetries
+}
+
+// isRetried reports whether the azcore retry policy retries a try
+func isRetried(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		// A request whose context ended is not retried
+		return ctx.Err() == nil && !errors.Is(err, context.Canceled)
+	}
+	for _, statusCode := range retryStatusCodes {
+		if resp.StatusCode == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// retryReason describes why a try failed
+func retryReason(resp *http.Response, err error) string {
+	if err != nil {
+		return fmt.Sprintf("request failed: %v", err)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return "throttled (HTTP 429)"
+	}
+	return fmt.Sprintf("HTTP %v", resp.StatusCode)
+}
+
+// retryDelay describes the delay requested by ARM in the Retry-After header
+func retryDelay(resp *http.Response) string {
+	if resp == nil {
+		return ""
+	}
+	retryAfter := resp.Header.Get("Retry-After")
+	if retryAfter == "" {
+		return ""
+	}
+	if seconds, err := strconv.Atoi(retryAfter); err == nil {
+		return fmt.Sprintf(" in %v as requested by Retry-After", time.Duration(seconds)*time.Second)
+	}
+	return fmt.Sprintf(" after %v as requested by Retry-After", retryAfter)
+}
+
+// shortPath strips the subscription and resource group from the path of an ARM request
+func shortPath(path string) string {
+	if index := strings.Index(path, "/providers/"); index >= 0 {
+		return path[index+len("/providers/"):]
+	}
+	return path
+}
@@ -13,12 +13,15 @@ import (
 	"context"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/patrikcze/go-anf/pkg/iam"
+	"github.com/patrikcze/go-anf/pkg/models"
 	"github.com/patrikcze/go-anf/pkg/uri"
 	"github.com/patrikcze/go-anf/pkg/utils"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
@@ -29,13 +32,17 @@ const (
 	nfsv3     = "NFSv3"
 	nfsv41    = "NFSv4.1"
 	cifs      = "CIFS"
+
+	maxSnapshotsPerVolume int32 = 255
 )
 
 var (
 	validProtocols = []string{nfsv3, nfsv41, cifs}
+	weekDays       = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
 )
 
-func validateANFServiceLevel(serviceLevel string) (validatedServiceLevel armnetapp.ServiceLevel, err error) {
+// ValidateANFServiceLevel converts a case insensitive service level name into its SDK value
+func ValidateANFServiceLevel(serviceLevel string) (validatedServiceLevel armnetapp.ServiceLevel, err error) {
 	var svcLevel armnetapp.ServiceLevel
 
 	switch strings.ToLower(serviceLevel) {
@@ -46,98 +53,94 @@ func validateANFServiceLevel(serviceLevel string) (validatedServiceLevel armneta
 	case "standard":
 		svcLevel = armnetapp.ServiceLevelStandard
 	default:
-		return "", fmt.Errorf("invalid service level, supported service levels are: %v", armnetapp.PossibleServiceLevelValues())
+		return "", invalidArgument("invalid service level, supported service levels are: %v", armnetapp.PossibleServiceLevelValues())
 	}
 
 	return svcLevel, nil
 }
 
-func getResourcesClient() (*armresources.Client, error) {
-	cred, subscriptionID, err := iam.GetAuthorizer()
-	if err != nil {
-		return nil, err
-	}
-
-	client, err := armresources.NewClient(subscriptionID, cred, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	return client, nil
+// Client holds the clients of all resource types used by this package,
+// they share a single credential so its token is cached between calls
+type Client struct {
+	subscriptionID   string
+	resources        *armresources.Client
+	accounts         *armnetapp.AccountsClient
+	pools            *armnetapp.PoolsClient
+	volumes          *armnetapp.VolumesClient
+	snapshots        *armnetapp.SnapshotsClient
+	snapshotPolicies *armnetapp.SnapshotPoliciesClient
+	backupPolicies   *armnetapp.BackupPoliciesClient
+	backups          *armnetapp.BackupsClient
+	vaults           *armnetapp.VaultsClient
+	journal          Journal
+	waitOptions      *WaitOptions
 }
 
-func getAccountsClient() (*armnetapp.AccountsClient, error) {
-	cred, subscriptionID, err := iam.GetAuthorizer()
-	if err != nil {
-		return nil, err
+// NewClient creates a Client for a subscription, options may be nil
+func NewClient(credential azcore.TokenCredential, subscriptionID string, options *arm.ClientOptions) (*Client, error) {
+	// The options are copied so the caller's value is not modified
+	clientOptions := arm.ClientOptions{}
+	if options != nil {
+		clientOptions = *options
 	}
-
-	client, err := armnetapp.NewAccountsClient(subscriptionID, cred, nil)
-	if err != nil {
-		return nil, err
+	if clientOptions.Telemetry.ApplicationID == "" {
+		clientOptions.Telemetry.ApplicationID = userAgent
 	}
 
-	return client, nil
-}
+	c := &Client{subscriptionID: subscriptionID}
 
-func getPoolsClient() (*armnetapp.PoolsClient, error) {
-	cred, subscriptionID, err := iam.GetAuthorizer()
-	if err != nil {
-		return nil, err
+	var err error
+	if c.resources, err = armresources.NewClient(subscriptionID, credential, &clientOptions); err != nil {
+		return nil, fmt.Errorf("cannot create resources client: %w", newError(err))
 	}
-
-	client, err := armnetapp.NewPoolsClient(subscriptionID, cred, nil)
-	if err != nil {
-		return nil, err
+	if c.accounts, err = armnetapp.NewAccountsClient(subscriptionID, credential, &clientOptions); err != nil {
+		return nil, fmt.Errorf("cannot create accounts client: %w", newError(err))
 	}
-	return client, nil
-}
-
-func getVolumesClient() (*armnetapp.VolumesClient, error) {
-	cred, subscriptionID, err := iam.GetAuthorizer()
-	if err != nil {
-		return nil, err
+	if c.pools, err = armnetapp.NewPoolsClient(subscriptionID, credential, &clientOptions); err != nil {
+		return nil, fmt.Errorf("cannot create pools client: %w", newError(err))
 	}
-
-	client, err := armnetapp.NewVolumesClient(subscriptionID, cred, nil)
-	if err != nil {
-		return nil, err
+	if c.volumes, err = armnetapp.NewVolumesClient(subscriptionID, credential, &clientOptions); err != nil {
+		return nil, fmt.Errorf("cannot create volumes client: %w", newError(err))
 	}
-	return client, nil
-}
-
-func getSnapshotsClient() (*armnetapp.SnapshotsClient, error) {
-	cred, subscriptionID, err := iam.GetAuthorizer()
-	if err != nil {
-		return nil, err
+	if c.snapshots, err = armnetapp.NewSnapshotsClient(subscriptionID, credential, &clientOptions); err != nil {
+		return nil, fmt.Errorf("cannot create snapshots client: %w", newError(err))
 	}
-
-	client, err := armnetapp.NewSnapshotsClient(subscriptionID, cred, nil)
-	if err != nil {
-		return nil, err
+	if c.snapshotPolicies, err = armnetapp.NewSnapshotPoliciesClient(subscriptionID, credential, &clientOptions); err != nil {
+		return nil, fmt.Errorf("cannot create snapshot policies client: %w", newError(err))
 	}
-	return client, nil
+	if c.backupPolicies, err = armnetapp.NewBackupPoliciesClient(subscriptionID, credential, &clientOptions); err != nil {
+		return nil, fmt.Errorf("cannot create backup policies client: %w", newError(err))
+	}
+	if c.backups, err = armnetapp.NewBackupsClient(subscriptionID, credential, &clientOptions); err != nil {
+		return nil, fmt.Errorf("cannot create backups client: %w", newError(err))
+	}
+	if c.vaults, err = armnetapp.NewVaultsClient(subscriptionID, credential, &clientOptions); err != nil {
+		return nil, fmt.Errorf("cannot create vaults client: %w", newError(err))
+	}
+
+	return c, nil
 }
 
-func getSnapshotPoliciesClient() (*armnetapp.SnapshotPoliciesClient, error) {
-	cred, subscriptionID, err := iam.GetAuthorizer()
+// NewClientFromAuthFile creates a Client from the authentication file referenced by
+// the AZURE_AUTH_LOCATION environment variable, see iam.GetAuthorizer. A non-empty
+// subscriptionID or tenantID replaces the one of the file, e.g. the one of a go-anf profile.
+func NewClientFromAuthFile(subscriptionID, tenantID string, options *arm.ClientOptions) (*Client, error) {
+	credential, subscriptionID, err := iam.GetAuthorizer(subscriptionID, tenantID)
 	if err != nil {
 		return nil, err
 	}
 
-	client, err := armnetapp.NewSnapshotPoliciesClient(subscriptionID, cred, nil)
-	if err != nil {
-		return nil, err
-	}
-	return client, nil
+	return NewClient(credential, subscriptionID, options)
+}
+
+// SubscriptionID returns the subscription the client operates on
+func (c *Client) SubscriptionID() string {
+	return c.subscriptionID
 }
 
 // GetResourceByID gets a generic resource
-func GetResourceByID(ctx context.Context, resourceID, APIVersion string) (armresources.ClientGetResponse, error) {
-	resourcesClient, err := getResourcesClient()
-	if err != nil {
-		return armresources.ClientGetResponse{}, err
-	}
+func (c *Client) GetResourceByID(ctx context.Context, resourceID, APIVersion string) (armresources.ClientGetResponse, error) {
+	resourcesClient := c.resources
 
 	parentResource := ""
 	resourceGroup := uri.GetResourceGroup(resourceID)
@@ -164,12 +167,34 @@ func GetResourceByID(ctx context.Context, resourceID, APIVersion string) (armres
 }
 
 // CreateANFAccount creates an ANF Account resource
-func CreateANFAccount(ctx context.Context, location, resourceGroupName, accountName string, activeDirectories []*armnetapp.ActiveDirectory, tags map[string]*string) (*armnetapp.Account, error) {
-	accountClient, err := getAccountsClient()
+func (c *Client) CreateANFAccount(ctx context.Context, location, resourceGroupName, accountName string, activeDirectories []*armnetapp.ActiveDirectory, tags map[string]*string) (*armnetapp.Account, error) {
+	future, err := c.beginCreateANFAccount(ctx, location, resourceGroupName, accountName, activeDirectories, tags)
 	if err != nil {
 		return nil, err
 	}
 
+	resp, err := pollUntilDone(ctx, c, future, OperationAccountCreate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the account create or update future response: %w", newError(err))
+	}
+
+	return &resp.Account, nil
+}
+
+// BeginCreateANFAccount starts the creation of an ANF Account resource and returns its operation without waiting for it
+func (c *Client) BeginCreateANFAccount(ctx context.Context, location, resourceGroupName, accountName string, activeDirectories []*armnetapp.ActiveDirectory, tags map[string]*string) (*Operation, error) {
+	future, err := c.beginCreateANFAccount(ctx, location, resourceGroupName, accountName, activeDirectories, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return startOperation(ctx, c, future, OperationAccountCreate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName))
+}
+
+// beginCreateANFAccount sends the request of CreateANFAccount
+func (c *Client) beginCreateANFAccount(ctx context.Context, location, resourceGroupName, accountName string, activeDirectories []*armnetapp.ActiveDirectory, tags map[string]*string) (*runtime.Poller[armnetapp.AccountsClientCreateOrUpdateResponse], error) {
+	accountClient := c.accounts
+
 	accountProperties := armnetapp.AccountProperties{}
 
 	if activeDirectories != nil {
@@ -190,25 +215,150 @@ func CreateANFAccount(ctx context.Context, location, resourceGroupName, accountN
 		nil,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create account: %v", err)
+		return nil, fmt.Errorf("cannot create account: %w", newError(err))
+	}
+
+	return future, nil
+}
+
+// GetANFAccount gets an ANF Account resource
+func (c *Client) GetANFAccount(ctx context.Context, resourceGroupName, accountName string) (*armnetapp.Account, error) {
+	accountClient := c.accounts
+
+	resp, err := accountClient.Get(
+		ctx,
+		resourceGroupName,
+		accountName,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get account: %w", newError(err))
 	}
 
-	resp, err := future.PollUntilDone(ctx, nil)
+	return &resp.Account, nil
+}
+
+// ListANFAccounts lists all ANF Accounts within a resource group
+func (c *Client) ListANFAccounts(ctx context.Context, resourceGroupName string) ([]*armnetapp.Account, error) {
+	accountClient := c.accounts
+
+	accounts := []*armnetapp.Account{}
+
+	pager := accountClient.NewListPager(resourceGroupName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list accounts: %w", newError(err))
+		}
+		accounts = append(accounts, page.Value...)
+	}
+
+	return accounts, nil
+}
+
+// ListANFAccountsBySubscription lists all ANF Accounts within the subscription
+func (c *Client) ListANFAccountsBySubscription(ctx context.Context) ([]*armnetapp.Account, error) {
+	accountClient := c.accounts
+
+	accounts := []*armnetapp.Account{}
+
+	pager := accountClient.NewListBySubscriptionPager(nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list accounts: %w", newError(err))
+		}
+		accounts = append(accounts, page.Value...)
+	}
+
+	return accounts, nil
+}
+
+// UpdateANFAccount updates an ANF Account resource, only the tags and
+// active directory connections present in the patch are changed
+func (c *Client) UpdateANFAccount(ctx context.Context, location, resourceGroupName, accountName string, activeDirectories []*armnetapp.ActiveDirectory, tags map[string]*string) (*armnetapp.Account, error) {
+	future, err := c.beginUpdateANFAccount(ctx, location, resourceGroupName, accountName, activeDirectories, tags)
 	if err != nil {
-		return nil, fmt.Errorf("cannot get the account create or update future response: %v", err)
+		return nil, err
+	}
+
+	resp, err := pollUntilDone(ctx, c, future, OperationAccountUpdate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the account update future response: %w", newError(err))
 	}
 
 	return &resp.Account, nil
 }
 
+// BeginUpdateANFAccount starts the update of an ANF Account resource and returns its operation without waiting for it
+func (c *Client) BeginUpdateANFAccount(ctx context.Context, location, resourceGroupName, accountName string, activeDirectories []*armnetapp.ActiveDirectory, tags map[string]*string) (*Operation, error) {
+	future, err := c.beginUpdateANFAccount(ctx, location, resourceGroupName, accountName, activeDirectories, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return startOperation(ctx, c, future, OperationAccountUpdate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName))
+}
+
+// beginUpdateANFAccount sends the request of UpdateANFAccount
+func (c *Client) beginUpdateANFAccount(ctx context.Context, location, resourceGroupName, accountName string, activeDirectories []*armnetapp.ActiveDirectory, tags map[string]*string) (*runtime.Poller[armnetapp.AccountsClientUpdateResponse], error) {
+	accountClient := c.accounts
+
+	accountPatch := armnetapp.AccountPatch{
+		Location: to.Ptr(location),
+		Tags:     tags,
+	}
+
+	if activeDirectories != nil {
+		accountPatch.Properties = &armnetapp.AccountProperties{
+			ActiveDirectories: activeDirectories,
+		}
+	}
+
+	future, err := accountClient.BeginUpdate(
+		ctx,
+		resourceGroupName,
+		accountName,
+		accountPatch,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot update account: %w", newError(err))
+	}
+
+	return future, nil
+}
+
 // CreateANFCapacityPool creates an ANF Capacity Pool within ANF Account
-func CreateANFCapacityPool(ctx context.Context, location, resourceGroupName, accountName, poolName, serviceLevel string, sizeBytes int64, tags map[string]*string) (*armnetapp.CapacityPool, error) {
-	poolClient, err := getPoolsClient()
+func (c *Client) CreateANFCapacityPool(ctx context.Context, location, resourceGroupName, accountName, poolName, serviceLevel string, sizeBytes int64, tags map[string]*string) (*armnetapp.CapacityPool, error) {
+	future, err := c.beginCreateANFCapacityPool(ctx, location, resourceGroupName, accountName, poolName, serviceLevel, sizeBytes, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := pollUntilDone(ctx, c, future, OperationPoolCreate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the pool create or update future response: %w", newError(err))
+	}
+
+	return &resp.CapacityPool, nil
+}
+
+// BeginCreateANFCapacityPool starts the creation of an ANF Capacity Pool and returns its operation without waiting for it
+func (c *Client) BeginCreateANFCapacityPool(ctx context.Context, location, resourceGroupName, accountName, poolName, serviceLevel string, sizeBytes int64, tags map[string]*string) (*Operation, error) {
+	future, err := c.beginCreateANFCapacityPool(ctx, location, resourceGroupName, accountName, poolName, serviceLevel, sizeBytes, tags)
 	if err != nil {
 		return nil, err
 	}
 
-	svcLevel, err := validateANFServiceLevel(serviceLevel)
+	return startOperation(ctx, c, future, OperationPoolCreate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName))
+}
+
+// beginCreateANFCapacityPool sends the request of CreateANFCapacityPool
+func (c *Client) beginCreateANFCapacityPool(ctx context.Context, location, resourceGroupName, accountName, poolName, serviceLevel string, sizeBytes int64, tags map[string]*string) (*runtime.Poller[armnetapp.PoolsClientCreateOrUpdateResponse], error) {
+	poolClient := c.pools
+
+	svcLevel, err := ValidateANFServiceLevel(serviceLevel)
 	if err != nil {
 		return nil, err
 	}
@@ -230,108 +380,239 @@ func CreateANFCapacityPool(ctx context.Context, location, resourceGroupName, acc
 	)
 
 	if err != nil {
-		return nil, fmt.Errorf("cannot create pool: %v", err)
+		return nil, fmt.Errorf("cannot create pool: %w", newError(err))
 	}
 
-	resp, err := future.PollUntilDone(ctx, nil)
+	return future, nil
+}
+
+// GetANFCapacityPool gets an ANF Capacity Pool
+func (c *Client) GetANFCapacityPool(ctx context.Context, resourceGroupName, accountName, poolName string) (*armnetapp.CapacityPool, error) {
+	poolClient := c.pools
+
+	resp, err := poolClient.Get(
+		ctx,
+		resourceGroupName,
+		accountName,
+		poolName,
+		nil,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("cannot get the pool create or update future response: %v", err)
+		return nil, fmt.Errorf("cannot get pool: %w", newError(err))
 	}
 
 	return &resp.CapacityPool, nil
 }
 
-// CreateANFVolume creates an ANF volume within a Capacity Pool
-func CreateANFVolume(ctx context.Context, location, resourceGroupName, accountName, poolName, volumeName, serviceLevel, subnetID, snapshotID string, protocolTypes []string, volumeUsageQuota int64, unixReadOnly, unixReadWrite bool, tags map[string]*string, dataProtectionObject armnetapp.VolumePropertiesDataProtection) (*armnetapp.Volume, error) {
-	if len(protocolTypes) > 2 {
-		return nil, fmt.Errorf("maximum of two protocol types are supported")
+// ListANFCapacityPools lists all Capacity Pools within an ANF Account
+func (c *Client) ListANFCapacityPools(ctx context.Context, resourceGroupName, accountName string) ([]*armnetapp.CapacityPool, error) {
+	poolClient := c.pools
+
+	pools := []*armnetapp.CapacityPool{}
+
+	pager := poolClient.NewListPager(resourceGroupName, accountName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list pools: %w", newError(err))
+		}
+		pools = append(pools, page.Value...)
 	}
 
-	if len(protocolTypes) > 1 && utils.Contains(protocolTypes, "NFSv4.1") {
-		return nil, fmt.Errorf("only cifs/nfsv3 protocol types are supported as dual protocol")
+	return pools, nil
+}
+
+// UpdateANFCapacityPool patches an ANF Capacity Pool. The service level of a pool cannot be
+// patched, volumes change service level by moving to a pool of the target service level.
+func (c *Client) UpdateANFCapacityPool(ctx context.Context, location, resourceGroupName, accountName, poolName string, poolPropertiesPatch armnetapp.PoolPatchProperties, tags map[string]*string) (*armnetapp.CapacityPool, error) {
+	future, err := c.beginUpdateANFCapacityPool(ctx, location, resourceGroupName, accountName, poolName, poolPropertiesPatch, tags)
+	if err != nil {
+		return nil, err
 	}
 
-	_, found := utils.FindInSlice(validProtocols, protocolTypes[0])
-	if !found {
-		return nil, fmt.Errorf("invalid protocol type, valid protocol types are: %v", validProtocols)
+	resp, err := pollUntilDone(ctx, c, future, OperationPoolUpdate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the pool update future response: %w", newError(err))
 	}
 
-	svcLevel, err := validateANFServiceLevel(serviceLevel)
+	return &resp.CapacityPool, nil
+}
+
+// BeginUpdateANFCapacityPool starts the patch of an ANF Capacity Pool and returns its operation without waiting for it
+func (c *Client) BeginUpdateANFCapacityPool(ctx context.Context, location, resourceGroupName, accountName, poolName string, poolPropertiesPatch armnetapp.PoolPatchProperties, tags map[string]*string) (*Operation, error) {
+	future, err := c.beginUpdateANFCapacityPool(ctx, location, resourceGroupName, accountName, poolName, poolPropertiesPatch, tags)
 	if err != nil {
 		return nil, err
 	}
 
-	volumeClient, err := getVolumesClient()
+	return startOperation(ctx, c, future, OperationPoolUpdate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName))
+}
+
+// beginUpdateANFCapacityPool sends the request of UpdateANFCapacityPool
+func (c *Client) beginUpdateANFCapacityPool(ctx context.Context, location, resourceGroupName, accountName, poolName string, poolPropertiesPatch armnetapp.PoolPatchProperties, tags map[string]*string) (*runtime.Poller[armnetapp.PoolsClientUpdateResponse], error) {
+	poolClient := c.pools
+
+	future, err := poolClient.BeginUpdate(
+		ctx,
+		resourceGroupName,
+		accountName,
+		poolName,
+		armnetapp.CapacityPoolPatch{
+			Location:   to.Ptr(location),
+			Tags:       tags,
+			Properties: &poolPropertiesPatch,
+		},
+		nil,
+	)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("cannot update pool: %w", newError(err))
 	}
 
-	exportPolicy := armnetapp.VolumePropertiesExportPolicy{}
-
-	if _, found := utils.FindInSlice(protocolTypes, cifs); !found {
-		exportPolicy = armnetapp.VolumePropertiesExportPolicy{
-			Rules: []*armnetapp.ExportPolicyRule{
-				{
-					AllowedClients: to.Ptr("0.0.0.0/0"),
-					Cifs:           to.Ptr(map[bool]bool{true: true, false: false}[protocolTypes[0] == cifs]),
-					Nfsv3:          to.Ptr(map[bool]bool{true: true, false: false}[protocolTypes[0] == nfsv3]),
-					Nfsv41:         to.Ptr(map[bool]bool{true: true, false: false}[protocolTypes[0] == nfsv41]),
-					RuleIndex:      to.Ptr[int32](1),
-					UnixReadOnly:   to.Ptr(unixReadOnly),
-					UnixReadWrite:  to.Ptr(unixReadWrite),
-				},
-			},
+	return future, nil
+}
+
+// ValidateANFProtocolTypes checks a volume protocol type combination is supported
+func ValidateANFProtocolTypes(protocolTypes []string) error {
+	if len(protocolTypes) == 0 {
+		return invalidArgument("at least one protocol type is required, valid protocol types are: %v", validProtocols)
+	}
+
+	if len(protocolTypes) > 2 {
+		return invalidArgument("maximum of two protocol types are supported")
+	}
+
+	for _, protocolType := range protocolTypes {
+		if _, found := utils.FindInSlice(validProtocols, protocolType); !found {
+			return invalidArgument("invalid protocol type %v, valid protocol types are: %v", protocolType, validProtocols)
 		}
 	}
 
-	protocolTypeSlice := make([]*string, len(protocolTypes))
-	for i, protocolType := range protocolTypes {
-		protocolTypeSlice[i] = &protocolType
+	// Dual protocol volumes serve the same files over SMB and one NFS version
+	if len(protocolTypes) == 2 && (protocolTypes[0] == protocolTypes[1] || !utils.Contains(protocolTypes, cifs)) {
+		return invalidArgument("invalid protocol types %v, dual protocol volumes combine CIFS with either NFSv3 or NFSv4.1", protocolTypes)
+	}
+
+	return nil
+}
+
+// CreateANFVolume creates an ANF volume within a Capacity Pool
+func (c *Client) CreateANFVolume(ctx context.Context, spec VolumeSpec) (*armnetapp.Volume, error) {
+	future, err := c.beginCreateANFVolume(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := pollUntilDone(ctx, c, future, OperationVolumeCreate, c.anfResourceID(spec.ResourceGroup, "netAppAccounts", spec.Account, "capacityPools", spec.Pool, "volumes", spec.Name))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the volume create or update future response: %w", newError(err))
+	}
+
+	return &resp.Volume, nil
+}
+
+// BeginCreateANFVolume starts the creation of an ANF volume and returns its operation without waiting for it
+func (c *Client) BeginCreateANFVolume(ctx context.Context, spec VolumeSpec) (*Operation, error) {
+	future, err := c.beginCreateANFVolume(ctx, spec)
+	if err != nil {
+		return nil, err
 	}
 
-	volumeProperties := armnetapp.VolumeProperties{
-		SnapshotID:     map[bool]*string{true: to.Ptr(snapshotID), false: nil}[snapshotID != ""],
-		ExportPolicy:   map[bool]*armnetapp.VolumePropertiesExportPolicy{true: &exportPolicy, false: nil}[protocolTypes[0] != cifs],
-		ProtocolTypes:  protocolTypeSlice,
-		ServiceLevel:   &svcLevel,
-		SubnetID:       to.Ptr(subnetID),
-		UsageThreshold: to.Ptr[int64](volumeUsageQuota),
-		CreationToken:  to.Ptr(volumeName),
-		DataProtection: &dataProtectionObject,
+	return startOperation(ctx, c, future, OperationVolumeCreate, c.anfResourceID(spec.ResourceGroup, "netAppAccounts", spec.Account, "capacityPools", spec.Pool, "volumes", spec.Name))
+}
+
+// beginCreateANFVolume validates the spec and sends the request of CreateANFVolume
+func (c *Client) beginCreateANFVolume(ctx context.Context, spec VolumeSpec) (*runtime.Poller[armnetapp.VolumesClientCreateOrUpdateResponse], error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	if err := c.validateANFVolumeActiveDirectory(ctx, spec); err != nil {
+		return nil, err
 	}
 
+	volumeClient := c.volumes
+
 	future, err := volumeClient.BeginCreateOrUpdate(
+		ctx,
+		spec.ResourceGroup,
+		spec.Account,
+		spec.Pool,
+		spec.Name,
+		spec.Volume(),
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create volume: %w", newError(err))
+	}
+
+	return future, nil
+}
+
+// GetANFVolume gets an ANF volume
+func (c *Client) GetANFVolume(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string) (*armnetapp.Volume, error) {
+	volumeClient := c.volumes
+
+	resp, err := volumeClient.Get(
 		ctx,
 		resourceGroupName,
 		accountName,
 		poolName,
 		volumeName,
-		armnetapp.Volume{
-			Location:   to.Ptr(location),
-			Tags:       tags,
-			Properties: &volumeProperties,
-		},
 		nil,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create volume: %v", err)
+		return nil, fmt.Errorf("cannot get volume: %w", newError(err))
 	}
 
-	resp, err := future.PollUntilDone(ctx, nil)
+	return &resp.Volume, nil
+}
+
+// ListANFVolumes lists all volumes within a Capacity Pool
+func (c *Client) ListANFVolumes(ctx context.Context, resourceGroupName, accountName, poolName string) ([]*armnetapp.Volume, error) {
+	volumeClient := c.volumes
+
+	volumes := []*armnetapp.Volume{}
+
+	pager := volumeClient.NewListPager(resourceGroupName, accountName, poolName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list volumes: %w", newError(err))
+		}
+		volumes = append(volumes, page.Value...)
+	}
+
+	return volumes, nil
+}
+
+// UpdateANFVolume update an ANF volume
+func (c *Client) UpdateANFVolume(ctx context.Context, location, resourceGroupName, accountName, poolName, volumeName string, volumePropertiesPatch armnetapp.VolumePatchProperties, tags map[string]*string) (*armnetapp.Volume, error) {
+	future, err := c.beginUpdateANFVolume(ctx, location, resourceGroupName, accountName, poolName, volumeName, volumePropertiesPatch, tags)
 	if err != nil {
-		return nil, fmt.Errorf("cannot get the volume create or update future response: %v", err)
+		return nil, err
+	}
+
+	resp, err := pollUntilDone(ctx, c, future, OperationVolumeUpdate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName))
+	if err != nil {
+		return nil, err
 	}
 
 	return &resp.Volume, nil
 }
 
-// UpdateANFVolume update an ANF volume
-func UpdateANFVolume(ctx context.Context, location, resourceGroupName, accountName, poolName, volumeName string, volumePropertiesPatch armnetapp.VolumePatchProperties, tags map[string]*string) (*armnetapp.Volume, error) {
-	volumeClient, err := getVolumesClient()
+// BeginUpdateANFVolume starts the update of an ANF volume and returns its operation without waiting for it
+func (c *Client) BeginUpdateANFVolume(ctx context.Context, location, resourceGroupName, accountName, poolName, volumeName string, volumePropertiesPatch armnetapp.VolumePatchProperties, tags map[string]*string) (*Operation, error) {
+	future, err := c.beginUpdateANFVolume(ctx, location, resourceGroupName, accountName, poolName, volumeName, volumePropertiesPatch, tags)
 	if err != nil {
 		return nil, err
 	}
 
+	return startOperation(ctx, c, future, OperationVolumeUpdate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName))
+}
+
+// beginUpdateANFVolume sends the request of UpdateANFVolume
+func (c *Client) beginUpdateANFVolume(ctx context.Context, location, resourceGroupName, accountName, poolName, volumeName string, volumePropertiesPatch armnetapp.VolumePatchProperties, tags map[string]*string) (*runtime.Poller[armnetapp.VolumesClientUpdateResponse], error) {
+	volumeClient := c.volumes
+
 	future, err := volumeClient.BeginUpdate(
 		ctx,
 		resourceGroupName,
@@ -346,24 +627,41 @@ func UpdateANFVolume(ctx context.Context, location, resourceGroupName, accountNa
 		nil,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("cannot update volume: %v", err)
+		return nil, fmt.Errorf("cannot update volume: %w", newError(err))
 	}
 
-	resp, err := future.PollUntilDone(ctx, nil)
+	return future, nil
+}
+
+// AuthorizeReplication - authorizes volume replication
+func (c *Client) AuthorizeReplication(ctx context.Context, resourceGroupName, accountName, poolName, volumeName, remoteVolumeResourceID string) error {
+	future, err := c.beginAuthorizeReplication(ctx, resourceGroupName, accountName, poolName, volumeName, remoteVolumeResourceID)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return &resp.Volume, nil
+	_, err = pollUntilDone(ctx, c, future, OperationReplicationAuthorize, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName))
+	if err != nil {
+		return fmt.Errorf("cannot get authorize volume replication future response: %w", newError(err))
+	}
+
+	return nil
 }
 
-// AuthorizeReplication - authorizes volume replication
-func AuthorizeReplication(ctx context.Context, resourceGroupName, accountName, poolName, volumeName, remoteVolumeResourceID string) error {
-	volumeClient, err := getVolumesClient()
+// BeginAuthorizeReplication starts the authorization of a volume replication and returns its operation without waiting for it
+func (c *Client) BeginAuthorizeReplication(ctx context.Context, resourceGroupName, accountName, poolName, volumeName, remoteVolumeResourceID string) (*Operation, error) {
+	future, err := c.beginAuthorizeReplication(ctx, resourceGroupName, accountName, poolName, volumeName, remoteVolumeResourceID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	return startOperation(ctx, c, future, OperationReplicationAuthorize, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName))
+}
+
+// beginAuthorizeReplication sends the request of AuthorizeReplication
+func (c *Client) beginAuthorizeReplication(ctx context.Context, resourceGroupName, accountName, poolName, volumeName, remoteVolumeResourceID string) (*runtime.Poller[armnetapp.VolumesClientAuthorizeReplicationResponse], error) {
+	volumeClient := c.volumes
+
 	future, err := volumeClient.BeginAuthorizeReplication(
 		ctx,
 		resourceGroupName,
@@ -376,24 +674,42 @@ func AuthorizeReplication(ctx context.Context, resourceGroupName, accountName, p
 		nil,
 	)
 	if err != nil {
-		return fmt.Errorf("cannot authorize volume replication: %v", err)
+		return nil, fmt.Errorf("cannot authorize volume replication: %w", newError(err))
 	}
 
-	_, err = future.PollUntilDone(ctx, nil)
+	return future, nil
+}
+
+// DeleteANFVolumeReplication deletes the replication of a volume and waits until the volume is not part of a replication anymore
+func (c *Client) DeleteANFVolumeReplication(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string) error {
+	future, err := c.beginDeleteANFVolumeReplication(ctx, resourceGroupName, accountName, poolName, volumeName)
 	if err != nil {
-		return fmt.Errorf("cannot get authorize volume replication future response: %v", err)
+		return err
 	}
 
-	return nil
+	volumeID := c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName)
+	_, err = pollUntilDone(ctx, c, future, OperationReplicationDelete, volumeID)
+	if err != nil {
+		return fmt.Errorf("cannot get delete volume replication future response: %w", newError(err))
+	}
+
+	return c.WaitForNoANFReplication(ctx, volumeID, nil)
 }
 
-// DeleteANFVolumeReplication - authorizes volume replication
-func DeleteANFVolumeReplication(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string) error {
-	volumeClient, err := getVolumesClient()
+// BeginDeleteANFVolumeReplication starts the deletion of a volume replication and returns its operation without waiting for it
+func (c *Client) BeginDeleteANFVolumeReplication(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string) (*Operation, error) {
+	future, err := c.beginDeleteANFVolumeReplication(ctx, resourceGroupName, accountName, poolName, volumeName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	return startOperation(ctx, c, future, OperationReplicationDelete, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName))
+}
+
+// beginDeleteANFVolumeReplication sends the request of DeleteANFVolumeReplication
+func (c *Client) beginDeleteANFVolumeReplication(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string) (*runtime.Poller[armnetapp.VolumesClientDeleteReplicationResponse], error) {
+	volumeClient := c.volumes
+
 	future, err := volumeClient.BeginDeleteReplication(
 		ctx,
 		resourceGroupName,
@@ -403,24 +719,41 @@ func DeleteANFVolumeReplication(ctx context.Context, resourceGroupName, accountN
 		nil,
 	)
 	if err != nil {
-		return fmt.Errorf("cannot delete volume replication: %v", err)
+		return nil, fmt.Errorf("cannot delete volume replication: %w", newError(err))
 	}
 
-	_, err = future.PollUntilDone(ctx, nil)
+	return future, nil
+}
+
+// CreateANFSnapshot creates a Snapshot from an ANF volume
+func (c *Client) CreateANFSnapshot(ctx context.Context, location, resourceGroupName, accountName, poolName, volumeName, snapshotName string, tags map[string]*string) (*armnetapp.Snapshot, error) {
+	future, err := c.beginCreateANFSnapshot(ctx, location, resourceGroupName, accountName, poolName, volumeName, snapshotName, tags)
 	if err != nil {
-		return fmt.Errorf("cannot get delete volume replication future response: %v", err)
+		return nil, err
 	}
 
-	return nil
+	resp, err := pollUntilDone(ctx, c, future, OperationSnapshotCreate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName, "snapshots", snapshotName))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the snapshot create or update future response: %w", newError(err))
+	}
+
+	return &resp.Snapshot, nil
 }
 
-// CreateANFSnapshot creates a Snapshot from an ANF volume
-func CreateANFSnapshot(ctx context.Context, location, resourceGroupName, accountName, poolName, volumeName, snapshotName string, tags map[string]*string) (*armnetapp.Snapshot, error) {
-	snapshotClient, err := getSnapshotsClient()
+// BeginCreateANFSnapshot starts the creation of a Snapshot of an ANF volume and returns its operation without waiting for it
+func (c *Client) BeginCreateANFSnapshot(ctx context.Context, location, resourceGroupName, accountName, poolName, volumeName, snapshotName string, tags map[string]*string) (*Operation, error) {
+	future, err := c.beginCreateANFSnapshot(ctx, location, resourceGroupName, accountName, poolName, volumeName, snapshotName, tags)
 	if err != nil {
 		return nil, err
 	}
 
+	return startOperation(ctx, c, future, OperationSnapshotCreate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName, "snapshots", snapshotName))
+}
+
+// beginCreateANFSnapshot sends the request of CreateANFSnapshot
+func (c *Client) beginCreateANFSnapshot(ctx context.Context, location, resourceGroupName, accountName, poolName, volumeName, snapshotName string, tags map[string]*string) (*runtime.Poller[armnetapp.SnapshotsClientCreateResponse], error) {
+	snapshotClient := c.snapshots
+
 	future, err := snapshotClient.BeginCreate(
 		ctx,
 		resourceGroupName,
@@ -434,52 +767,147 @@ func CreateANFSnapshot(ctx context.Context, location, resourceGroupName, account
 		nil,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create snapshot: %v", err)
+		return nil, fmt.Errorf("cannot create snapshot: %w", newError(err))
 	}
 
-	resp, err := future.PollUntilDone(ctx, nil)
+	return future, nil
+}
+
+// GetANFSnapshot gets a Snapshot of an ANF volume
+func (c *Client) GetANFSnapshot(ctx context.Context, resourceGroupName, accountName, poolName, volumeName, snapshotName string) (*armnetapp.Snapshot, error) {
+	snapshotClient := c.snapshots
+
+	resp, err := snapshotClient.Get(
+		ctx,
+		resourceGroupName,
+		accountName,
+		poolName,
+		volumeName,
+		snapshotName,
+		nil,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("cannot get the snapshot create or update future response: %v", err)
+		return nil, fmt.Errorf("cannot get snapshot: %w", newError(err))
 	}
 
 	return &resp.Snapshot, nil
 }
 
-// DeleteANFSnapshot deletes a Snapshot from an ANF volume
-func DeleteANFSnapshot(ctx context.Context, resourceGroupName, accountName, poolName, volumeName, snapshotName string) error {
-	snapshotClient, err := getSnapshotsClient()
+// ListANFSnapshots lists all Snapshots of an ANF volume
+func (c *Client) ListANFSnapshots(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string) ([]*armnetapp.Snapshot, error) {
+	snapshotClient := c.snapshots
+
+	snapshots := []*armnetapp.Snapshot{}
+
+	pager := snapshotClient.NewListPager(resourceGroupName, accountName, poolName, volumeName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list snapshots: %w", newError(err))
+		}
+		snapshots = append(snapshots, page.Value...)
+	}
+
+	return snapshots, nil
+}
+
+// RevertANFVolume reverts an ANF volume to one of its Snapshots, every Snapshot
+// taken after it is deleted by the service
+func (c *Client) RevertANFVolume(ctx context.Context, resourceGroupName, accountName, poolName, volumeName, snapshotID string) error {
+	future, err := c.beginRevertANFVolume(ctx, resourceGroupName, accountName, poolName, volumeName, snapshotID)
 	if err != nil {
 		return err
 	}
 
-	future, err := snapshotClient.BeginDelete(
+	_, err = pollUntilDone(ctx, c, future, OperationVolumeRevert, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName))
+	if err != nil {
+		return fmt.Errorf("cannot get the volume revert future response: %w", newError(err))
+	}
+
+	return nil
+}
+
+// BeginRevertANFVolume starts the revert of an ANF volume to one of its Snapshots and returns its operation without waiting for it
+func (c *Client) BeginRevertANFVolume(ctx context.Context, resourceGroupName, accountName, poolName, volumeName, snapshotID string) (*Operation, error) {
+	future, err := c.beginRevertANFVolume(ctx, resourceGroupName, accountName, poolName, volumeName, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	return startOperation(ctx, c, future, OperationVolumeRevert, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName))
+}
+
+// beginRevertANFVolume sends the request of RevertANFVolume
+func (c *Client) beginRevertANFVolume(ctx context.Context, resourceGroupName, accountName, poolName, volumeName, snapshotID string) (*runtime.Poller[armnetapp.VolumesClientRevertResponse], error) {
+	volumeClient := c.volumes
+
+	future, err := volumeClient.BeginRevert(
 		ctx,
 		resourceGroupName,
 		accountName,
 		poolName,
 		volumeName,
-		snapshotName,
+		armnetapp.VolumeRevert{
+			SnapshotID: to.Ptr(snapshotID),
+		},
 		nil,
 	)
 	if err != nil {
-		return fmt.Errorf("cannot delete snapshot: %v", err)
+		return nil, fmt.Errorf("cannot revert volume: %w", newError(err))
+	}
+
+	return future, nil
+}
+
+// DeleteANFSnapshot deletes a Snapshot from an ANF volume
+func (c *Client) DeleteANFSnapshot(ctx context.Context, resourceGroupName, accountName, poolName, volumeName, snapshotName string) error {
+	future, err := c.beginDeleteANFSnapshot(ctx, resourceGroupName, accountName, poolName, volumeName, snapshotName)
+	if err != nil {
+		return err
 	}
 
-	_, err = future.PollUntilDone(ctx, nil)
+	_, err = pollUntilDone(ctx, c, future, OperationSnapshotDelete, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName, "snapshots", snapshotName))
 	if err != nil {
-		return fmt.Errorf("cannot get the snapshot delete future response: %v", err)
+		return fmt.Errorf("cannot get the snapshot delete future response: %w", newError(err))
 	}
 
 	return nil
 }
 
-// CreateANFSnapshotPolicy creates a Snapshot Policy to be used on volumes
-func CreateANFSnapshotPolicy(ctx context.Context, resourceGroupName, accountName, policyName string, policy armnetapp.SnapshotPolicy) (*armnetapp.SnapshotPolicy, error) {
-	snapshotPolicyClient, err := getSnapshotPoliciesClient()
+// BeginDeleteANFSnapshot starts the deletion of a Snapshot and returns its operation without waiting for it
+func (c *Client) BeginDeleteANFSnapshot(ctx context.Context, resourceGroupName, accountName, poolName, volumeName, snapshotName string) (*Operation, error) {
+	future, err := c.beginDeleteANFSnapshot(ctx, resourceGroupName, accountName, poolName, volumeName, snapshotName)
 	if err != nil {
 		return nil, err
 	}
 
+	return startOperation(ctx, c, future, OperationSnapshotDelete, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName, "snapshots", snapshotName))
+}
+
+// beginDeleteANFSnapshot sends the request of DeleteANFSnapshot
+func (c *Client) beginDeleteANFSnapshot(ctx context.Context, resourceGroupName, accountName, poolName, volumeName, snapshotName string) (*runtime.Poller[armnetapp.SnapshotsClientDeleteResponse], error) {
+	snapshotClient := c.snapshots
+
+	future, err := snapshotClient.BeginDelete(
+		ctx,
+		resourceGroupName,
+		accountName,
+		poolName,
+		volumeName,
+		snapshotName,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot delete snapshot: %w", newError(err))
+	}
+
+	return future, nil
+}
+
+// CreateANFSnapshotPolicy creates a Snapshot Policy to be used on volumes
+func (c *Client) CreateANFSnapshotPolicy(ctx context.Context, resourceGroupName, accountName, policyName string, policy armnetapp.SnapshotPolicy) (*armnetapp.SnapshotPolicy, error) {
+	snapshotPolicyClient := c.snapshotPolicies
+
 	snapshotPolicy, err := snapshotPolicyClient.Create(
 		ctx,
 		resourceGroupName,
@@ -489,19 +917,41 @@ func CreateANFSnapshotPolicy(ctx context.Context, resourceGroupName, accountName
 		nil,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create snapshot policy: %v", err)
+		return nil, fmt.Errorf("cannot create snapshot policy: %w", newError(err))
 	}
 
 	return &snapshotPolicy.SnapshotPolicy, nil
 }
 
 // UpdateANFSnapshotPolicy update an ANF volume
-func UpdateANFSnapshotPolicy(ctx context.Context, resourceGroupName, accountName, policyName string, snapshotPolicyPatch armnetapp.SnapshotPolicyPatch) (*armnetapp.SnapshotPolicy, error) {
-	snapshotPolicyClient, err := getSnapshotPoliciesClient()
+func (c *Client) UpdateANFSnapshotPolicy(ctx context.Context, resourceGroupName, accountName, policyName string, snapshotPolicyPatch armnetapp.SnapshotPolicyPatch) (*armnetapp.SnapshotPolicy, error) {
+	future, err := c.beginUpdateANFSnapshotPolicy(ctx, resourceGroupName, accountName, policyName, snapshotPolicyPatch)
 	if err != nil {
 		return nil, err
 	}
 
+	resp, err := pollUntilDone(ctx, c, future, OperationSnapshotPolicyUpdate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "snapshotPolicies", policyName))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the snapshot policy update future response: %w", newError(err))
+	}
+
+	return &resp.SnapshotPolicy, nil
+}
+
+// BeginUpdateANFSnapshotPolicy starts the update of a Snapshot Policy and returns its operation without waiting for it
+func (c *Client) BeginUpdateANFSnapshotPolicy(ctx context.Context, resourceGroupName, accountName, policyName string, snapshotPolicyPatch armnetapp.SnapshotPolicyPatch) (*Operation, error) {
+	future, err := c.beginUpdateANFSnapshotPolicy(ctx, resourceGroupName, accountName, policyName, snapshotPolicyPatch)
+	if err != nil {
+		return nil, err
+	}
+
+	return startOperation(ctx, c, future, OperationSnapshotPolicyUpdate, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "snapshotPolicies", policyName))
+}
+
+// beginUpdateANFSnapshotPolicy sends the request of UpdateANFSnapshotPolicy
+func (c *Client) beginUpdateANFSnapshotPolicy(ctx context.Context, resourceGroupName, accountName, policyName string, snapshotPolicyPatch armnetapp.SnapshotPolicyPatch) (*runtime.Poller[armnetapp.SnapshotPoliciesClientUpdateResponse], error) {
+	snapshotPolicyClient := c.snapshotPolicies
+
 	future, err := snapshotPolicyClient.BeginUpdate(
 		ctx,
 		resourceGroupName,
@@ -511,21 +961,211 @@ func UpdateANFSnapshotPolicy(ctx context.Context, resourceGroupName, accountName
 		nil,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("cannot update snapshot policy: %v", err)
+		return nil, fmt.Errorf("cannot update snapshot policy: %w", newError(err))
 	}
 
-	resp, err := future.PollUntilDone(ctx, nil)
+	return future, nil
+}
+
+// GetANFSnapshotPolicy gets a Snapshot Policy
+func (c *Client) GetANFSnapshotPolicy(ctx context.Context, resourceGroupName, accountName, policyName string) (*armnetapp.SnapshotPolicy, error) {
+	snapshotPolicyClient := c.snapshotPolicies
+
+	resp, err := snapshotPolicyClient.Get(
+		ctx,
+		resourceGroupName,
+		accountName,
+		policyName,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get snapshot policy: %w", newError(err))
+	}
 
 	return &resp.SnapshotPolicy, nil
 }
 
+// ListANFSnapshotPolicies lists all Snapshot Policies within an ANF Account
+func (c *Client) ListANFSnapshotPolicies(ctx context.Context, resourceGroupName, accountName string) ([]*armnetapp.SnapshotPolicy, error) {
+	snapshotPolicyClient := c.snapshotPolicies
+
+	policies := []*armnetapp.SnapshotPolicy{}
+
+	pager := snapshotPolicyClient.NewListPager(resourceGroupName, accountName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list snapshot policies: %w", newError(err))
+		}
+		policies = append(policies, page.Value...)
+	}
+
+	return policies, nil
+}
+
+// NewANFSnapshotPolicyProperties validates the schedules and builds the properties of a Snapshot Policy,
+// all validation problems are reported at once
+func NewANFSnapshotPolicyProperties(schedules models.SnapshotPolicySchedules, enabled bool) (*armnetapp.SnapshotPolicyProperties, error) {
+	problems := []string{}
+
+	checkRange := func(name string, value, min, max int32) {
+		if value < min || value > max {
+			problems = append(problems, fmt.Sprintf("%v must be between %v and %v, got %v", name, min, max, value))
+		}
+	}
+
+	for _, keep := range []struct {
+		name  string
+		value int32
+	}{
+		{"hourly snapshots to keep", schedules.HourlySnapshotsToKeep},
+		{"daily snapshots to keep", schedules.DailySnapshotsToKeep},
+		{"weekly snapshots to keep", schedules.WeeklySnapshotsToKeep},
+		{"monthly snapshots to keep", schedules.MonthlySnapshotsToKeep},
+	} {
+		checkRange(keep.name, keep.value, 0, maxSnapshotsPerVolume)
+	}
+
+	total := schedules.HourlySnapshotsToKeep + schedules.DailySnapshotsToKeep + schedules.WeeklySnapshotsToKeep + schedules.MonthlySnapshotsToKeep
+	if total == 0 {
+		problems = append(problems, "at least one of the hourly, daily, weekly or monthly schedules is required")
+	} else if total > maxSnapshotsPerVolume {
+		problems = append(problems, fmt.Sprintf("a volume can keep at most %v snapshots, the schedules keep %v", maxSnapshotsPerVolume, total))
+	}
+
+	properties := &armnetapp.SnapshotPolicyProperties{
+		Enabled: to.Ptr(enabled),
+	}
+
+	if schedules.HourlySnapshotsToKeep > 0 {
+		checkRange("hourly minute", schedules.HourlyMinute, 0, 59)
+		properties.HourlySchedule = &armnetapp.HourlySchedule{
+			SnapshotsToKeep: to.Ptr(schedules.HourlySnapshotsToKeep),
+			Minute:          to.Ptr(schedules.HourlyMinute),
+		}
+	}
+
+	if schedules.DailySnapshotsToKeep > 0 {
+		checkRange("daily hour", schedules.DailyHour, 0, 23)
+		checkRange("daily minute", schedules.DailyMinute, 0, 59)
+		properties.DailySchedule = &armnetapp.DailySchedule{
+			SnapshotsToKeep: to.Ptr(schedules.DailySnapshotsToKeep),
+			Hour:            to.Ptr(schedules.DailyHour),
+			Minute:          to.Ptr(schedules.DailyMinute),
+		}
+	}
+
+	if schedules.WeeklySnapshotsToKeep > 0 {
+		checkRange("weekly hour", schedules.WeeklyHour, 0, 23)
+		checkRange("weekly minute", schedules.WeeklyMinute, 0, 59)
+
+		days := []string{}
+		for _, day := range schedules.WeeklyDays {
+			validDay := ""
+			for _, weekDay := range weekDays {
+				if strings.EqualFold(weekDay, strings.TrimSpace(day)) {
+					validDay = weekDay
+				}
+			}
+			if validDay == "" {
+				problems = append(problems, fmt.Sprintf("invalid weekly day %v, valid days are: %v", day, weekDays))
+				continue
+			}
+			days = append(days, validDay)
+		}
+		if len(schedules.WeeklyDays) == 0 {
+			problems = append(problems, "weekly schedule requires at least one day")
+		}
+
+		properties.WeeklySchedule = &armnetapp.WeeklySchedule{
+			SnapshotsToKeep: to.Ptr(schedules.WeeklySnapshotsToKeep),
+			Day:             to.Ptr(strings.Join(days, ",")),
+			Hour:            to.Ptr(schedules.WeeklyHour),
+			Minute:          to.Ptr(schedules.WeeklyMinute),
+		}
+	}
+
+	if schedules.MonthlySnapshotsToKeep > 0 {
+		checkRange("monthly hour", schedules.MonthlyHour, 0, 23)
+		checkRange("monthly minute", schedules.MonthlyMinute, 0, 59)
+
+		days := []string{}
+		for _, day := range schedules.MonthlyDays {
+			checkRange("monthly day", day, 1, 31)
+			days = append(days, fmt.Sprintf("%v", day))
+		}
+		if len(schedules.MonthlyDays) == 0 {
+			problems = append(problems, "monthly schedule requires at least one day of the month")
+		}
+
+		properties.MonthlySchedule = &armnetapp.MonthlySchedule{
+			SnapshotsToKeep: to.Ptr(schedules.MonthlySnapshotsToKeep),
+			DaysOfMonth:     to.Ptr(strings.Join(days, ",")),
+			Hour:            to.Ptr(schedules.MonthlyHour),
+			Minute:          to.Ptr(schedules.MonthlyMinute),
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, invalidArgument("invalid snapshot policy: %v", strings.Join(problems, "; "))
+	}
+
+	return properties, nil
+}
+
+// NewANFSnapshotPolicyPatchProperties builds the properties of a Snapshot Policy update like NewANFSnapshotPolicyProperties.
+// ARM keeps the schedules left out of a PATCH, so the schedules keeping no snapshots are sent with 0 snapshots to keep to remove them.
+func NewANFSnapshotPolicyPatchProperties(schedules models.SnapshotPolicySchedules, enabled bool) (*armnetapp.SnapshotPolicyProperties, error) {
+	properties, err := NewANFSnapshotPolicyProperties(schedules, enabled)
+	if err != nil {
+		return nil, err
+	}
+
+	if properties.HourlySchedule == nil {
+		properties.HourlySchedule = &armnetapp.HourlySchedule{SnapshotsToKeep: to.Ptr[int32](0)}
+	}
+	if properties.DailySchedule == nil {
+		properties.DailySchedule = &armnetapp.DailySchedule{SnapshotsToKeep: to.Ptr[int32](0)}
+	}
+	if properties.WeeklySchedule == nil {
+		properties.WeeklySchedule = &armnetapp.WeeklySchedule{SnapshotsToKeep: to.Ptr[int32](0)}
+	}
+	if properties.MonthlySchedule == nil {
+		properties.MonthlySchedule = &armnetapp.MonthlySchedule{SnapshotsToKeep: to.Ptr[int32](0)}
+	}
+
+	return properties, nil
+}
+
 // DeleteANFVolume deletes a volume
-func DeleteANFVolume(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string) error {
-	volumesClient, err := getVolumesClient()
+func (c *Client) DeleteANFVolume(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string) error {
+	future, err := c.beginDeleteANFVolume(ctx, resourceGroupName, accountName, poolName, volumeName)
 	if err != nil {
 		return err
 	}
 
+	_, err = pollUntilDone(ctx, c, future, OperationVolumeDelete, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName))
+	if err != nil {
+		return fmt.Errorf("cannot get the volume delete future response: %w", newError(err))
+	}
+
+	return nil
+}
+
+// BeginDeleteANFVolume starts the deletion of a volume and returns its operation without waiting for it
+func (c *Client) BeginDeleteANFVolume(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string) (*Operation, error) {
+	future, err := c.beginDeleteANFVolume(ctx, resourceGroupName, accountName, poolName, volumeName)
+	if err != nil {
+		return nil, err
+	}
+
+	return startOperation(ctx, c, future, OperationVolumeDelete, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName, "volumes", volumeName))
+}
+
+// beginDeleteANFVolume sends the request of DeleteANFVolume
+func (c *Client) beginDeleteANFVolume(ctx context.Context, resourceGroupName, accountName, poolName, volumeName string) (*runtime.Poller[armnetapp.VolumesClientDeleteResponse], error) {
+	volumesClient := c.volumes
+
 	future, err := volumesClient.BeginDelete(
 		ctx,
 		resourceGroupName,
@@ -535,24 +1175,41 @@ func DeleteANFVolume(ctx context.Context, resourceGroupName, accountName, poolNa
 		nil,
 	)
 	if err != nil {
-		return fmt.Errorf("cannot delete volume: %v", err)
+		return nil, fmt.Errorf("cannot delete volume: %w", newError(err))
 	}
 
-	_, err = future.PollUntilDone(ctx, nil)
+	return future, nil
+}
+
+// DeleteANFCapacityPool deletes a capacity pool
+func (c *Client) DeleteANFCapacityPool(ctx context.Context, resourceGroupName, accountName, poolName string) error {
+	future, err := c.beginDeleteANFCapacityPool(ctx, resourceGroupName, accountName, poolName)
+	if err != nil {
+		return err
+	}
+
+	_, err = pollUntilDone(ctx, c, future, OperationPoolDelete, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName))
 	if err != nil {
-		return fmt.Errorf("cannot get the volume delete future response: %v", err)
+		return fmt.Errorf("cannot get the capacity pool delete future response: %w", newError(err))
 	}
 
 	return nil
 }
 
-// DeleteANFCapacityPool deletes a capacity pool
-func DeleteANFCapacityPool(ctx context.Context, resourceGroupName, accountName, poolName string) error {
-	poolsClient, err := getPoolsClient()
+// BeginDeleteANFCapacityPool starts the deletion of a capacity pool and returns its operation without waiting for it
+func (c *Client) BeginDeleteANFCapacityPool(ctx context.Context, resourceGroupName, accountName, poolName string) (*Operation, error) {
+	future, err := c.beginDeleteANFCapacityPool(ctx, resourceGroupName, accountName, poolName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	return startOperation(ctx, c, future, OperationPoolDelete, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "capacityPools", poolName))
+}
+
+// beginDeleteANFCapacityPool sends the request of DeleteANFCapacityPool
+func (c *Client) beginDeleteANFCapacityPool(ctx context.Context, resourceGroupName, accountName, poolName string) (*runtime.Poller[armnetapp.PoolsClientDeleteResponse], error) {
+	poolsClient := c.pools
+
 	future, err := poolsClient.BeginDelete(
 		ctx,
 		resourceGroupName,
@@ -561,24 +1218,41 @@ func DeleteANFCapacityPool(ctx context.Context, resourceGroupName, accountName,
 		nil,
 	)
 	if err != nil {
-		return fmt.Errorf("cannot delete capacity pool: %v", err)
+		return nil, fmt.Errorf("cannot delete capacity pool: %w", newError(err))
+	}
+
+	return future, nil
+}
+
+// DeleteANFSnapshotPolicy deletes a snapshot policy
+func (c *Client) DeleteANFSnapshotPolicy(ctx context.Context, resourceGroupName, accountName, policyName string) error {
+	future, err := c.beginDeleteANFSnapshotPolicy(ctx, resourceGroupName, accountName, policyName)
+	if err != nil {
+		return err
 	}
 
-	_, err = future.PollUntilDone(ctx, nil)
+	_, err = pollUntilDone(ctx, c, future, OperationSnapshotPolicyDelete, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "snapshotPolicies", policyName))
 	if err != nil {
-		return fmt.Errorf("cannot get the capacity pool delete future response: %v", err)
+		return fmt.Errorf("cannot get the snapshot policy delete future response: %w", newError(err))
 	}
 
 	return nil
 }
 
-// DeleteANFSnapshotPolicy deletes a snapshot policy
-func DeleteANFSnapshotPolicy(ctx context.Context, resourceGroupName, accountName, policyName string) error {
-	snapshotPolicyClient, err := getSnapshotPoliciesClient()
+// BeginDeleteANFSnapshotPolicy starts the deletion of a snapshot policy and returns its operation without waiting for it
+func (c *Client) BeginDeleteANFSnapshotPolicy(ctx context.Context, resourceGroupName, accountName, policyName string) (*Operation, error) {
+	future, err := c.beginDeleteANFSnapshotPolicy(ctx, resourceGroupName, accountName, policyName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	return startOperation(ctx, c, future, OperationSnapshotPolicyDelete, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName, "snapshotPolicies", policyName))
+}
+
+// beginDeleteANFSnapshotPolicy sends the request of DeleteANFSnapshotPolicy
+func (c *Client) beginDeleteANFSnapshotPolicy(ctx context.Context, resourceGroupName, accountName, policyName string) (*runtime.Poller[armnetapp.SnapshotPoliciesClientDeleteResponse], error) {
+	snapshotPolicyClient := c.snapshotPolicies
+
 	future, err := snapshotPolicyClient.BeginDelete(
 		ctx,
 		resourceGroupName,
@@ -587,191 +1261,51 @@ func DeleteANFSnapshotPolicy(ctx context.Context, resourceGroupName, accountName
 		nil,
 	)
 	if err != nil {
-		return fmt.Errorf("cannot delete snapshot policy: %v", err)
+		return nil, fmt.Errorf("cannot delete snapshot policy: %w", newError(err))
 	}
 
-	_, err = future.PollUntilDone(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("cannot get the snapshot policy delete future response: %v", err)
-	}
-
-	return nil
+	return future, nil
 }
 
 // DeleteANFAccount deletes an account
-func DeleteANFAccount(ctx context.Context, resourceGroupName, accountName string) error {
-	accountsClient, err := getAccountsClient()
+func (c *Client) DeleteANFAccount(ctx context.Context, resourceGroupName, accountName string) error {
+	future, err := c.beginDeleteANFAccount(ctx, resourceGroupName, accountName)
 	if err != nil {
 		return err
 	}
 
-	future, err := accountsClient.BeginDelete(
-		ctx,
-		resourceGroupName,
-		accountName,
-		nil,
-	)
-
+	_, err = pollUntilDone(ctx, c, future, OperationAccountDelete, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName))
 	if err != nil {
-		return fmt.Errorf("cannot delete account: %v", err)
-	}
-
-	_, err = future.PollUntilDone(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("cannot get the account delete future response: %v", err)
+		return fmt.Errorf("cannot get the account delete future response: %w", newError(err))
 	}
 
 	return nil
 }
 
-// WaitForNoANFResource waits for a specified resource to don't exist anymore following a deletion.
-// This is due to a known issue related to ARM Cache where the state of the resource is still cached within ARM infrastructure
-// reporting that it still exists so looping into a get process will return 404 as soon as the cached state expires
-func WaitForNoANFResource(ctx context.Context, resourceID string, intervalInSec int, retries int, checkForReplication bool) error {
-	var err error
-
-	for i := 0; i < retries; i++ {
-		time.Sleep(time.Duration(intervalInSec) * time.Second)
-		if uri.IsANFSnapshot(resourceID) {
-			client, _ := getSnapshotsClient()
-			_, err = client.Get(
-				ctx,
-				uri.GetResourceGroup(resourceID),
-				uri.GetANFAccount(resourceID),
-				uri.GetANFCapacityPool(resourceID),
-				uri.GetANFVolume(resourceID),
-				uri.GetANFSnapshot(resourceID),
-				nil,
-			)
-		} else if uri.IsANFVolume(resourceID) {
-			client, _ := getVolumesClient()
-			if !checkForReplication {
-				_, err = client.Get(
-					ctx,
-					uri.GetResourceGroup(resourceID),
-					uri.GetANFAccount(resourceID),
-					uri.GetANFCapacityPool(resourceID),
-					uri.GetANFVolume(resourceID),
-					nil,
-				)
-			} else {
-				_, err = client.ReplicationStatus(
-					ctx,
-					uri.GetResourceGroup(resourceID),
-					uri.GetANFAccount(resourceID),
-					uri.GetANFCapacityPool(resourceID),
-					uri.GetANFVolume(resourceID),
-					nil,
-				)
-			}
-		} else if uri.IsANFCapacityPool(resourceID) {
-			client, _ := getPoolsClient()
-			_, err = client.Get(
-				ctx,
-				uri.GetResourceGroup(resourceID),
-				uri.GetANFAccount(resourceID),
-				uri.GetANFCapacityPool(resourceID),
-				nil,
-			)
-		} else if uri.IsANFSnapshotPolicy(resourceID) {
-			client, _ := getSnapshotPoliciesClient()
-			_, err = client.Get(
-				ctx,
-				uri.GetResourceGroup(resourceID),
-				uri.GetANFAccount(resourceID),
-				uri.GetANFSnapshotPolicy(resourceID),
-				nil,
-			)
-		} else if uri.IsANFAccount(resourceID) {
-			client, _ := getAccountsClient()
-			_, err = client.Get(
-				ctx,
-				uri.GetResourceGroup(resourceID),
-				uri.GetANFAccount(resourceID),
-				nil,
-			)
-		}
-
-		// In this case error is expected
-		if err != nil {
-			return nil
-		}
+// BeginDeleteANFAccount starts the deletion of an account and returns its operation without waiting for it
+func (c *Client) BeginDeleteANFAccount(ctx context.Context, resourceGroupName, accountName string) (*Operation, error) {
+	future, err := c.beginDeleteANFAccount(ctx, resourceGroupName, accountName)
+	if err != nil {
+		return nil, err
 	}
 
-	return fmt.Errorf("exceeded number of retries: %v", retries)
+	return startOperation(ctx, c, future, OperationAccountDelete, c.anfResourceID(resourceGroupName, "netAppAccounts", accountName))
 }
 
-// WaitForANFResource waits for a specified resource to be fully ready following a creation operation.
-func WaitForANFResource(ctx context.Context, resourceID string, intervalInSec int, retries int, checkForReplication bool) error {
-	var err error
+// beginDeleteANFAccount sends the request of DeleteANFAccount
+func (c *Client) beginDeleteANFAccount(ctx context.Context, resourceGroupName, accountName string) (*runtime.Poller[armnetapp.AccountsClientDeleteResponse], error) {
+	accountsClient := c.accounts
 
-	for i := 0; i < retries; i++ {
-		time.Sleep(time.Duration(intervalInSec) * time.Second)
-		if uri.IsANFSnapshot(resourceID) {
-			client, _ := getSnapshotsClient()
-			_, err = client.Get(
-				ctx,
-				uri.GetResourceGroup(resourceID),
-				uri.GetANFAccount(resourceID),
-				uri.GetANFCapacityPool(resourceID),
-				uri.GetANFVolume(resourceID),
-				uri.GetANFSnapshot(resourceID),
-				nil,
-			)
-		} else if uri.IsANFVolume(resourceID) {
-			client, _ := getVolumesClient()
-			if !checkForReplication {
-				_, err = client.Get(
-					ctx,
-					uri.GetResourceGroup(resourceID),
-					uri.GetANFAccount(resourceID),
-					uri.GetANFCapacityPool(resourceID),
-					uri.GetANFVolume(resourceID),
-					nil,
-				)
-			} else {
-				_, err = client.ReplicationStatus(
-					ctx,
-					uri.GetResourceGroup(resourceID),
-					uri.GetANFAccount(resourceID),
-					uri.GetANFCapacityPool(resourceID),
-					uri.GetANFVolume(resourceID),
-					nil,
-				)
-			}
-		} else if uri.IsANFCapacityPool(resourceID) {
-			client, _ := getPoolsClient()
-			_, err = client.Get(
-				ctx,
-				uri.GetResourceGroup(resourceID),
-				uri.GetANFAccount(resourceID),
-				uri.GetANFCapacityPool(resourceID),
-				nil,
-			)
-		} else if uri.IsANFSnapshotPolicy(resourceID) {
-			client, _ := getSnapshotPoliciesClient()
-			_, err = client.Get(
-				ctx,
-				uri.GetResourceGroup(resourceID),
-				uri.GetANFAccount(resourceID),
-				uri.GetANFSnapshotPolicy(resourceID),
-				nil,
-			)
-		} else if uri.IsANFAccount(resourceID) {
-			client, _ := getAccountsClient()
-			_, err = client.Get(
-				ctx,
-				uri.GetResourceGroup(resourceID),
-				uri.GetANFAccount(resourceID),
-				nil,
-			)
-		}
+	future, err := accountsClient.BeginDelete(
+		ctx,
+		resourceGroupName,
+		accountName,
+		nil,
+	)
 
-		// In this case, we exit when there is no error
-		if err == nil {
-			return nil
-		}
+	if err != nil {
+		return nil, fmt.Errorf("cannot delete account: %w", newError(err))
 	}
 
-	return fmt.Errorf("resource still not found after number of retries: %v, error: %v", retries, err)
+	return future, nil
 }
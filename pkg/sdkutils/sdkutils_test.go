@@ -0,0 +1,188 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+package sdkutils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/patrikcze/go-anf/pkg/emulator"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+)
+
+const (
+	testLocation      = "westus"
+	testResourceGroup = "rg"
+	testSubnetID      = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/anf"
+)
+
+// newTestClient returns a client talking to a new emulator, with waits short enough for tests
+func newTestClient(t *testing.T, options emulator.Options) *Client {
+	t.Helper()
+
+	server := emulator.NewServer(options)
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(emulator.Credential{}, emulator.SubscriptionID, server.ClientOptions())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	client.SetWaitOptions(&WaitOptions{Interval: 10 * time.Millisecond, MaxInterval: 100 * time.Millisecond, Timeout: 30 * time.Second})
+	return client
+}
+
+// testVolumeSpec returns a valid spec of a 100 GiB NFSv3 volume
+func testVolumeSpec(accountName, poolName, volumeName string) VolumeSpec {
+	return VolumeSpec{
+		Location:       testLocation,
+		ResourceGroup:  testResourceGroup,
+		Account:        accountName,
+		Pool:           poolName,
+		Name:           volumeName,
+		ServiceLevel:   "Premium",
+		SubnetID:       testSubnetID,
+		ProtocolTypes:  []string{nfsv3},
+		UsageThreshold: 100 * VolumeQuotaIncrement,
+	}
+}
+
+func TestClientCreateGetDelete(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, emulator.Options{})
+
+	account, err := client.CreateANFAccount(ctx, testLocation, testResourceGroup, "account1", nil, map[string]*string{"env": to.Ptr("test")})
+	if err != nil {
+		t.Fatalf("CreateANFAccount() error = %v", err)
+	}
+	if got := valueOf(account.Name); got != "account1" {
+		t.Errorf("account name = %q, want account1", got)
+	}
+
+	if _, err := client.CreateANFCapacityPool(ctx, testLocation, testResourceGroup, "account1", "pool1", "Premium", 4<<40, nil); err != nil {
+		t.Fatalf("CreateANFCapacityPool() error = %v", err)
+	}
+	pool, err := client.GetANFCapacityPool(ctx, testResourceGroup, "account1", "pool1")
+	if err != nil {
+		t.Fatalf("GetANFCapacityPool() error = %v", err)
+	}
+	if got := *pool.Properties.Size; got != 4<<40 {
+		t.Errorf("pool size = %v, want %v", got, int64(4<<40))
+	}
+	if got := valueOf(pool.Properties.ProvisioningState); got != provisioningStateSucceeded {
+		t.Errorf("pool provisioning state = %q, want %q", got, provisioningStateSucceeded)
+	}
+
+	if _, err := client.CreateANFVolume(ctx, testVolumeSpec("account1", "pool1", "volume1")); err != nil {
+		t.Fatalf("CreateANFVolume() error = %v", err)
+	}
+	volume, err := client.GetANFVolume(ctx, testResourceGroup, "account1", "pool1", "volume1")
+	if err != nil {
+		t.Fatalf("GetANFVolume() error = %v", err)
+	}
+	if got := *volume.Properties.UsageThreshold; got != 100*VolumeQuotaIncrement {
+		t.Errorf("volume quota = %v, want %v", got, 100*VolumeQuotaIncrement)
+	}
+	if len(volume.Properties.MountTargets) == 0 {
+		t.Errorf("volume has no mount target")
+	}
+
+	// Parents cannot be deleted before their children
+	if err := client.DeleteANFCapacityPool(ctx, testResourceGroup, "account1", "pool1"); !errors.Is(err, ErrConflict) {
+		t.Errorf("DeleteANFCapacityPool() of a pool with volumes error = %v, want ErrConflict", err)
+	}
+
+	if err := client.DeleteANFVolume(ctx, testResourceGroup, "account1", "pool1", "volume1"); err != nil {
+		t.Fatalf("DeleteANFVolume() error = %v", err)
+	}
+	if err := client.DeleteANFCapacityPool(ctx, testResourceGroup, "account1", "pool1"); err != nil {
+		t.Fatalf("DeleteANFCapacityPool() error = %v", err)
+	}
+	if err := client.DeleteANFAccount(ctx, testResourceGroup, "account1"); err != nil {
+		t.Fatalf("DeleteANFAccount() error = %v", err)
+	}
+
+	if _, err := client.GetANFVolume(ctx, testResourceGroup, "account1", "pool1", "volume1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetANFVolume() after delete error = %v, want ErrNotFound", err)
+	}
+	if _, err := client.GetANFAccount(ctx, testResourceGroup, "account1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetANFAccount() after delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestClientLongRunningOperation(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, emulator.Options{OperationDelay: 500 * time.Millisecond})
+
+	operation, err := client.BeginCreateANFAccount(ctx, testLocation, testResourceGroup, "account1", nil, nil)
+	if err != nil {
+		t.Fatalf("BeginCreateANFAccount() error = %v", err)
+	}
+	if operation.Status != OperationInProgress || operation.ResumeToken == "" {
+		t.Fatalf("operation = %+v, want an operation in progress with a resume token", operation)
+	}
+
+	account, err := client.GetANFAccount(ctx, testResourceGroup, "account1")
+	if err != nil {
+		t.Fatalf("GetANFAccount() error = %v", err)
+	}
+	if got := valueOf(account.Properties.ProvisioningState); got != "Creating" {
+		t.Errorf("provisioning state during the creation = %q, want Creating", got)
+	}
+
+	// Another process only has the operation as recorded in the journal, without its poller
+	resumed := &Operation{
+		ID:          operation.ID,
+		Type:        operation.Type,
+		ResourceID:  operation.ResourceID,
+		Status:      operation.Status,
+		StartedAt:   operation.StartedAt,
+		ResumeToken: operation.ResumeToken,
+	}
+	if err := client.ResumeOperation(ctx, resumed, nil); err != nil {
+		t.Fatalf("ResumeOperation() error = %v", err)
+	}
+	if resumed.Status != OperationSucceeded || resumed.FinishedAt == nil {
+		t.Errorf("resumed operation = %+v, want a finished operation that succeeded", resumed)
+	}
+
+	// The synchronous variant polls until the operation completed
+	pool, err := client.CreateANFCapacityPool(ctx, testLocation, testResourceGroup, "account1", "pool1", "Standard", 4<<40, nil)
+	if err != nil {
+		t.Fatalf("CreateANFCapacityPool() error = %v", err)
+	}
+	if got := valueOf(pool.Properties.ProvisioningState); got != provisioningStateSucceeded {
+		t.Errorf("pool provisioning state = %q, want %q", got, provisioningStateSucceeded)
+	}
+}
+
+func TestClientNotFoundAfterDelete(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, emulator.Options{CacheDelay: 300 * time.Millisecond})
+
+	if _, err := client.CreateANFAccount(ctx, testLocation, testResourceGroup, "account1", nil, nil); err != nil {
+		t.Fatalf("CreateANFAccount() error = %v", err)
+	}
+	if err := client.DeleteANFAccount(ctx, testResourceGroup, "account1"); err != nil {
+		t.Fatalf("DeleteANFAccount() error = %v", err)
+	}
+
+	// The ARM cache still returns the account for a while after its deletion completed
+	if _, err := client.GetANFAccount(ctx, testResourceGroup, "account1"); err != nil {
+		t.Fatalf("GetANFAccount() right after delete error = %v, want the cached account", err)
+	}
+
+	resourceID := client.anfResourceID(testResourceGroup, "netAppAccounts", "account1")
+	if err := client.WaitForNoANFResource(ctx, resourceID, nil); err != nil {
+		t.Fatalf("WaitForNoANFResource() error = %v", err)
+	}
+	_, err := client.GetANFAccount(ctx, testResourceGroup, "account1")
+	if !errors.Is(err, ErrNotFound) || !IsNotFound(err) {
+		t.Errorf("GetANFAccount() after the cache delay error = %v, want ErrNotFound", err)
+	}
+}
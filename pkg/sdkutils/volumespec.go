@@ -0,0 +1,314 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// Specification of a new volume. A VolumeSpec is validated as a whole and
+// turned into the volume sent to Azure, the optional properties it leaves
+// unset are omitted from the request so the service defaults apply.
+// Volumes served over SMB, alone or together with NFS as dual protocol
+// volumes, and LDAP enabled volumes rely on the Active Directory
+// connection of their account, which is checked before they are created.
+
+package sdkutils
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/patrikcze/go-anf/pkg/utils"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+)
+
+const (
+	// VolumeQuotaIncrement is the granularity volume quotas are provisioned in
+	VolumeQuotaIncrement int64 = 1 << 30
+
+	minVolumeQuota = 100 * VolumeQuotaIncrement
+	maxVolumeQuota = 100 << 40
+)
+
+var (
+	validSecurityStyles  = []string{string(armnetapp.SecurityStyleNtfs), string(armnetapp.SecurityStyleUnix)}
+	validNetworkFeatures = []string{string(armnetapp.NetworkFeaturesBasic), string(armnetapp.NetworkFeaturesStandard)}
+
+	// unixPermissionsPattern matches four octal digits, e.g. 0755, the first one sets the setuid, setgid and sticky bits
+	unixPermissionsPattern = regexp.MustCompile(`^[0-7]{4}$`)
+)
+
+// VolumeSpec object definition, the desired state of a new volume.
+// Pointer, slice and empty string fields after DataProtection are optional.
+// There is no availability zone: API version 2022-05-01 of armnetapp v1.0.0 has no Zones on volumes.
+type VolumeSpec struct {
+	Location      string
+	ResourceGroup string
+	Account       string
+	Pool          string
+	Name          string
+	// ServiceLevel is Standard, Premium or Ultra, usually the one of the capacity pool
+	ServiceLevel string
+	// SubnetID is the resource ID of a subnet delegated to Microsoft.NetApp/volumes
+	SubnetID string
+	// ProtocolTypes are NFSv3, NFSv4.1 or CIFS
+	ProtocolTypes []string
+	// UsageThreshold is the quota of the volume in bytes, a whole number of GiB
+	UsageThreshold int64
+	// SnapshotID is the resource ID of a snapshot the volume is created from
+	SnapshotID string
+	// BackupID is the resource ID of a backup the volume is restored from
+	BackupID string
+	// UnixReadOnly exports the volume read only to NFS clients of the default export rule
+	UnixReadOnly bool
+	// ExportRules is the export policy of a NFS volume, when empty every client may mount the volume
+	ExportRules []ExportRule
+	Tags        map[string]*string
+	// DataProtection assigns a snapshot or backup policy or makes the volume a replication destination
+	DataProtection *armnetapp.VolumePropertiesDataProtection
+
+	// ThroughputMibps is the throughput of a volume of a manual QoS capacity pool
+	ThroughputMibps *float32
+	// SecurityStyle is ntfs or unix, it decides whether Windows ACLs or UNIX permissions
+	// apply to the files of a dual protocol volume
+	SecurityStyle string
+	// NetworkFeatures is Basic or Standard
+	NetworkFeatures string
+	// KerberosEnabled enables Kerberos on a NFSv4.1 volume
+	KerberosEnabled *bool
+	// LdapEnabled resolves the NFS users and groups through the LDAP service of the Active Directory connection
+	LdapEnabled *bool
+	// SnapshotDirectoryVisible shows the .snapshot directory to NFS clients
+	SnapshotDirectoryVisible *bool
+	// UnixPermissions are the octal permissions of the volume root, e.g. 0770
+	UnixPermissions string
+}
+
+// Validate checks the whole spec and reports all problems found in a single ErrInvalidArgument error
+func (s *VolumeSpec) Validate() error {
+	problems := s.Problems()
+	if len(problems) == 0 {
+		return nil
+	}
+
+	if s.Name == "" {
+		return invalidArgument("invalid volume: %v", strings.Join(problems, "; "))
+	}
+	return invalidArgument("invalid volume %v: %v", s.Name, strings.Join(problems, "; "))
+}
+
+// Problems returns every problem of the spec, empty when it is valid
+func (s *VolumeSpec) Problems() []string {
+	problems := []string{}
+	problem := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+
+	for _, required := range []struct{ name, value string }{
+		{"location", s.Location},
+		{"resource group", s.ResourceGroup},
+		{"account", s.Account},
+		{"capacity pool", s.Pool},
+		{"name", s.Name},
+		{"subnet ID", s.SubnetID},
+	} {
+		if required.value == "" {
+			problem("%v is required", required.name)
+		}
+	}
+	if s.SubnetID != "" && !strings.Contains(strings.ToLower(s.SubnetID), "/subnets/") {
+		problem("subnet ID %v is not a subnet resource ID", s.SubnetID)
+	}
+
+	if _, err := ValidateANFServiceLevel(s.ServiceLevel); err != nil {
+		problem("%v", err)
+	}
+	protocolErr := ValidateANFProtocolTypes(s.ProtocolTypes)
+	if protocolErr != nil {
+		problem("%v", protocolErr)
+	}
+
+	switch {
+	case s.UsageThreshold%VolumeQuotaIncrement != 0:
+		problem("quota %v is not a whole number of GiB", utils.FormatSize(s.UsageThreshold))
+	case s.UsageThreshold < minVolumeQuota || s.UsageThreshold > maxVolumeQuota:
+		problem("quota %v must be between %v and %v", utils.FormatSize(s.UsageThreshold), utils.FormatSize(minVolumeQuota), utils.FormatSize(maxVolumeQuota))
+	}
+
+	if s.SnapshotID != "" && s.BackupID != "" {
+		problem("a volume is created from either a snapshot or a backup, not both")
+	}
+
+	if s.ThroughputMibps != nil && *s.ThroughputMibps <= 0 {
+		problem("throughput must be greater than 0 MiB/s")
+	}
+	if s.SecurityStyle != "" {
+		if !containsFold(validSecurityStyles, s.SecurityStyle) {
+			problem("invalid security style %v, valid security styles are: %v", s.SecurityStyle, validSecurityStyles)
+		} else if protocolErr == nil && strings.EqualFold(s.SecurityStyle, string(armnetapp.SecurityStyleNtfs)) && !utils.Contains(s.ProtocolTypes, cifs) {
+			problem("security style ntfs requires the CIFS protocol")
+		} else if protocolErr == nil && strings.EqualFold(s.SecurityStyle, string(armnetapp.SecurityStyleUnix)) && !s.hasNFS() {
+			problem("security style unix requires the NFSv3 or NFSv4.1 protocol")
+		}
+	}
+	if s.NetworkFeatures != "" && !containsFold(validNetworkFeatures, s.NetworkFeatures) {
+		problem("invalid network features %v, valid network features are: %v", s.NetworkFeatures, validNetworkFeatures)
+	}
+	if protocolErr == nil && s.KerberosEnabled != nil && *s.KerberosEnabled && !utils.Contains(s.ProtocolTypes, nfsv41) {
+		problem("Kerberos requires the NFSv4.1 protocol")
+	}
+	if protocolErr == nil && s.LdapEnabled != nil && *s.LdapEnabled && !s.hasNFS() {
+		problem("LDAP requires the NFSv3 or NFSv4.1 protocol")
+	}
+	if protocolErr == nil && utils.Contains(s.ProtocolTypes, nfsv41) && utils.Contains(s.ProtocolTypes, cifs) && (s.LdapEnabled == nil || !*s.LdapEnabled) {
+		problem("dual protocol NFSv4.1 and CIFS volumes require LDAP to map the NFS users to Windows users, enable it")
+	}
+	if s.UnixPermissions != "" {
+		if !unixPermissionsPattern.MatchString(s.UnixPermissions) {
+			problem("invalid unix permissions %v, expected four octal digits such as 0755", s.UnixPermissions)
+		} else if protocolErr == nil && !s.hasNFS() {
+			problem("unix permissions require the NFSv3 or NFSv4.1 protocol")
+		}
+	}
+
+	if len(s.ExportRules) > 0 && protocolErr == nil {
+		if !s.hasNFS() {
+			problem("export rules require the NFSv3 or NFSv4.1 protocol")
+		}
+		problems = append(problems, exportRulesProblems(s.ExportRules, s.ProtocolTypes, s.KerberosEnabled != nil && *s.KerberosEnabled)...)
+	}
+
+	if s.DataProtection != nil && s.DataProtection.Replication != nil {
+		if replication := s.DataProtection.Replication; replication.RemoteVolumeResourceID == nil || *replication.RemoteVolumeResourceID == "" {
+			problem("the replication source volume ID is required")
+		}
+	}
+
+	return problems
+}
+
+// Volume returns the volume sent to Azure to create the spec, the spec is expected to be valid
+func (s *VolumeSpec) Volume() armnetapp.Volume {
+	serviceLevel, _ := ValidateANFServiceLevel(s.ServiceLevel)
+
+	protocolTypes := make([]*string, len(s.ProtocolTypes))
+	for i, protocolType := range s.ProtocolTypes {
+		protocolTypes[i] = to.Ptr(protocolType)
+	}
+
+	// SMB access is controlled by share permissions, the export policy only applies to the NFS side
+	var exportPolicy *armnetapp.VolumePropertiesExportPolicy
+	if s.hasNFS() {
+		rules := s.ExportRules
+		if len(rules) == 0 {
+			rules = []ExportRule{DefaultExportRule(s.ProtocolTypes, s.UnixReadOnly, s.KerberosEnabled != nil && *s.KerberosEnabled)}
+		}
+		// The rules were validated with the spec, Replace only orders and numbers them
+		builder := &ExportPolicyBuilder{}
+		_ = builder.Replace(rules)
+		exportPolicy = &armnetapp.VolumePropertiesExportPolicy{
+			Rules: builder.ExportPolicyRules(),
+		}
+	}
+
+	properties := armnetapp.VolumeProperties{
+		ExportPolicy:             exportPolicy,
+		ProtocolTypes:            protocolTypes,
+		ServiceLevel:             &serviceLevel,
+		SubnetID:                 to.Ptr(s.SubnetID),
+		UsageThreshold:           to.Ptr(s.UsageThreshold),
+		CreationToken:            to.Ptr(s.Name),
+		DataProtection:           s.DataProtection,
+		ThroughputMibps:          s.ThroughputMibps,
+		KerberosEnabled:          s.KerberosEnabled,
+		LdapEnabled:              s.LdapEnabled,
+		SnapshotDirectoryVisible: s.SnapshotDirectoryVisible,
+	}
+	if s.SnapshotID != "" {
+		properties.SnapshotID = to.Ptr(s.SnapshotID)
+	}
+	if s.BackupID != "" {
+		properties.BackupID = to.Ptr(s.BackupID)
+	}
+	if s.SecurityStyle != "" {
+		properties.SecurityStyle = to.Ptr(armnetapp.SecurityStyle(strings.ToLower(s.SecurityStyle)))
+	}
+	if s.NetworkFeatures != "" {
+		properties.NetworkFeatures = to.Ptr(armnetapp.NetworkFeatures(matchFold(validNetworkFeatures, s.NetworkFeatures)))
+	}
+	if s.UnixPermissions != "" {
+		properties.UnixPermissions = to.Ptr(s.UnixPermissions)
+	}
+	if s.DataProtection != nil && s.DataProtection.Replication != nil {
+		properties.VolumeType = to.Ptr(dataProtectionVolumeType)
+	}
+
+	return armnetapp.Volume{
+		Location:   to.Ptr(s.Location),
+		Tags:       s.Tags,
+		Properties: &properties,
+	}
+}
+
+// hasNFS reports whether the volume is served over NFSv3 or NFSv4.1
+func (s *VolumeSpec) hasNFS() bool {
+	return utils.Contains(s.ProtocolTypes, nfsv3) || utils.Contains(s.ProtocolTypes, nfsv41)
+}
+
+// validateANFVolumeActiveDirectory checks that the account of a volume has the Active Directory connection
+// the volume needs: SMB volumes join their SMB server to the domain, LDAP enabled volumes look up users in it
+func (c *Client) validateANFVolumeActiveDirectory(ctx context.Context, spec VolumeSpec) error {
+	needs := []string{}
+	if utils.Contains(spec.ProtocolTypes, cifs) {
+		needs = append(needs, "SMB")
+	}
+	if spec.LdapEnabled != nil && *spec.LdapEnabled {
+		needs = append(needs, "LDAP")
+	}
+	if len(needs) == 0 {
+		return nil
+	}
+
+	account, err := c.GetANFAccount(ctx, spec.ResourceGroup, spec.Account)
+	if err != nil {
+		return err
+	}
+
+	if account.Properties == nil || len(account.Properties.ActiveDirectories) == 0 {
+		return invalidArgument("volume %v uses %v, which requires an Active Directory connection on account %v", spec.Name, strings.Join(needs, " and "), spec.Account)
+	}
+
+	activeDirectory := account.Properties.ActiveDirectories[0]
+	if activeDirectory.Domain == nil || *activeDirectory.Domain == "" || activeDirectory.DNS == nil || *activeDirectory.DNS == "" {
+		return invalidArgument("the Active Directory connection of account %v has no domain or DNS servers, volume %v cannot use %v", spec.Account, spec.Name, strings.Join(needs, " and "))
+	}
+	if utils.Contains(needs, "SMB") && (activeDirectory.SmbServerName == nil || *activeDirectory.SmbServerName == "") {
+		return invalidArgument("the Active Directory connection of account %v has no SMB server name, volume %v cannot use SMB", spec.Account, spec.Name)
+	}
+	// LDAP signing and LDAP over TLS are optional, LDAP over TLS needs the certificate to verify the domain controllers
+	if utils.Contains(needs, "LDAP") {
+		ldapOverTLS := activeDirectory.LdapOverTLS != nil && *activeDirectory.LdapOverTLS
+		if ldapOverTLS && (activeDirectory.ServerRootCACertificate == nil || *activeDirectory.ServerRootCACertificate == "") {
+			return invalidArgument("the Active Directory connection of account %v uses LDAP over TLS without a server root CA certificate, volume %v cannot use LDAP", spec.Account, spec.Name)
+		}
+	}
+
+	return nil
+}
+
+// containsFold reports whether values contains value, ignoring case
+func containsFold(values []string, value string) bool {
+	return matchFold(values, value) != ""
+}
+
+// matchFold returns the entry of values equal to value ignoring case, empty when there is none
+func matchFold(values []string, value string) string {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,282 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+package sdkutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/patrikcze/go-anf/pkg/emulator"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+)
+
+func TestVolumeSpecValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(s *VolumeSpec)
+		// problems are parts of the expected problems, none for a valid spec
+		problems []string
+	}{
+		{
+			name: "valid",
+			edit: func(s *VolumeSpec) {},
+		},
+		{
+			name: "valid with optional properties",
+			edit: func(s *VolumeSpec) {
+				s.ProtocolTypes = []string{nfsv41}
+				s.ThroughputMibps = to.Ptr[float32](64)
+				s.SecurityStyle = "Unix"
+				s.NetworkFeatures = "standard"
+				s.KerberosEnabled = to.Ptr(true)
+				s.UnixPermissions = "0770"
+			},
+		},
+		{
+			name: "missing required fields",
+			edit: func(s *VolumeSpec) {
+				s.Location, s.ResourceGroup, s.Account, s.Pool, s.SubnetID = "", "", "", "", ""
+			},
+			problems: []string{"location is required", "resource group is required", "account is required", "capacity pool is required", "subnet ID is required"},
+		},
+		{
+			name: "subnet ID of another resource",
+			edit: func(s *VolumeSpec) {
+				s.SubnetID = "/subscriptions/0/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet"
+			},
+			problems: []string{"is not a subnet resource ID"},
+		},
+		{
+			name:     "invalid service level",
+			edit:     func(s *VolumeSpec) { s.ServiceLevel = "Gold" },
+			problems: []string{"invalid service level"},
+		},
+		{
+			name:     "invalid protocol",
+			edit:     func(s *VolumeSpec) { s.ProtocolTypes = []string{"SMB"} },
+			problems: []string{"SMB"},
+		},
+		{
+			name:     "quota not a whole number of GiB",
+			edit:     func(s *VolumeSpec) { s.UsageThreshold = 100*VolumeQuotaIncrement + 1 },
+			problems: []string{"is not a whole number of GiB"},
+		},
+		{
+			name:     "quota too small",
+			edit:     func(s *VolumeSpec) { s.UsageThreshold = 50 * VolumeQuotaIncrement },
+			problems: []string{"must be between"},
+		},
+		{
+			name: "snapshot and backup",
+			edit: func(s *VolumeSpec) {
+				volumeID := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.NetApp/netAppAccounts/account1/capacityPools/pool1/volumes/volume0"
+				s.SnapshotID = volumeID + "/snapshots/snapshot1"
+				s.BackupID = volumeID + "/backups/backup1"
+			},
+			problems: []string{"either a snapshot or a backup, not both"},
+		},
+		{
+			name:     "zero throughput",
+			edit:     func(s *VolumeSpec) { s.ThroughputMibps = to.Ptr[float32](0) },
+			problems: []string{"throughput must be greater than 0 MiB/s"},
+		},
+		{
+			name:     "invalid security style",
+			edit:     func(s *VolumeSpec) { s.SecurityStyle = "mixed" },
+			problems: []string{"invalid security style mixed"},
+		},
+		{
+			name:     "ntfs security style without CIFS",
+			edit:     func(s *VolumeSpec) { s.SecurityStyle = "ntfs" },
+			problems: []string{"security style ntfs requires the CIFS protocol"},
+		},
+		{
+			name:     "invalid network features",
+			edit:     func(s *VolumeSpec) { s.NetworkFeatures = "Premium" },
+			problems: []string{"invalid network features Premium"},
+		},
+		{
+			name:     "Kerberos without NFSv4.1",
+			edit:     func(s *VolumeSpec) { s.KerberosEnabled = to.Ptr(true) },
+			problems: []string{"Kerberos requires the NFSv4.1 protocol"},
+		},
+		{
+			name:     "invalid unix permissions",
+			edit:     func(s *VolumeSpec) { s.UnixPermissions = "755" },
+			problems: []string{"invalid unix permissions 755"},
+		},
+		{
+			name: "valid dual protocol with LDAP",
+			edit: func(s *VolumeSpec) {
+				s.ProtocolTypes = []string{nfsv41, cifs}
+				s.SecurityStyle = "ntfs"
+				s.LdapEnabled = to.Ptr(true)
+			},
+		},
+		{
+			name:     "dual protocol NFSv4.1 without LDAP",
+			edit:     func(s *VolumeSpec) { s.ProtocolTypes = []string{nfsv41, cifs} },
+			problems: []string{"dual protocol NFSv4.1 and CIFS volumes require LDAP"},
+		},
+		{
+			name: "NFS properties of a SMB volume",
+			edit: func(s *VolumeSpec) {
+				s.ProtocolTypes = []string{cifs}
+				s.SecurityStyle = "unix"
+				s.LdapEnabled = to.Ptr(true)
+				s.UnixPermissions = "0755"
+			},
+			problems: []string{"security style unix requires", "LDAP requires", "unix permissions require"},
+		},
+		{
+			name: "replication without source volume",
+			edit: func(s *VolumeSpec) {
+				s.DataProtection = &armnetapp.VolumePropertiesDataProtection{Replication: &armnetapp.ReplicationObject{}}
+			},
+			problems: []string{"the replication source volume ID is required"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := testVolumeSpec("account1", "pool1", "volume1")
+			tt.edit(&spec)
+
+			err := spec.Validate()
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrInvalidArgument) {
+				t.Fatalf("Validate() error = %v, want ErrInvalidArgument", err)
+			}
+			if problems := spec.Problems(); len(problems) != len(tt.problems) {
+				t.Errorf("Problems() = %q, want %v problems", problems, len(tt.problems))
+			}
+			for _, problem := range tt.problems {
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("Validate() error = %v, want it to report %q", err, problem)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateANFVolumeActiveDirectory(t *testing.T) {
+	directory := func(edit func(d *armnetapp.ActiveDirectory)) []*armnetapp.ActiveDirectory {
+		d := &armnetapp.ActiveDirectory{
+			Domain:        to.Ptr("contoso.com"),
+			DNS:           to.Ptr("10.0.0.4"),
+			SmbServerName: to.Ptr("anf"),
+			Username:      to.Ptr("admin"),
+			Password:      to.Ptr("secret"),
+			LdapSigning:   to.Ptr(true),
+		}
+		edit(d)
+		return []*armnetapp.ActiveDirectory{d}
+	}
+	smb := func(s *VolumeSpec) { s.ProtocolTypes = []string{cifs} }
+	ldap := func(s *VolumeSpec) { s.LdapEnabled = to.Ptr(true) }
+
+	tests := []struct {
+		name              string
+		activeDirectories []*armnetapp.ActiveDirectory
+		edit              func(s *VolumeSpec)
+		// problem is a part of the expected error, empty when the volume is created
+		problem string
+	}{
+		{
+			name: "NFS volume without Active Directory",
+			edit: func(s *VolumeSpec) {},
+		},
+		{
+			name:    "SMB volume without Active Directory",
+			edit:    smb,
+			problem: "requires an Active Directory connection",
+		},
+		{
+			name:              "SMB volume without SMB server name",
+			activeDirectories: directory(func(d *armnetapp.ActiveDirectory) { d.SmbServerName = nil }),
+			edit:              smb,
+			problem:           "has no SMB server name",
+		},
+		{
+			name:              "SMB volume",
+			activeDirectories: directory(func(d *armnetapp.ActiveDirectory) {}),
+			edit:              smb,
+		},
+		{
+			name:              "LDAP volume without LDAP signing or LDAP over TLS",
+			activeDirectories: directory(func(d *armnetapp.ActiveDirectory) { d.LdapSigning = nil }),
+			edit:              ldap,
+		},
+		{
+			name:              "LDAP volume without DNS servers",
+			activeDirectories: directory(func(d *armnetapp.ActiveDirectory) { d.DNS = nil }),
+			edit:              ldap,
+			problem:           "has no domain or DNS servers",
+		},
+		{
+			name: "LDAP volume with LDAP over TLS without root CA certificate",
+			activeDirectories: directory(func(d *armnetapp.ActiveDirectory) {
+				d.LdapSigning = nil
+				d.LdapOverTLS = to.Ptr(true)
+			}),
+			edit:    ldap,
+			problem: "without a server root CA certificate",
+		},
+		{
+			name: "LDAP volume with LDAP over TLS",
+			activeDirectories: directory(func(d *armnetapp.ActiveDirectory) {
+				d.LdapSigning = nil
+				d.LdapOverTLS = to.Ptr(true)
+				d.ServerRootCACertificate = to.Ptr("LS0tLS1CRUdJTiBDRVJUSUZJQ0FURS0tLS0t")
+			}),
+			edit: ldap,
+		},
+		{
+			name:              "LDAP volume with LDAP signing",
+			activeDirectories: directory(func(d *armnetapp.ActiveDirectory) {}),
+			edit:              ldap,
+		},
+	}
+
+	ctx := context.Background()
+	client := newTestClient(t, emulator.Options{})
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accountName := fmt.Sprintf("account%v", i)
+			if _, err := client.CreateANFAccount(ctx, testLocation, testResourceGroup, accountName, tt.activeDirectories, nil); err != nil {
+				t.Fatalf("CreateANFAccount() error = %v", err)
+			}
+			if _, err := client.CreateANFCapacityPool(ctx, testLocation, testResourceGroup, accountName, "pool1", "Premium", 4<<40, nil); err != nil {
+				t.Fatalf("CreateANFCapacityPool() error = %v", err)
+			}
+
+			spec := testVolumeSpec(accountName, "pool1", "volume1")
+			tt.edit(&spec)
+			_, err := client.CreateANFVolume(ctx, spec)
+			if tt.problem == "" {
+				if err != nil {
+					t.Fatalf("CreateANFVolume() error = %v, want nil", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrInvalidArgument) || !strings.Contains(err.Error(), tt.problem) {
+				t.Errorf("CreateANFVolume() error = %v, want ErrInvalidArgument reporting %q", err, tt.problem)
+			}
+		})
+	}
+}
@@ -0,0 +1,359 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// Waiting for resources to reach a state. Long-running operations can
+// complete before ARM reports the final state of a resource, e.g. a
+// deleted resource is still returned from the ARM cache for a while, so
+// the state is polled with exponential backoff until it is reached.
+
+package sdkutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/patrikcze/go-anf/pkg/uri"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+)
+
+const (
+	defaultWaitInterval    = 5 * time.Second
+	defaultWaitMaxInterval = time.Minute
+	defaultWaitTimeout     = 30 * time.Minute
+
+	provisioningStateSucceeded = "Succeeded"
+	provisioningStateFailed    = "Failed"
+
+	// stateNotFound is reported while a resource does not exist
+	stateNotFound = "NotFound"
+)
+
+// WaitOptions controls how the Wait helpers poll, zero values select the defaults
+type WaitOptions struct {
+	// Interval is the delay after the first check, it doubles after every check up to MaxInterval
+	Interval time.Duration
+	// MaxInterval caps the delay between two checks
+	MaxInterval time.Duration
+	// Timeout bounds the whole wait on top of the deadline of the context
+	Timeout time.Duration
+}
+
+// Condition checks whether a resource reached the awaited state and returns the state it observed.
+// A returned error stops the wait, conditions keep waiting through transient errors.
+type Condition func(ctx context.Context) (done bool, state string, err error)
+
+// Wait checks condition until it is done, with exponential backoff and jitter between the checks.
+// It stops when the condition fails, ctx ends or the timeout expires, the error reports the last observed state.
+func Wait(ctx context.Context, options *WaitOptions, condition Condition) error {
+	opts := WaitOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultWaitInterval
+	}
+	if opts.MaxInterval < opts.Interval {
+		opts.MaxInterval = defaultWaitMaxInterval
+		if opts.MaxInterval < opts.Interval {
+			opts.MaxInterval = opts.Interval
+		}
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultWaitTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	state := "unknown"
+	interval := opts.Interval
+	for attempt := 1; ; attempt++ {
+		done, observed, err := condition(ctx)
+		if observed != "" {
+			state = observed
+		}
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("%w, last observed state: %v", err, state)
+		}
+		if done {
+			return nil
+		}
+
+		// Equal jitter: wait between half and all of the interval
+		delay := interval/2 + time.Duration(rand.Int63n(int64(interval/2)+1))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("%w after %v and %v checks, last observed state: %v", ErrTimeout, time.Since(start).Round(100*time.Millisecond), attempt, state)
+			}
+			return fmt.Errorf("stopped waiting after %v checks: %w, last observed state: %v", attempt, ctx.Err(), state)
+		case <-timer.C:
+		}
+
+		interval *= 2
+		if interval > opts.MaxInterval {
+			interval = opts.MaxInterval
+		}
+	}
+}
+
+// SetWaitOptions sets the options of the waits of the client: the Wait* methods called with nil options
+// and the long-running operations, whose duration is bounded by the timeout when it is set
+func (c *Client) SetWaitOptions(options *WaitOptions) {
+	c.waitOptions = options
+}
+
+// defaultWaitOptions returns options, falling back to the wait options of the client
+func (c *Client) defaultWaitOptions(options *WaitOptions) *WaitOptions {
+	if options == nil {
+		return c.waitOptions
+	}
+	return options
+}
+
+// operationContext bounds a long-running operation by the timeout of the wait options of the client
+func (c *Client) operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.waitOptions == nil || c.waitOptions.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.waitOptions.Timeout)
+}
+
+// WaitForANFResource waits for a resource to reach the Succeeded provisioning state following a creation or update
+func (c *Client) WaitForANFResource(ctx context.Context, resourceID string, options *WaitOptions) error {
+	if err := Wait(ctx, c.defaultWaitOptions(options), c.ProvisioningSucceeded(resourceID)); err != nil {
+		return fmt.Errorf("resource %v is not ready: %w", resourceID, err)
+	}
+	return nil
+}
+
+// WaitForNoANFResource waits for a resource to not exist anymore following a deletion.
+// This is due to a known issue related to ARM Cache where the state of the resource is still cached within ARM infrastructure
+// reporting that it still exists so looping into a get process will return 404 as soon as the cached state expires
+func (c *Client) WaitForNoANFResource(ctx context.Context, resourceID string, options *WaitOptions) error {
+	if err := Wait(ctx, c.defaultWaitOptions(options), c.ResourceAbsent(resourceID)); err != nil {
+		return fmt.Errorf("resource %v still exists: %w", resourceID, err)
+	}
+	return nil
+}
+
+// WaitForANFReplication waits for the replication of a volume to reach a mirror state, e.g. Mirrored or Broken
+func (c *Client) WaitForANFReplication(ctx context.Context, resourceID string, mirrorState armnetapp.MirrorState, options *WaitOptions) error {
+	if err := Wait(ctx, c.defaultWaitOptions(options), c.ReplicationMirrorState(resourceID, mirrorState)); err != nil {
+		return fmt.Errorf("replication of volume %v is not %v: %w", resourceID, mirrorState, err)
+	}
+	return nil
+}
+
+// WaitForNoANFReplication waits for a volume to not be part of a replication anymore following a replication deletion
+func (c *Client) WaitForNoANFReplication(ctx context.Context, resourceID string, options *WaitOptions) error {
+	if err := Wait(ctx, c.defaultWaitOptions(options), c.ReplicationAbsent(resourceID)); err != nil {
+		return fmt.Errorf("replication of volume %v still exists: %w", resourceID, err)
+	}
+	return nil
+}
+
+// ProvisioningSucceeded is a Condition met once the resource reports the Succeeded provisioning state,
+// the Failed provisioning state fails the wait
+func (c *Client) ProvisioningSucceeded(resourceID string) Condition {
+	return func(ctx context.Context) (bool, string, error) {
+		state, err := c.getProvisioningState(ctx, resourceID)
+		if err != nil {
+			return checkError(err)
+		}
+
+		switch state {
+		case provisioningStateSucceeded:
+			return true, state, nil
+		case provisioningStateFailed:
+			return false, state, fmt.Errorf("provisioning failed")
+		}
+		return false, state, nil
+	}
+}
+
+// ResourceAbsent is a Condition met once reading the resource returns HTTP 404
+func (c *Client) ResourceAbsent(resourceID string) Condition {
+	return func(ctx context.Context) (bool, string, error) {
+		state, err := c.getProvisioningState(ctx, resourceID)
+		if err != nil {
+			if IsNotFound(err) {
+				return true, stateNotFound, nil
+			}
+			return checkError(err)
+		}
+		return false, state, nil
+	}
+}
+
+// ReplicationMirrorState is a Condition met once the replication of a volume reports the mirror state
+func (c *Client) ReplicationMirrorState(resourceID string, mirrorState armnetapp.MirrorState) Condition {
+	return func(ctx context.Context) (bool, string, error) {
+		status, err := c.getReplicationStatus(ctx, resourceID)
+		if err != nil {
+			return checkError(err)
+		}
+
+		state := ""
+		if status.MirrorState != nil {
+			state = string(*status.MirrorState)
+		}
+		if status.RelationshipStatus != nil {
+			state = fmt.Sprintf("%v (%v)", state, *status.RelationshipStatus)
+		}
+		return status.MirrorState != nil && *status.MirrorState == mirrorState, state, nil
+	}
+}
+
+// ReplicationAbsent is a Condition met once reading the replication status of a volume returns HTTP 404
+func (c *Client) ReplicationAbsent(resourceID string) Condition {
+	return func(ctx context.Context) (bool, string, error) {
+		status, err := c.getReplicationStatus(ctx, resourceID)
+		if err != nil {
+			if IsNotFound(err) {
+				return true, stateNotFound, nil
+			}
+			return checkError(err)
+		}
+
+		state := ""
+		if status.MirrorState != nil {
+			state = string(*status.MirrorState)
+		}
+		return false, state, nil
+	}
+}
+
+// IsNotFound reports whether an error is an HTTP 404 response, the only proof that a resource does not exist
+func IsNotFound(err error) bool {
+	var responseErr *azcore.ResponseError
+	return errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound
+}
+
+// checkError turns an error of a Condition check into its result: a missing resource and transient
+// failures (throttling, server errors, network errors) keep the wait going, other errors stop it
+func checkError(err error) (bool, string, error) {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return false, fmt.Sprintf("network error: %v", err), nil
+	}
+
+	var responseErr *azcore.ResponseError
+	if !errors.As(err, &responseErr) {
+		return false, "", newError(err)
+	}
+
+	switch {
+	case responseErr.StatusCode == http.StatusNotFound:
+		return false, stateNotFound, nil
+	case responseErr.StatusCode == http.StatusTooManyRequests || responseErr.StatusCode >= http.StatusInternalServerError:
+		return false, fmt.Sprintf("HTTP %v %v", responseErr.StatusCode, responseErr.ErrorCode), nil
+	}
+	return false, "", newError(err)
+}
+
+// getProvisioningState reads the provisioning state of an account, pool, volume, snapshot, backup, snapshot policy or backup policy
+func (c *Client) getProvisioningState(ctx context.Context, resourceID string) (string, error) {
+	var state *string
+
+	resourceGroupName := uri.GetResourceGroup(resourceID)
+	accountName := uri.GetANFAccount(resourceID)
+	switch {
+	case uri.IsANFSnapshot(resourceID):
+		resp, err := c.snapshots.Get(ctx, resourceGroupName, accountName, uri.GetANFCapacityPool(resourceID), uri.GetANFVolume(resourceID), uri.GetANFSnapshot(resourceID), nil)
+		if err != nil {
+			return "", err
+		}
+		if resp.Properties != nil {
+			state = resp.Properties.ProvisioningState
+		}
+	case uri.IsANFBackup(resourceID):
+		resp, err := c.backups.Get(ctx, resourceGroupName, accountName, uri.GetANFCapacityPool(resourceID), uri.GetANFVolume(resourceID), uri.GetANFBackup(resourceID), nil)
+		if err != nil {
+			return "", err
+		}
+		if resp.Properties != nil {
+			state = resp.Properties.ProvisioningState
+		}
+	case uri.IsANFVolume(resourceID):
+		resp, err := c.volumes.Get(ctx, resourceGroupName, accountName, uri.GetANFCapacityPool(resourceID), uri.GetANFVolume(resourceID), nil)
+		if err != nil {
+			return "", err
+		}
+		if resp.Properties != nil {
+			state = resp.Properties.ProvisioningState
+		}
+	case uri.IsANFCapacityPool(resourceID):
+		resp, err := c.pools.Get(ctx, resourceGroupName, accountName, uri.GetANFCapacityPool(resourceID), nil)
+		if err != nil {
+			return "", err
+		}
+		if resp.Properties != nil {
+			state = resp.Properties.ProvisioningState
+		}
+	case uri.IsANFSnapshotPolicy(resourceID):
+		resp, err := c.snapshotPolicies.Get(ctx, resourceGroupName, accountName, uri.GetANFSnapshotPolicy(resourceID), nil)
+		if err != nil {
+			return "", err
+		}
+		if resp.Properties != nil {
+			state = resp.Properties.ProvisioningState
+		}
+	case uri.IsANFBackupPolicy(resourceID):
+		resp, err := c.backupPolicies.Get(ctx, resourceGroupName, accountName, uri.GetANFBackupPolicy(resourceID), nil)
+		if err != nil {
+			return "", err
+		}
+		if resp.Properties != nil {
+			state = resp.Properties.ProvisioningState
+		}
+	case uri.IsANFAccount(resourceID):
+		resp, err := c.accounts.Get(ctx, resourceGroupName, accountName, nil)
+		if err != nil {
+			return "", err
+		}
+		if resp.Properties != nil {
+			state = resp.Properties.ProvisioningState
+		}
+	default:
+		return "", invalidArgument("%v is not an Azure NetApp Files resource ID", resourceID)
+	}
+
+	if state == nil {
+		return "", nil
+	}
+	return *state, nil
+}
+
+// getReplicationStatus reads the replication status of a volume
+func (c *Client) getReplicationStatus(ctx context.Context, resourceID string) (*armnetapp.ReplicationStatus, error) {
+	if !uri.IsANFVolume(resourceID) {
+		return nil, invalidArgument("%v is not a volume resource ID", resourceID)
+	}
+
+	resp, err := c.volumes.ReplicationStatus(
+		ctx,
+		uri.GetResourceGroup(resourceID),
+		uri.GetANFAccount(resourceID),
+		uri.GetANFCapacityPool(resourceID),
+		uri.GetANFVolume(resourceID),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp.ReplicationStatus, nil
+}
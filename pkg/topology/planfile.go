@@ -0,0 +1,125 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// Saved plans: a reviewed plan is written to a file together with the
+// topology it was computed from, so that applying it later runs exactly
+// what was reviewed, or nothing when the live resources changed since.
+
+package topology
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+)
+
+// planFileVersion is increased whenever the plan file format changes incompatibly
+const planFileVersion = 1
+
+// SavedPlan object definition, a plan together with the topology it was computed from
+type SavedPlan struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	Topology  *Topology `json:"topology"`
+	Plan      *Plan     `json:"plan"`
+}
+
+// Save writes the plan and its topology to a file readable by the current user only
+func (p *Plan) Save(path string, topology *Topology) error {
+	content, err := json.MarshalIndent(SavedPlan{
+		Version:   planFileVersion,
+		CreatedAt: time.Now().UTC(),
+		Topology:  topology,
+		Plan:      p,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot serialize plan: %v", err)
+	}
+
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		return fmt.Errorf("cannot write plan file: %v", err)
+	}
+
+	return nil
+}
+
+// LoadPlan reads a plan saved with Plan.Save
+func LoadPlan(path string) (*SavedPlan, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read plan file: %v", err)
+	}
+
+	saved := &SavedPlan{}
+	if err := json.Unmarshal(content, saved); err != nil {
+		return nil, fmt.Errorf("cannot parse plan file %v: %v", path, err)
+	}
+	if saved.Version != planFileVersion {
+		return nil, fmt.Errorf("plan file %v has version %v, this go-anf only supports version %v", path, saved.Version, planFileVersion)
+	}
+	if saved.Topology == nil || saved.Plan == nil {
+		return nil, fmt.Errorf("plan file %v is incomplete", path)
+	}
+
+	return saved, nil
+}
+
+// Verify plans the saved topology again and fails when the result differs from the saved plan,
+// which means the live resources changed after the plan was reviewed
+func (s *SavedPlan) Verify(ctx context.Context, client *sdkutils.Client) error {
+	live, err := NewPlan(ctx, client, s.Topology, s.Plan.Prune)
+	if err != nil {
+		return err
+	}
+
+	differences := diffPlans(s.Plan, live)
+	if len(differences) > 0 {
+		return fmt.Errorf("plan created %v is stale, the live resources changed since: %v; run plan again", s.CreatedAt.Local().Format(time.RFC3339), strings.Join(differences, "; "))
+	}
+
+	return nil
+}
+
+// diffPlans describes the actions that differ between two plans
+func diffPlans(saved, live *Plan) []string {
+	key := func(action *Action) string {
+		return fmt.Sprintf("%v %v %v", action.Operation, action.Kind, action.Address())
+	}
+	describe := func(action *Action) string {
+		changes := make([]string, 0, len(action.Changes))
+		for _, change := range action.Changes {
+			changes = append(changes, fmt.Sprintf("%v=%q->%q", change.Field, change.Current, change.Desired))
+		}
+		return fmt.Sprintf("%v %v", action.ResourceID, strings.Join(changes, ","))
+	}
+
+	liveActions := map[string]*Action{}
+	for _, action := range live.Actions {
+		liveActions[key(action)] = action
+	}
+
+	differences := []string{}
+	for _, action := range saved.Actions {
+		liveAction, found := liveActions[key(action)]
+		delete(liveActions, key(action))
+		if !found {
+			differences = append(differences, fmt.Sprintf("%v is no longer needed", key(action)))
+		} else if describe(action) != describe(liveAction) {
+			differences = append(differences, fmt.Sprintf("%v now has different changes", key(action)))
+		}
+	}
+	for _, action := range live.Actions {
+		if _, found := liveActions[key(action)]; found {
+			differences = append(differences, fmt.Sprintf("%v is now needed", key(action)))
+		}
+	}
+
+	return differences
+}
@@ -0,0 +1,954 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// Reconciliation of a topology: the difference between the desired and
+// the current state is computed as a plan of create, update and delete
+// actions, which is then applied in dependency order.
+
+package topology
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/netapp/armnetapp"
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+	"github.com/patrikcze/go-anf/pkg/uri"
+	"github.com/patrikcze/go-anf/pkg/utils"
+)
+
+// Plan operations
+const (
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
+
+// Resource kinds, listed in the order they are created
+const (
+	KindAccount        = "account"
+	KindSnapshotPolicy = "snapshotPolicy"
+	KindPool           = "pool"
+	KindVolume         = "volume"
+)
+
+var operationVerbs = map[string]string{
+	OperationCreate: "Creating",
+	OperationUpdate: "Updating",
+	OperationDelete: "Deleting",
+}
+
+var kindOrder = map[string]int{
+	KindAccount:        0,
+	KindSnapshotPolicy: 1,
+	KindPool:           2,
+	KindVolume:         3,
+}
+
+// Change object definition, a single field that differs between the current and the desired state
+type Change struct {
+	Field   string `json:"field" yaml:"field"`
+	Current string `json:"current,omitempty" yaml:"current,omitempty"`
+	Desired string `json:"desired,omitempty" yaml:"desired,omitempty"`
+}
+
+// Action object definition, a single operation on a resource, ResourceID is set for existing resources
+type Action struct {
+	Operation  string    `json:"operation" yaml:"operation"`
+	Kind       string    `json:"kind" yaml:"kind"`
+	Account    string    `json:"account" yaml:"account"`
+	Pool       string    `json:"pool,omitempty" yaml:"pool,omitempty"`
+	Name       string    `json:"name" yaml:"name"`
+	ResourceID string    `json:"resourceId,omitempty" yaml:"resourceId,omitempty"`
+	Changes    []*Change `json:"changes,omitempty" yaml:"changes,omitempty"`
+}
+
+// Plan object definition, the actions needed to reconcile a topology in the order they are applied
+type Plan struct {
+	ResourceGroup string    `json:"resourceGroup" yaml:"resourceGroup"`
+	Prune         bool      `json:"prune" yaml:"prune"`
+	Actions       []*Action `json:"actions" yaml:"actions"`
+}
+
+// field is a named value compared between the current and the desired state
+type field struct {
+	name  string
+	value string
+}
+
+// planner collects the actions and the conflicts found while comparing a topology with Azure
+type planner struct {
+	ctx       context.Context
+	client    *sdkutils.Client
+	topology  *Topology
+	plan      *Plan
+	conflicts []string
+}
+
+// Address returns a readable path of the resource, e.g. account/pool/volume
+func (a *Action) Address() string {
+	switch a.Kind {
+	case KindAccount:
+		return a.Account
+	case KindSnapshotPolicy:
+		return fmt.Sprintf("%v/snapshotPolicies/%v", a.Account, a.Name)
+	case KindPool:
+		return fmt.Sprintf("%v/%v", a.Account, a.Name)
+	}
+	return fmt.Sprintf("%v/%v/%v", a.Account, a.Pool, a.Name)
+}
+
+// changed reports whether the action changes a field
+func (a *Action) changed(name string) bool {
+	for _, change := range a.Changes {
+		if change.Field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of actions of an operation
+func (p *Plan) Count(operation string) int {
+	count := 0
+	for _, action := range p.Actions {
+		if action.Operation == operation {
+			count++
+		}
+	}
+	return count
+}
+
+// NewPlan compares the topology with the resources in its resource group and returns the actions
+// reconciling them, with prune the resources missing from the topology are deleted
+func NewPlan(ctx context.Context, client *sdkutils.Client, topology *Topology, prune bool) (*Plan, error) {
+	if err := topology.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := &planner{
+		ctx:      ctx,
+		client:   client,
+		topology: topology,
+		plan: &Plan{
+			ResourceGroup: topology.ResourceGroup,
+			Prune:         prune,
+			Actions:       []*Action{},
+		},
+	}
+
+	accounts, err := client.ListANFAccounts(ctx, topology.ResourceGroup)
+	if err != nil {
+		return nil, err
+	}
+	current := map[string]*armnetapp.Account{}
+	for _, account := range accounts {
+		current[uri.GetANFAccount(*account.ID)] = account
+	}
+
+	desired := map[string]bool{}
+	for _, account := range topology.Accounts {
+		desired[account.Name] = true
+		if err := p.account(account, current[account.Name]); err != nil {
+			return nil, err
+		}
+	}
+
+	if prune {
+		for _, name := range sortedKeys(current) {
+			if !desired[name] {
+				if err := p.pruneAccount(current[name]); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if len(p.conflicts) > 0 {
+		return nil, fmt.Errorf("cannot reconcile topology: %v", strings.Join(p.conflicts, "; "))
+	}
+
+	// Creates and updates go parents first, deletes follow children first
+	sort.SliceStable(p.plan.Actions, func(i, j int) bool {
+		return actionOrder(p.plan.Actions[i]) < actionOrder(p.plan.Actions[j])
+	})
+
+	return p.plan, nil
+}
+
+func actionOrder(action *Action) int {
+	if action.Operation == OperationDelete {
+		return len(kindOrder) + len(kindOrder) - kindOrder[action.Kind]
+	}
+	return kindOrder[action.Kind]
+}
+
+// add compares the fields of a resource and records a create or an update action,
+// a difference in an immutable field is recorded as a conflict instead
+func (p *planner) add(action *Action, exists bool, current, desired []field, immutable ...string) {
+	currentValues := map[string]string{}
+	for _, f := range current {
+		currentValues[f.name] = f.value
+	}
+
+	for _, f := range desired {
+		if currentValues[f.name] == f.value {
+			continue
+		}
+		if exists && utils.Contains(immutable, f.name) {
+			p.conflicts = append(p.conflicts, fmt.Sprintf("%v %v: %v cannot be changed from %v to %v, recreate the resource", action.Kind, action.Address(), f.name, currentValues[f.name], f.value))
+			continue
+		}
+		action.Changes = append(action.Changes, &Change{Field: f.name, Current: currentValues[f.name], Desired: f.value})
+	}
+
+	action.Operation = OperationCreate
+	if exists {
+		action.Operation = OperationUpdate
+		if len(action.Changes) == 0 {
+			return
+		}
+	}
+
+	p.plan.Actions = append(p.plan.Actions, action)
+}
+
+func (p *planner) delete(kind, resourceID string) {
+	action := &Action{
+		Operation:  OperationDelete,
+		Kind:       kind,
+		Account:    uri.GetANFAccount(resourceID),
+		ResourceID: resourceID,
+	}
+
+	switch kind {
+	case KindAccount:
+		action.Name = action.Account
+	case KindSnapshotPolicy:
+		action.Name = uri.GetANFSnapshotPolicy(resourceID)
+	case KindPool:
+		action.Name = uri.GetANFCapacityPool(resourceID)
+	case KindVolume:
+		action.Pool = uri.GetANFCapacityPool(resourceID)
+		action.Name = uri.GetANFVolume(resourceID)
+	}
+
+	p.plan.Actions = append(p.plan.Actions, action)
+}
+
+func (p *planner) account(account *Account, current *armnetapp.Account) error {
+	resourceGroupName := p.topology.ResourceGroup
+	action := &Action{Kind: KindAccount, Account: account.Name, Name: account.Name}
+
+	desired := []field{{"location", normalizeLocation(to.Ptr(p.topology.AccountLocation(account)))}}
+	if account.Tags != nil {
+		desired = append(desired, field{"tags", formatTags(account.Tags)})
+	}
+
+	if current == nil {
+		p.add(action, false, nil, desired)
+		for _, policy := range account.SnapshotPolicies {
+			p.snapshotPolicy(account, policy, nil)
+		}
+		for _, pool := range account.Pools {
+			p.pool(account, pool, nil)
+		}
+		return nil
+	}
+
+	action.ResourceID = *current.ID
+	p.add(action, true, []field{
+		{"location", normalizeLocation(current.Location)},
+		{"tags", formatCurrentTags(current.Tags)},
+	}, desired, "location")
+
+	policies, err := p.client.ListANFSnapshotPolicies(p.ctx, resourceGroupName, account.Name)
+	if err != nil {
+		return err
+	}
+	currentPolicies := map[string]*armnetapp.SnapshotPolicy{}
+	for _, policy := range policies {
+		currentPolicies[uri.GetANFSnapshotPolicy(*policy.ID)] = policy
+	}
+	desiredPolicies := map[string]bool{}
+	for _, policy := range account.SnapshotPolicies {
+		desiredPolicies[policy.Name] = true
+		p.snapshotPolicy(account, policy, currentPolicies[policy.Name])
+	}
+
+	pools, err := p.client.ListANFCapacityPools(p.ctx, resourceGroupName, account.Name)
+	if err != nil {
+		return err
+	}
+	currentPools := map[string]*armnetapp.CapacityPool{}
+	for _, pool := range pools {
+		currentPools[uri.GetANFCapacityPool(*pool.ID)] = pool
+	}
+	desiredPools := map[string]bool{}
+	for _, pool := range account.Pools {
+		desiredPools[pool.Name] = true
+		if err := p.pool(account, pool, currentPools[pool.Name]); err != nil {
+			return err
+		}
+	}
+
+	if p.plan.Prune {
+		for _, name := range sortedKeys(currentPools) {
+			if !desiredPools[name] {
+				if err := p.prunePool(currentPools[name]); err != nil {
+					return err
+				}
+			}
+		}
+		for _, name := range sortedKeys(currentPolicies) {
+			if !desiredPolicies[name] {
+				p.delete(KindSnapshotPolicy, *currentPolicies[name].ID)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *planner) snapshotPolicy(account *Account, policy *SnapshotPolicy, current *armnetapp.SnapshotPolicy) {
+	action := &Action{Kind: KindSnapshotPolicy, Account: account.Name, Name: policy.Name}
+
+	// Validate already checked the schedules can be converted
+	schedules, _ := policy.Schedules()
+	properties, _ := sdkutils.NewANFSnapshotPolicyProperties(schedules, policy.IsEnabled())
+	desired := snapshotPolicyFields(properties)
+	if policy.Tags != nil {
+		desired = append(desired, field{"tags", formatTags(policy.Tags)})
+	}
+
+	if current == nil {
+		p.add(action, false, nil, desired)
+		return
+	}
+
+	action.ResourceID = *current.ID
+	p.add(action, true, append(snapshotPolicyFields(current.Properties), field{"tags", formatCurrentTags(current.Tags)}), desired)
+}
+
+func (p *planner) pool(account *Account, pool *Pool, current *armnetapp.CapacityPool) error {
+	action := &Action{Kind: KindPool, Account: account.Name, Name: pool.Name}
+
+	serviceLevel, _ := sdkutils.ValidateANFServiceLevel(pool.ServiceLevel)
+	sizeBytes, _ := utils.ParseSize(pool.Size)
+	desired := []field{
+		{"serviceLevel", string(serviceLevel)},
+		{"size", utils.FormatSize(sizeBytes)},
+	}
+	if pool.QosType != "" {
+		desired = append(desired, field{"qosType", qosType(pool.QosType)})
+	}
+	if pool.Tags != nil {
+		desired = append(desired, field{"tags", formatTags(pool.Tags)})
+	}
+
+	if current == nil {
+		p.add(action, false, nil, desired)
+		for _, volume := range pool.Volumes {
+			p.volume(account, pool, volume, nil)
+		}
+		return nil
+	}
+
+	action.ResourceID = *current.ID
+	currentFields := []field{{"tags", formatCurrentTags(current.Tags)}}
+	if properties := current.Properties; properties != nil {
+		if properties.ServiceLevel != nil {
+			currentFields = append(currentFields, field{"serviceLevel", string(*properties.ServiceLevel)})
+		}
+		if properties.Size != nil {
+			currentFields = append(currentFields, field{"size", utils.FormatSize(*properties.Size)})
+		}
+		if properties.QosType != nil {
+			currentFields = append(currentFields, field{"qosType", string(*properties.QosType)})
+		}
+	}
+	p.add(action, true, currentFields, desired, "serviceLevel")
+
+	volumes, err := p.client.ListANFVolumes(p.ctx, p.topology.ResourceGroup, account.Name, pool.Name)
+	if err != nil {
+		return err
+	}
+	currentVolumes := map[string]*armnetapp.Volume{}
+	for _, volume := range volumes {
+		currentVolumes[uri.GetANFVolume(*volume.ID)] = volume
+	}
+	desiredVolumes := map[string]bool{}
+	for _, volume := range pool.Volumes {
+		desiredVolumes[volume.Name] = true
+		p.volume(account, pool, volume, currentVolumes[volume.Name])
+	}
+
+	if p.plan.Prune {
+		for _, name := range sortedKeys(currentVolumes) {
+			if !desiredVolumes[name] {
+				p.delete(KindVolume, *currentVolumes[name].ID)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *planner) volume(account *Account, pool *Pool, volume *Volume, current *armnetapp.Volume) {
+	action := &Action{Kind: KindVolume, Account: account.Name, Pool: pool.Name, Name: volume.Name}
+
+	quotaBytes, _ := utils.ParseSize(volume.Quota)
+	desired := []field{
+		{"protocolTypes", strings.Join(volume.ProtocolTypes(), ",")},
+		{"subnetId", strings.ToLower(volume.SubnetID)},
+		{"usageThreshold", utils.FormatSize(quotaBytes)},
+	}
+	if volume.SnapshotPolicy != "" {
+		desired = append(desired, field{"snapshotPolicy", volume.SnapshotPolicy})
+	}
+	if len(volume.ExportRules) > 0 {
+		desired = append(desired, field{"exportPolicy", formatExportRules(volume.exportPolicyRules())})
+	}
+	desired = append(desired, volumeOptionalFields(volume.ThroughputMibps, volume.SecurityStyle, volume.NetworkFeatures, volume.KerberosEnabled, volume.LdapEnabled, volume.SnapshotDirectoryVisible, volume.UnixPermissions)...)
+	if volume.Tags != nil {
+		desired = append(desired, field{"tags", formatTags(volume.Tags)})
+	}
+
+	if current == nil {
+		p.add(action, false, nil, desired)
+		return
+	}
+
+	action.ResourceID = *current.ID
+	currentFields := []field{{"tags", formatCurrentTags(current.Tags)}}
+	if properties := current.Properties; properties != nil {
+		protocolTypes := []string{}
+		for _, protocolType := range properties.ProtocolTypes {
+			protocolTypes = append(protocolTypes, *protocolType)
+		}
+		currentFields = append(currentFields,
+			field{"protocolTypes", strings.Join(protocolTypes, ",")},
+			field{"subnetId", strings.ToLower(valueOf(properties.SubnetID))},
+		)
+		if properties.UsageThreshold != nil {
+			currentFields = append(currentFields, field{"usageThreshold", utils.FormatSize(*properties.UsageThreshold)})
+		}
+		if dataProtection := properties.DataProtection; dataProtection != nil && dataProtection.Snapshot != nil {
+			currentFields = append(currentFields, field{"snapshotPolicy", uri.GetANFSnapshotPolicy(valueOf(dataProtection.Snapshot.SnapshotPolicyID))})
+		}
+		if properties.ExportPolicy != nil {
+			currentFields = append(currentFields, field{"exportPolicy", formatExportRules(properties.ExportPolicy.Rules)})
+		}
+		securityStyle := ""
+		if properties.SecurityStyle != nil {
+			securityStyle = string(*properties.SecurityStyle)
+		}
+		networkFeatures := ""
+		if properties.NetworkFeatures != nil {
+			networkFeatures = string(*properties.NetworkFeatures)
+		}
+		currentFields = append(currentFields, volumeOptionalFields(properties.ThroughputMibps, securityStyle, networkFeatures, properties.KerberosEnabled, properties.LdapEnabled, properties.SnapshotDirectoryVisible, valueOf(properties.UnixPermissions))...)
+	}
+	p.add(action, true, currentFields, desired, "protocolTypes", "subnetId", "securityStyle", "networkFeatures", "kerberosEnabled", "ldapEnabled", "snapshotDirectoryVisible")
+}
+
+// volumeOptionalFields describes the optional properties of a volume, the unset ones are left out
+func volumeOptionalFields(throughputMibps *float32, securityStyle, networkFeatures string, kerberosEnabled, ldapEnabled, snapshotDirectoryVisible *bool, unixPermissions string) []field {
+	fields := []field{}
+	if throughputMibps != nil {
+		fields = append(fields, field{"throughputMibps", fmt.Sprintf("%v", *throughputMibps)})
+	}
+	if securityStyle != "" {
+		fields = append(fields, field{"securityStyle", strings.ToLower(securityStyle)})
+	}
+	if networkFeatures != "" {
+		fields = append(fields, field{"networkFeatures", strings.ToLower(networkFeatures)})
+	}
+	if kerberosEnabled != nil {
+		fields = append(fields, field{"kerberosEnabled", fmt.Sprintf("%v", *kerberosEnabled)})
+	}
+	if ldapEnabled != nil {
+		fields = append(fields, field{"ldapEnabled", fmt.Sprintf("%v", *ldapEnabled)})
+	}
+	if snapshotDirectoryVisible != nil {
+		fields = append(fields, field{"snapshotDirectoryVisible", fmt.Sprintf("%v", *snapshotDirectoryVisible)})
+	}
+	if unixPermissions != "" {
+		fields = append(fields, field{"unixPermissions", unixPermissions})
+	}
+	return fields
+}
+
+// pruneAccount deletes an account missing from the topology together with everything it contains
+func (p *planner) pruneAccount(account *armnetapp.Account) error {
+	accountName := uri.GetANFAccount(*account.ID)
+
+	pools, err := p.client.ListANFCapacityPools(p.ctx, p.topology.ResourceGroup, accountName)
+	if err != nil {
+		return err
+	}
+	for _, pool := range pools {
+		if err := p.prunePool(pool); err != nil {
+			return err
+		}
+	}
+
+	policies, err := p.client.ListANFSnapshotPolicies(p.ctx, p.topology.ResourceGroup, accountName)
+	if err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		p.delete(KindSnapshotPolicy, *policy.ID)
+	}
+
+	p.delete(KindAccount, *account.ID)
+	return nil
+}
+
+// prunePool deletes a pool missing from the topology together with its volumes
+func (p *planner) prunePool(pool *armnetapp.CapacityPool) error {
+	volumes, err := p.client.ListANFVolumes(p.ctx, p.topology.ResourceGroup, uri.GetANFAccount(*pool.ID), uri.GetANFCapacityPool(*pool.ID))
+	if err != nil {
+		return err
+	}
+	for _, volume := range volumes {
+		p.delete(KindVolume, *volume.ID)
+	}
+
+	p.delete(KindPool, *pool.ID)
+	return nil
+}
+
+// Apply runs the actions of the plan in order, stopping at the first failure
+func (p *Plan) Apply(ctx context.Context, client *sdkutils.Client, topology *Topology) error {
+	for _, action := range p.Actions {
+		utils.ConsoleOutput(fmt.Sprintf("%v %v %v...", operationVerbs[action.Operation], action.Kind, action.Address()))
+
+		var err error
+		if action.Operation == OperationDelete {
+			err = deleteResource(ctx, client, action)
+		} else {
+			err = applyAction(ctx, client, topology, action)
+		}
+		if err != nil {
+			return fmt.Errorf("cannot %v %v %v: %w", action.Operation, action.Kind, action.Address(), err)
+		}
+	}
+
+	return nil
+}
+
+// applyAction creates or updates a resource from its definition in the topology
+func applyAction(ctx context.Context, client *sdkutils.Client, topology *Topology, action *Action) error {
+	resourceGroupName := topology.ResourceGroup
+	account := topology.findAccount(action.Account)
+	if account == nil {
+		return fmt.Errorf("account %v is not defined in the topology", action.Account)
+	}
+	location := topology.AccountLocation(account)
+	create := action.Operation == OperationCreate
+
+	var resourceID string
+	switch action.Kind {
+	case KindAccount:
+		if create {
+			created, err := client.CreateANFAccount(ctx, location, resourceGroupName, account.Name, nil, tagsPtr(account.Tags))
+			if err != nil {
+				return err
+			}
+			resourceID = *created.ID
+		} else if _, err := client.UpdateANFAccount(ctx, location, resourceGroupName, account.Name, nil, tagsPtr(account.Tags)); err != nil {
+			return err
+		}
+
+	case KindSnapshotPolicy:
+		policy := account.findSnapshotPolicy(action.Name)
+		if policy == nil {
+			return fmt.Errorf("snapshot policy is not defined in the topology")
+		}
+		schedules, err := policy.Schedules()
+		if err != nil {
+			return err
+		}
+		// The schedules removed from the topology are only removed from the policy when the update sends them empty
+		newProperties := sdkutils.NewANFSnapshotPolicyPatchProperties
+		if create {
+			newProperties = sdkutils.NewANFSnapshotPolicyProperties
+		}
+		properties, err := newProperties(schedules, policy.IsEnabled())
+		if err != nil {
+			return err
+		}
+
+		if create {
+			created, err := client.CreateANFSnapshotPolicy(ctx, resourceGroupName, account.Name, policy.Name, armnetapp.SnapshotPolicy{
+				Location:   to.Ptr(location),
+				Tags:       tagsPtr(policy.Tags),
+				Properties: properties,
+			})
+			if err != nil {
+				return err
+			}
+			resourceID = *created.ID
+		} else if _, err := client.UpdateANFSnapshotPolicy(ctx, resourceGroupName, account.Name, policy.Name, armnetapp.SnapshotPolicyPatch{
+			Location:   to.Ptr(location),
+			Tags:       tagsPtr(policy.Tags),
+			Properties: properties,
+		}); err != nil {
+			return err
+		}
+
+	case KindPool:
+		pool := account.findPool(action.Name)
+		if pool == nil {
+			return fmt.Errorf("pool is not defined in the topology")
+		}
+		sizeBytes, err := utils.ParseSize(pool.Size)
+		if err != nil {
+			return err
+		}
+
+		if create {
+			created, err := client.CreateANFCapacityPool(ctx, location, resourceGroupName, account.Name, pool.Name, pool.ServiceLevel, sizeBytes, tagsPtr(pool.Tags))
+			if err != nil {
+				return err
+			}
+			resourceID = *created.ID
+		}
+
+		// Pools are created with automatic QoS, a manual QoS type is patched afterwards
+		patch := armnetapp.PoolPatchProperties{}
+		if !create && action.changed("size") {
+			patch.Size = to.Ptr(sizeBytes)
+		}
+		if action.changed("qosType") && !(create && strings.EqualFold(pool.QosType, "auto")) {
+			patch.QosType = to.Ptr(armnetapp.QosType(qosType(pool.QosType)))
+		}
+		if patch.Size != nil || patch.QosType != nil || (!create && action.changed("tags")) {
+			if resourceID != "" {
+				if err := client.WaitForANFResource(ctx, resourceID, nil); err != nil {
+					return err
+				}
+			}
+			if _, err := client.UpdateANFCapacityPool(ctx, location, resourceGroupName, account.Name, pool.Name, patch, tagsPtr(pool.Tags)); err != nil {
+				return err
+			}
+		}
+
+	case KindVolume:
+		pool := account.findPool(action.Pool)
+		var volume *Volume
+		if pool != nil {
+			volume = pool.findVolume(action.Name)
+		}
+		if volume == nil {
+			return fmt.Errorf("volume is not defined in the topology")
+		}
+		quotaBytes, err := utils.ParseSize(volume.Quota)
+		if err != nil {
+			return err
+		}
+
+		snapshotPolicyID := ""
+		if volume.SnapshotPolicy != "" && (create || action.changed("snapshotPolicy")) {
+			policy, err := client.GetANFSnapshotPolicy(ctx, resourceGroupName, account.Name, volume.SnapshotPolicy)
+			if err != nil {
+				return err
+			}
+			snapshotPolicyID = *policy.ID
+		}
+
+		if create {
+			spec, err := topology.VolumeSpec(account, pool, volume)
+			if err != nil {
+				return err
+			}
+			if snapshotPolicyID != "" {
+				spec.DataProtection = &armnetapp.VolumePropertiesDataProtection{
+					Snapshot: &armnetapp.VolumeSnapshotProperties{
+						SnapshotPolicyID: to.Ptr(snapshotPolicyID),
+					},
+				}
+			}
+
+			created, err := client.CreateANFVolume(ctx, spec)
+			if err != nil {
+				return err
+			}
+			resourceID = *created.ID
+		}
+
+		// Volumes are created with the rules of the topology, or a default rule when it has none
+		patch := armnetapp.VolumePatchProperties{}
+		if !create && action.changed("exportPolicy") {
+			patch.ExportPolicy = &armnetapp.VolumePatchPropertiesExportPolicy{
+				Rules: volume.exportPolicyRules(),
+			}
+		}
+		if !create && action.changed("usageThreshold") {
+			patch.UsageThreshold = to.Ptr(quotaBytes)
+		}
+		if !create && action.changed("throughputMibps") {
+			patch.ThroughputMibps = volume.ThroughputMibps
+		}
+		if !create && action.changed("unixPermissions") {
+			patch.UnixPermissions = to.Ptr(volume.UnixPermissions)
+		}
+		if !create && snapshotPolicyID != "" {
+			patch.DataProtection = &armnetapp.VolumePatchPropertiesDataProtection{
+				Snapshot: &armnetapp.VolumeSnapshotProperties{
+					SnapshotPolicyID: to.Ptr(snapshotPolicyID),
+				},
+			}
+		}
+		if patch.ExportPolicy != nil || patch.UsageThreshold != nil || patch.ThroughputMibps != nil || patch.UnixPermissions != nil || patch.DataProtection != nil || (!create && action.changed("tags")) {
+			if resourceID != "" {
+				if err := client.WaitForANFResource(ctx, resourceID, nil); err != nil {
+					return err
+				}
+			}
+			if _, err := client.UpdateANFVolume(ctx, location, resourceGroupName, account.Name, pool.Name, volume.Name, patch, tagsPtr(volume.Tags)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if resourceID != "" {
+		return client.WaitForANFResource(ctx, resourceID, nil)
+	}
+	return nil
+}
+
+// deleteResource deletes a resource by its ID and waits until ARM no longer reports it
+func deleteResource(ctx context.Context, client *sdkutils.Client, action *Action) error {
+	resourceID := action.ResourceID
+	resourceGroupName := uri.GetResourceGroup(resourceID)
+
+	var err error
+	switch action.Kind {
+	case KindVolume:
+		err = client.DeleteANFVolume(ctx, resourceGroupName, action.Account, action.Pool, action.Name)
+	case KindPool:
+		err = client.DeleteANFCapacityPool(ctx, resourceGroupName, action.Account, action.Name)
+	case KindSnapshotPolicy:
+		err = client.DeleteANFSnapshotPolicy(ctx, resourceGroupName, action.Account, action.Name)
+	case KindAccount:
+		err = client.DeleteANFAccount(ctx, resourceGroupName, action.Account)
+	}
+	if err != nil {
+		return err
+	}
+
+	return client.WaitForNoANFResource(ctx, resourceID, nil)
+}
+
+func (t *Topology) findAccount(name string) *Account {
+	for _, account := range t.Accounts {
+		if account.Name == name {
+			return account
+		}
+	}
+	return nil
+}
+
+func (a *Account) findSnapshotPolicy(name string) *SnapshotPolicy {
+	for _, policy := range a.SnapshotPolicies {
+		if policy.Name == name {
+			return policy
+		}
+	}
+	return nil
+}
+
+func (a *Account) findPool(name string) *Pool {
+	for _, pool := range a.Pools {
+		if pool.Name == name {
+			return pool
+		}
+	}
+	return nil
+}
+
+func (p *Pool) findVolume(name string) *Volume {
+	for _, volume := range p.Volumes {
+		if volume.Name == name {
+			return volume
+		}
+	}
+	return nil
+}
+
+// exportRules converts the export rules of the volume, indexed from 1 in the order they are listed,
+// the rules of a dual protocol volume also allow CIFS
+func (v *Volume) exportRules() []sdkutils.ExportRule {
+	cifs := utils.Contains(v.ProtocolTypes(), "CIFS")
+	rules := make([]sdkutils.ExportRule, 0, len(v.ExportRules))
+	for i, rule := range v.ExportRules {
+		rules = append(rules, sdkutils.ExportRule{
+			RuleIndex:      int32(i + 1),
+			AllowedClients: rule.AllowedClients,
+			NFSv3:          rule.NFSv3,
+			NFSv41:         rule.NFSv41,
+			CIFS:           cifs,
+			ReadOnly:       rule.ReadOnly,
+			RootAccess:     rule.RootAccess,
+			ChownMode:      rule.ChownMode,
+			Security:       rule.Security,
+		})
+	}
+	return rules
+}
+
+// exportPolicyRules converts the export rules of the volume into SDK rules
+func (v *Volume) exportPolicyRules() []*armnetapp.ExportPolicyRule {
+	rules := make([]*armnetapp.ExportPolicyRule, 0, len(v.ExportRules))
+	for _, rule := range v.exportRules() {
+		rules = append(rules, rule.ExportPolicyRule())
+	}
+	return rules
+}
+
+// formatExportRules renders export rules in index order, e.g. 1:10.0.0.0/24 nfsv3 rw root
+func formatExportRules(rules []*armnetapp.ExportPolicyRule) string {
+	sorted := append([]*armnetapp.ExportPolicyRule{}, rules...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return int32Value(sorted[i].RuleIndex) < int32Value(sorted[j].RuleIndex)
+	})
+
+	formatted := make([]string, 0, len(sorted))
+	for _, rule := range sorted {
+		exportRule := sdkutils.NewExportRule(rule)
+		parts := []string{fmt.Sprintf("%v:%v", int32Value(rule.RuleIndex), valueOf(rule.AllowedClients))}
+		if boolValue(rule.Nfsv3) {
+			parts = append(parts, "nfsv3")
+		}
+		if boolValue(rule.Nfsv41) {
+			parts = append(parts, "nfsv4.1")
+		}
+		switch {
+		case exportRule.NoAccess:
+			parts = append(parts, "none")
+		case !exportRule.ReadOnly:
+			parts = append(parts, "rw")
+		default:
+			parts = append(parts, "ro")
+		}
+		// The service grants root access unless it is explicitly disabled
+		if rule.HasRootAccess == nil || *rule.HasRootAccess {
+			parts = append(parts, "root")
+		}
+		// Restricted chown and sys security are the defaults of the service, only other values are shown
+		if rule.ChownMode != nil && *rule.ChownMode == armnetapp.ChownModeUnrestricted {
+			parts = append(parts, "chown")
+		}
+		if security := exportRule.SecurityFlavors(); len(security) != 1 || security[0] != sdkutils.SecuritySys {
+			parts = append(parts, "sec="+strings.Join(security, ":"))
+		}
+		formatted = append(formatted, strings.Join(parts, " "))
+	}
+	return strings.Join(formatted, "; ")
+}
+
+// snapshotPolicyFields describes the state and the schedules of a snapshot policy
+func snapshotPolicyFields(properties *armnetapp.SnapshotPolicyProperties) []field {
+	fields := []field{
+		{"enabled", "false"},
+		{"hourlySchedule", ""},
+		{"dailySchedule", ""},
+		{"weeklySchedule", ""},
+		{"monthlySchedule", ""},
+	}
+	if properties == nil {
+		return fields
+	}
+
+	fields[0].value = fmt.Sprintf("%v", boolValue(properties.Enabled))
+	if hourly := properties.HourlySchedule; hourly != nil && int32Value(hourly.SnapshotsToKeep) > 0 {
+		fields[1].value = fmt.Sprintf("keep %v at minute %v", int32Value(hourly.SnapshotsToKeep), int32Value(hourly.Minute))
+	}
+	if daily := properties.DailySchedule; daily != nil && int32Value(daily.SnapshotsToKeep) > 0 {
+		fields[2].value = fmt.Sprintf("keep %v at %02d:%02d", int32Value(daily.SnapshotsToKeep), int32Value(daily.Hour), int32Value(daily.Minute))
+	}
+	if weekly := properties.WeeklySchedule; weekly != nil && int32Value(weekly.SnapshotsToKeep) > 0 {
+		fields[3].value = fmt.Sprintf("keep %v on %v at %02d:%02d", int32Value(weekly.SnapshotsToKeep), valueOf(weekly.Day), int32Value(weekly.Hour), int32Value(weekly.Minute))
+	}
+	if monthly := properties.MonthlySchedule; monthly != nil && int32Value(monthly.SnapshotsToKeep) > 0 {
+		fields[4].value = fmt.Sprintf("keep %v on days %v at %02d:%02d", int32Value(monthly.SnapshotsToKeep), valueOf(monthly.DaysOfMonth), int32Value(monthly.Hour), int32Value(monthly.Minute))
+	}
+
+	return fields
+}
+
+// qosType converts a case insensitive QoS type into its SDK value
+func qosType(value string) string {
+	if strings.EqualFold(value, "manual") {
+		return string(armnetapp.QosTypeManual)
+	}
+	return string(armnetapp.QosTypeAuto)
+}
+
+// normalizeLocation makes locations comparable, ARM returns them lower case without spaces
+func normalizeLocation(location *string) string {
+	return strings.ReplaceAll(strings.ToLower(valueOf(location)), " ", "")
+}
+
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", key, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func formatCurrentTags(tags map[string]*string) string {
+	values := make(map[string]string, len(tags))
+	for key, value := range tags {
+		values[key] = valueOf(value)
+	}
+	return formatTags(values)
+}
+
+// tagsPtr converts topology tags into the map expected by the SDK, nil leaves tags untouched on update
+func tagsPtr(tags map[string]string) map[string]*string {
+	if tags == nil {
+		return nil
+	}
+	result := make(map[string]*string, len(tags))
+	for key, value := range tags {
+		result[key] = to.Ptr(value)
+	}
+	return result
+}
+
+func sortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func valueOf(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func int32Value(value *int32) int32 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+func boolValue(value *bool) bool {
+	return value != nil && *value
+}
@@ -0,0 +1,155 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+package topology
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/patrikcze/go-anf/pkg/emulator"
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+)
+
+const testSubnetID = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/anf"
+
+// newTestClient returns a client talking to a new emulator
+func newTestClient(t *testing.T) *sdkutils.Client {
+	t.Helper()
+
+	server := emulator.NewServer(emulator.Options{})
+	t.Cleanup(server.Close)
+
+	client, err := sdkutils.NewClient(emulator.Credential{}, emulator.SubscriptionID, server.ClientOptions())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	client.SetWaitOptions(&sdkutils.WaitOptions{Interval: 10 * time.Millisecond, MaxInterval: 100 * time.Millisecond, Timeout: 30 * time.Second})
+	return client
+}
+
+// testTopology returns an account with a snapshot policy and a pool holding a volume that uses the policy
+func testTopology() *Topology {
+	return &Topology{
+		ResourceGroup: "rg",
+		Location:      "westus",
+		Accounts: []*Account{{
+			Name: "account1",
+			Tags: map[string]string{"env": "test"},
+			SnapshotPolicies: []*SnapshotPolicy{{
+				Name:   "policy1",
+				Hourly: &Schedule{Keep: 6, Minute: 15},
+				Daily:  &Schedule{Keep: 7, At: "02:30"},
+			}},
+			Pools: []*Pool{{
+				Name:         "pool1",
+				ServiceLevel: "Premium",
+				Size:         "4TiB",
+				Volumes: []*Volume{{
+					Name:           "volume1",
+					Quota:          "100GiB",
+					SubnetID:       testSubnetID,
+					SnapshotPolicy: "policy1",
+				}},
+			}},
+		}},
+	}
+}
+
+// apply plans the topology, checks the number of actions of each operation and applies the plan
+func apply(t *testing.T, client *sdkutils.Client, topology *Topology, prune bool, creates, updates, deletes int) {
+	t.Helper()
+	ctx := context.Background()
+
+	plan, err := NewPlan(ctx, client, topology, prune)
+	if err != nil {
+		t.Fatalf("NewPlan() error = %v", err)
+	}
+	if plan.Count(OperationCreate) != creates || plan.Count(OperationUpdate) != updates || plan.Count(OperationDelete) != deletes {
+		t.Fatalf("plan has %v creates, %v updates and %v deletes, want %v, %v and %v: %v",
+			plan.Count(OperationCreate), plan.Count(OperationUpdate), plan.Count(OperationDelete), creates, updates, deletes, planActions(plan))
+	}
+	if err := plan.Apply(ctx, client, topology); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+}
+
+// converged checks that a new plan of the topology has no action left
+func converged(t *testing.T, client *sdkutils.Client, topology *Topology, prune bool) {
+	t.Helper()
+
+	plan, err := NewPlan(context.Background(), client, topology, prune)
+	if err != nil {
+		t.Fatalf("NewPlan() error = %v", err)
+	}
+	if len(plan.Actions) > 0 {
+		t.Fatalf("plan after apply has %v actions, want none: %v", len(plan.Actions), planActions(plan))
+	}
+}
+
+// planActions describes the actions of a plan and their changes for failure messages
+func planActions(plan *Plan) []string {
+	actions := []string{}
+	for _, action := range plan.Actions {
+		description := action.Operation + " " + action.Kind + " " + action.Address()
+		for _, change := range action.Changes {
+			description += " " + change.Field + ": " + change.Current + " -> " + change.Desired
+		}
+		actions = append(actions, description)
+	}
+	return actions
+}
+
+func TestPlanApplyConverges(t *testing.T) {
+	client := newTestClient(t)
+	topology := testTopology()
+
+	apply(t, client, topology, false, 4, 0, 0)
+	converged(t, client, topology, false)
+
+	// Updates: a larger pool and volume, the daily schedule removed and new account tags
+	account := topology.Accounts[0]
+	account.Tags = map[string]string{"env": "prod"}
+	account.SnapshotPolicies[0].Daily = nil
+	account.Pools[0].Size = "5TiB"
+	account.Pools[0].Volumes[0].Quota = "200GiB"
+
+	apply(t, client, topology, false, 0, 4, 0)
+	converged(t, client, topology, false)
+
+	policy, err := client.GetANFSnapshotPolicy(context.Background(), "rg", "account1", "policy1")
+	if err != nil {
+		t.Fatalf("GetANFSnapshotPolicy() error = %v", err)
+	}
+	if daily := policy.Properties.DailySchedule; daily != nil && daily.SnapshotsToKeep != nil && *daily.SnapshotsToKeep != 0 {
+		t.Errorf("daily schedule keeps %v snapshots after its removal, want 0", *daily.SnapshotsToKeep)
+	}
+}
+
+func TestPlanApplyPrunes(t *testing.T) {
+	client := newTestClient(t)
+	topology := testTopology()
+
+	apply(t, client, topology, false, 4, 0, 0)
+
+	// Without prune the resources missing from the topology are kept
+	account := topology.Accounts[0]
+	account.Pools[0].Volumes = nil
+	converged(t, client, topology, false)
+
+	apply(t, client, topology, true, 0, 0, 1)
+	converged(t, client, topology, true)
+
+	// The policy can only go once no volume uses it, and the pool once it is empty
+	account.SnapshotPolicies = nil
+	account.Pools = nil
+	apply(t, client, topology, true, 0, 0, 2)
+	converged(t, client, topology, true)
+
+	topology.Accounts = nil
+	apply(t, client, topology, true, 0, 0, 1)
+	converged(t, client, topology, true)
+}
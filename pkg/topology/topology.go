@@ -0,0 +1,312 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// This package describes the desired state of the Azure NetApp Files
+// resources of a resource group, accounts with their snapshot policies,
+// capacity pools and volumes, and reconciles it against Azure.
+
+package topology
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/patrikcze/go-anf/pkg/models"
+	"github.com/patrikcze/go-anf/pkg/sdkutils"
+	"github.com/patrikcze/go-anf/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	poolSizeIncrement int64 = 1 << 40
+)
+
+// Topology object definition, the desired state of a resource group
+type Topology struct {
+	ResourceGroup string     `json:"resourceGroup" yaml:"resourceGroup"`
+	Location      string     `json:"location,omitempty" yaml:"location,omitempty"`
+	Accounts      []*Account `json:"accounts" yaml:"accounts"`
+}
+
+// Account object definition, Location defaults to the topology location
+type Account struct {
+	Name             string            `json:"name" yaml:"name"`
+	Location         string            `json:"location,omitempty" yaml:"location,omitempty"`
+	Tags             map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	SnapshotPolicies []*SnapshotPolicy `json:"snapshotPolicies,omitempty" yaml:"snapshotPolicies,omitempty"`
+	Pools            []*Pool           `json:"pools,omitempty" yaml:"pools,omitempty"`
+}
+
+// SnapshotPolicy object definition, Enabled defaults to true
+type SnapshotPolicy struct {
+	Name    string            `json:"name" yaml:"name"`
+	Enabled *bool             `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Hourly  *Schedule         `json:"hourly,omitempty" yaml:"hourly,omitempty"`
+	Daily   *Schedule         `json:"daily,omitempty" yaml:"daily,omitempty"`
+	Weekly  *Schedule         `json:"weekly,omitempty" yaml:"weekly,omitempty"`
+	Monthly *Schedule         `json:"monthly,omitempty" yaml:"monthly,omitempty"`
+	Tags    map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// Schedule object definition, hourly schedules use Minute, the others use At (HH:MM, UTC),
+// weekly schedules take week day names in Days and monthly schedules take DaysOfMonth
+type Schedule struct {
+	Keep        int32    `json:"keep" yaml:"keep"`
+	Minute      int32    `json:"minute,omitempty" yaml:"minute,omitempty"`
+	At          string   `json:"at,omitempty" yaml:"at,omitempty"`
+	Days        []string `json:"days,omitempty" yaml:"days,omitempty"`
+	DaysOfMonth []int32  `json:"daysOfMonth,omitempty" yaml:"daysOfMonth,omitempty"`
+}
+
+// Pool object definition, Size uses the units accepted by utils.ParseSize
+type Pool struct {
+	Name         string            `json:"name" yaml:"name"`
+	ServiceLevel string            `json:"serviceLevel" yaml:"serviceLevel"`
+	Size         string            `json:"size" yaml:"size"`
+	QosType      string            `json:"qosType,omitempty" yaml:"qosType,omitempty"`
+	Tags         map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Volumes      []*Volume         `json:"volumes,omitempty" yaml:"volumes,omitempty"`
+}
+
+// Volume object definition, SnapshotPolicy names a policy of the same account
+// and Protocols defaults to NFSv3
+type Volume struct {
+	Name                     string            `json:"name" yaml:"name"`
+	Quota                    string            `json:"quota" yaml:"quota"`
+	SubnetID                 string            `json:"subnetId" yaml:"subnetId"`
+	Protocols                []string          `json:"protocols,omitempty" yaml:"protocols,omitempty"`
+	SnapshotPolicy           string            `json:"snapshotPolicy,omitempty" yaml:"snapshotPolicy,omitempty"`
+	ExportRules              []*ExportRule     `json:"exportRules,omitempty" yaml:"exportRules,omitempty"`
+	ThroughputMibps          *float32          `json:"throughputMibps,omitempty" yaml:"throughputMibps,omitempty"`
+	SecurityStyle            string            `json:"securityStyle,omitempty" yaml:"securityStyle,omitempty"`
+	NetworkFeatures          string            `json:"networkFeatures,omitempty" yaml:"networkFeatures,omitempty"`
+	KerberosEnabled          *bool             `json:"kerberosEnabled,omitempty" yaml:"kerberosEnabled,omitempty"`
+	LdapEnabled              *bool             `json:"ldapEnabled,omitempty" yaml:"ldapEnabled,omitempty"`
+	SnapshotDirectoryVisible *bool             `json:"snapshotDirectoryVisible,omitempty" yaml:"snapshotDirectoryVisible,omitempty"`
+	UnixPermissions          string            `json:"unixPermissions,omitempty" yaml:"unixPermissions,omitempty"`
+	Tags                     map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// ExportRule object definition, rules are indexed in the order they are listed
+type ExportRule struct {
+	AllowedClients string   `json:"allowedClients" yaml:"allowedClients"`
+	NFSv3          bool     `json:"nfsv3,omitempty" yaml:"nfsv3,omitempty"`
+	NFSv41         bool     `json:"nfsv41,omitempty" yaml:"nfsv41,omitempty"`
+	ReadOnly       bool     `json:"readOnly,omitempty" yaml:"readOnly,omitempty"`
+	RootAccess     *bool    `json:"rootAccess,omitempty" yaml:"rootAccess,omitempty"`
+	ChownMode      string   `json:"chownMode,omitempty" yaml:"chownMode,omitempty"`
+	Security       []string `json:"security,omitempty" yaml:"security,omitempty"`
+}
+
+// Load reads a topology from a YAML or JSON file, unknown fields are rejected
+func Load(path string) (*Topology, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read topology file: %v", err)
+	}
+
+	topology := &Topology{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(topology); err != nil {
+		return nil, fmt.Errorf("cannot parse topology file %v: %v", path, err)
+	}
+
+	return topology, nil
+}
+
+// Validate checks the whole topology and reports all problems found
+func (t *Topology) Validate() error {
+	problems := []string{}
+	problem := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+
+	if t.ResourceGroup == "" {
+		problem("resourceGroup is required")
+	}
+
+	accountNames := map[string]bool{}
+	for _, account := range t.Accounts {
+		if account.Name == "" {
+			problem("every account needs a name")
+			continue
+		}
+		if accountNames[account.Name] {
+			problem("account %v is defined more than once", account.Name)
+		}
+		accountNames[account.Name] = true
+
+		if t.AccountLocation(account) == "" {
+			problem("account %v: location is required, set it on the account or the topology", account.Name)
+		}
+
+		policyNames := map[string]bool{}
+		for _, policy := range account.SnapshotPolicies {
+			if policy.Name == "" {
+				problem("account %v: every snapshot policy needs a name", account.Name)
+				continue
+			}
+			if policyNames[policy.Name] {
+				problem("account %v: snapshot policy %v is defined more than once", account.Name, policy.Name)
+			}
+			policyNames[policy.Name] = true
+
+			schedules, err := policy.Schedules()
+			if err == nil {
+				_, err = sdkutils.NewANFSnapshotPolicyProperties(schedules, policy.IsEnabled())
+			}
+			if err != nil {
+				problem("account %v: snapshot policy %v: %v", account.Name, policy.Name, err)
+			}
+		}
+
+		poolNames := map[string]bool{}
+		volumeNames := map[string]bool{}
+		for _, pool := range account.Pools {
+			if pool.Name == "" {
+				problem("account %v: every pool needs a name", account.Name)
+				continue
+			}
+			if poolNames[pool.Name] {
+				problem("account %v: pool %v is defined more than once", account.Name, pool.Name)
+			}
+			poolNames[pool.Name] = true
+
+			if _, err := sdkutils.ValidateANFServiceLevel(pool.ServiceLevel); err != nil {
+				problem("pool %v/%v: %v", account.Name, pool.Name, err)
+			}
+			if sizeBytes, err := utils.ParseSize(pool.Size); err != nil {
+				problem("pool %v/%v: %v", account.Name, pool.Name, err)
+			} else if sizeBytes == 0 || sizeBytes%poolSizeIncrement != 0 {
+				problem("pool %v/%v: size %v is not a whole number of TiB", account.Name, pool.Name, pool.Size)
+			}
+			if pool.QosType != "" && !strings.EqualFold(pool.QosType, "auto") && !strings.EqualFold(pool.QosType, "manual") {
+				problem("pool %v/%v: qosType must be Auto or Manual", account.Name, pool.Name)
+			}
+
+			for _, volume := range pool.Volumes {
+				if volume.Name == "" {
+					problem("pool %v/%v: every volume needs a name", account.Name, pool.Name)
+					continue
+				}
+				// Volume names and their file paths are unique within an account
+				if volumeNames[volume.Name] {
+					problem("account %v: volume %v is defined more than once", account.Name, volume.Name)
+				}
+				volumeNames[volume.Name] = true
+
+				address := fmt.Sprintf("volume %v/%v/%v", account.Name, pool.Name, volume.Name)
+				if spec, err := t.VolumeSpec(account, pool, volume); err != nil {
+					problem("%v: %v", address, err)
+				} else {
+					for _, specProblem := range spec.Problems() {
+						problem("%v: %v", address, specProblem)
+					}
+				}
+				if volume.SnapshotPolicy != "" && !policyNames[volume.SnapshotPolicy] {
+					problem("%v: snapshot policy %v is not defined in account %v", address, volume.SnapshotPolicy, account.Name)
+				}
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid topology: %v", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// AccountLocation returns the location of an account, falling back to the topology location
+func (t *Topology) AccountLocation(account *Account) string {
+	if account.Location != "" {
+		return account.Location
+	}
+	return t.Location
+}
+
+// VolumeSpec returns the spec a volume of the topology is created from, its snapshot policy is
+// resolved to a resource ID when the volume is created
+func (t *Topology) VolumeSpec(account *Account, pool *Pool, volume *Volume) (sdkutils.VolumeSpec, error) {
+	quotaBytes, err := utils.ParseSize(volume.Quota)
+	if err != nil {
+		return sdkutils.VolumeSpec{}, err
+	}
+
+	return sdkutils.VolumeSpec{
+		Location:                 t.AccountLocation(account),
+		ResourceGroup:            t.ResourceGroup,
+		Account:                  account.Name,
+		Pool:                     pool.Name,
+		Name:                     volume.Name,
+		ServiceLevel:             pool.ServiceLevel,
+		SubnetID:                 volume.SubnetID,
+		ProtocolTypes:            volume.ProtocolTypes(),
+		UsageThreshold:           quotaBytes,
+		Tags:                     tagsPtr(volume.Tags),
+		ThroughputMibps:          volume.ThroughputMibps,
+		SecurityStyle:            volume.SecurityStyle,
+		NetworkFeatures:          volume.NetworkFeatures,
+		KerberosEnabled:          volume.KerberosEnabled,
+		LdapEnabled:              volume.LdapEnabled,
+		SnapshotDirectoryVisible: volume.SnapshotDirectoryVisible,
+		UnixPermissions:          volume.UnixPermissions,
+		ExportRules:              volume.exportRules(),
+	}, nil
+}
+
+// IsEnabled reports whether the snapshot policy is enabled, policies are enabled unless stated otherwise
+func (p *SnapshotPolicy) IsEnabled() bool {
+	return p.Enabled == nil || *p.Enabled
+}
+
+// Schedules converts the policy schedules into the form used by sdkutils
+func (p *SnapshotPolicy) Schedules() (models.SnapshotPolicySchedules, error) {
+	schedules := models.SnapshotPolicySchedules{}
+
+	if p.Hourly != nil {
+		schedules.HourlySnapshotsToKeep = p.Hourly.Keep
+		schedules.HourlyMinute = p.Hourly.Minute
+	}
+	if p.Daily != nil {
+		hour, minute, err := utils.ParseClock(p.Daily.At)
+		if err != nil {
+			return schedules, fmt.Errorf("daily schedule: %v", err)
+		}
+		schedules.DailySnapshotsToKeep = p.Daily.Keep
+		schedules.DailyHour, schedules.DailyMinute = hour, minute
+	}
+	if p.Weekly != nil {
+		hour, minute, err := utils.ParseClock(p.Weekly.At)
+		if err != nil {
+			return schedules, fmt.Errorf("weekly schedule: %v", err)
+		}
+		schedules.WeeklySnapshotsToKeep = p.Weekly.Keep
+		schedules.WeeklyDays = p.Weekly.Days
+		schedules.WeeklyHour, schedules.WeeklyMinute = hour, minute
+	}
+	if p.Monthly != nil {
+		hour, minute, err := utils.ParseClock(p.Monthly.At)
+		if err != nil {
+			return schedules, fmt.Errorf("monthly schedule: %v", err)
+		}
+		schedules.MonthlySnapshotsToKeep = p.Monthly.Keep
+		schedules.MonthlyDays = p.Monthly.DaysOfMonth
+		schedules.MonthlyHour, schedules.MonthlyMinute = hour, minute
+	}
+
+	return schedules, nil
+}
+
+// ProtocolTypes returns the protocol types of the volume, NFSv3 when none are listed
+func (v *Volume) ProtocolTypes() []string {
+	if len(v.Protocols) == 0 {
+		return []string{"NFSv3"}
+	}
+	return v.Protocols
+}
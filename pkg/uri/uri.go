@@ -177,6 +177,55 @@ func GetANFSnapshotPolicy(resourceURI string) string {
 	return snapshotPolicyName
 }
 
+// GetANFBackupPolicy gets backup policy name from resource id/uri
+func GetANFBackupPolicy(resourceURI string) string {
+
+	if len(strings.TrimSpace(resourceURI)) == 0 {
+		return ""
+	}
+
+	backupPolicyName := GetResourceValue(resourceURI, "/backupPolicies")
+	if backupPolicyName == "" {
+		return ""
+	}
+
+	return backupPolicyName
+}
+
+// GetANFBackupVault gets backup vault name from resource id/uri, API versions
+// up to 2022-05-01 name the vaults of an account /vaults instead of /backupVaults
+func GetANFBackupVault(resourceURI string) string {
+
+	if len(strings.TrimSpace(resourceURI)) == 0 {
+		return ""
+	}
+
+	backupVaultName := GetResourceValue(resourceURI, "/backupVaults")
+	if backupVaultName == "" {
+		backupVaultName = GetResourceValue(resourceURI, "/vaults")
+	}
+	if backupVaultName == "" {
+		return ""
+	}
+
+	return backupVaultName
+}
+
+// GetANFBackup gets backup name from resource id/uri
+func GetANFBackup(resourceURI string) string {
+
+	if len(strings.TrimSpace(resourceURI)) == 0 {
+		return ""
+	}
+
+	backupName := GetResourceValue(resourceURI, "/backups")
+	if backupName == "" {
+		return ""
+	}
+
+	return backupName
+}
+
 // IsANFResource checks if resource is an ANF related resource
 func IsANFResource(resourceURI string) bool {
 
@@ -205,6 +254,7 @@ func IsANFVolume(resourceURI string) bool {
 	}
 
 	return !IsANFSnapshot(resourceURI) &&
+		!IsANFBackup(resourceURI) &&
 		strings.LastIndex(resourceURI, "/volumes/") > -1
 }
 
@@ -217,6 +267,7 @@ func IsANFCapacityPool(resourceURI string) bool {
 
 	return !IsANFSnapshot(resourceURI) &&
 		!IsANFVolume(resourceURI) &&
+		!IsANFBackup(resourceURI) &&
 		strings.LastIndex(resourceURI, "/capacityPools/") > -1
 }
 
@@ -233,6 +284,43 @@ func IsANFSnapshotPolicy(resourceURI string) bool {
 		strings.LastIndex(resourceURI, "/snapshotPolicies/") > -1
 }
 
+// IsANFBackup checks resource is a backup of a volume
+func IsANFBackup(resourceURI string) bool {
+
+	if len(strings.TrimSpace(resourceURI)) == 0 || !IsANFResource(resourceURI) {
+		return false
+	}
+
+	// Backups are below their volume, a /backups/ segment before it is the name of a pool
+	volumeIndex := strings.LastIndex(resourceURI, "/volumes/")
+	return volumeIndex > -1 &&
+		strings.LastIndex(resourceURI, "/backups/") > volumeIndex
+}
+
+// IsANFBackupPolicy checks resource is a backup policy
+func IsANFBackupPolicy(resourceURI string) bool {
+
+	if len(strings.TrimSpace(resourceURI)) == 0 || !IsANFResource(resourceURI) {
+		return false
+	}
+
+	return !IsANFCapacityPool(resourceURI) &&
+		!IsANFVolume(resourceURI) &&
+		!IsANFBackup(resourceURI) &&
+		strings.LastIndex(resourceURI, "/backupPolicies/") > -1
+}
+
+// IsANFBackupVault checks resource is a backup vault
+func IsANFBackupVault(resourceURI string) bool {
+
+	if len(strings.TrimSpace(resourceURI)) == 0 || !IsANFResource(resourceURI) {
+		return false
+	}
+
+	return !IsANFCapacityPool(resourceURI) &&
+		(strings.LastIndex(resourceURI, "/backupVaults/") > -1 || strings.LastIndex(resourceURI, "/vaults/") > -1)
+}
+
 // IsANFAccount checks resource is an account
 func IsANFAccount(resourceURI string) bool {
 
@@ -243,8 +331,10 @@ func IsANFAccount(resourceURI string) bool {
 	return !IsANFSnapshot(resourceURI) &&
 		!IsANFVolume(resourceURI) &&
 		!IsANFCapacityPool(resourceURI) &&
+		!IsANFBackup(resourceURI) &&
 		!IsANFSnapshotPolicy(resourceURI) &&
 		strings.LastIndex(resourceURI, "/snapshotPolicies/") == -1 &&
 		strings.LastIndex(resourceURI, "/backupPolicies/") == -1 &&
+		!IsANFBackupVault(resourceURI) &&
 		strings.LastIndex(resourceURI, "/netAppAccounts/") > -1
 }
@@ -0,0 +1,102 @@
+// Copyright (c) Microsoft and contributors.  All rights reserved.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+package uri
+
+import (
+	"testing"
+)
+
+const (
+	testAccountID = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.NetApp/netAppAccounts/account1"
+	testVolumeID  = testAccountID + "/capacityPools/pool1/volumes/volume1"
+)
+
+// kinds are the Is functions of the resource types, in the order their results are reported
+var kinds = []struct {
+	name string
+	is   func(string) bool
+}{
+	{"account", IsANFAccount},
+	{"capacityPool", IsANFCapacityPool},
+	{"volume", IsANFVolume},
+	{"snapshot", IsANFSnapshot},
+	{"snapshotPolicy", IsANFSnapshotPolicy},
+	{"backup", IsANFBackup},
+	{"backupPolicy", IsANFBackupPolicy},
+	{"backupVault", IsANFBackupVault},
+}
+
+func TestResourceKinds(t *testing.T) {
+	tests := []struct {
+		id   string
+		kind string
+	}{
+		{testAccountID, "account"},
+		{testAccountID + "/capacityPools/pool1", "capacityPool"},
+		{testVolumeID, "volume"},
+		{testVolumeID + "/snapshots/snapshot1", "snapshot"},
+		{testAccountID + "/snapshotPolicies/policy1", "snapshotPolicy"},
+		{testVolumeID + "/backups/backup1", "backup"},
+		{testAccountID + "/backupPolicies/policy1", "backupPolicy"},
+		{testAccountID + "/vaults/vault1", "backupVault"},
+		{testAccountID + "/backupVaults/vault1", "backupVault"},
+		// Resources named like a resource type are still recognized by their path
+		{testAccountID + "/capacityPools/backups/volumes/vaults", "volume"},
+		{testAccountID + "/backupPolicies/vaults", "backupPolicy"},
+		{"/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/backups", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		matched := []string{}
+		for _, kind := range kinds {
+			if kind.is(tt.id) {
+				matched = append(matched, kind.name)
+			}
+		}
+
+		switch {
+		case tt.kind == "" && len(matched) > 0:
+			t.Errorf("%q is recognized as %v, want no ANF resource", tt.id, matched)
+		case tt.kind != "" && (len(matched) != 1 || matched[0] != tt.kind):
+			t.Errorf("%q is recognized as %v, want %v only", tt.id, matched, tt.kind)
+		}
+	}
+}
+
+func TestBackupNames(t *testing.T) {
+	tests := []struct {
+		id          string
+		get         func(string) string
+		getName     string
+		want        string
+		wantAccount string
+		wantVolume  string
+	}{
+		{testVolumeID + "/backups/backup1", GetANFBackup, "GetANFBackup", "backup1", "account1", "volume1"},
+		{testAccountID + "/backupPolicies/policy1", GetANFBackupPolicy, "GetANFBackupPolicy", "policy1", "account1", ""},
+		{testAccountID + "/vaults/vault1", GetANFBackupVault, "GetANFBackupVault", "vault1", "account1", ""},
+		{testAccountID + "/backupVaults/vault1", GetANFBackupVault, "GetANFBackupVault", "vault1", "account1", ""},
+		// Backups and backup policies are not mistaken for one another
+		{testAccountID + "/backupPolicies/policy1", GetANFBackup, "GetANFBackup", "", "account1", ""},
+		{testVolumeID + "/backups/backup1", GetANFBackupPolicy, "GetANFBackupPolicy", "", "account1", "volume1"},
+		{testVolumeID + "/backups/backup1", GetANFBackupVault, "GetANFBackupVault", "", "account1", "volume1"},
+		{"", GetANFBackup, "GetANFBackup", "", "", ""},
+		{"", GetANFBackupVault, "GetANFBackupVault", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.get(tt.id); got != tt.want {
+			t.Errorf("%v(%q) = %q, want %q", tt.getName, tt.id, got, tt.want)
+		}
+		if got := GetANFAccount(tt.id); got != tt.wantAccount {
+			t.Errorf("GetANFAccount(%q) = %q, want %q", tt.id, got, tt.wantAccount)
+		}
+		if got := GetANFVolume(tt.id); got != tt.wantVolume {
+			t.Errorf("GetANFVolume(%q) = %q, want %q", tt.id, got, tt.wantVolume)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -50,6 +51,71 @@ func GetTiBInBytes(size uint32) uint64 {
 	return uint64(size * 1024 * 1024 * 1024 * 1024)
 }
 
+// ParseSize converts a human readable size such as 4TiB, 100GiB or 500G into bytes,
+// a value without unit is taken as bytes
+func ParseSize(size string) (int64, error) {
+	value := strings.TrimSpace(size)
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+		{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+		{"B", 1},
+	}
+
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(strings.ToUpper(value), strings.ToUpper(unit.suffix)) {
+			value = strings.TrimSpace(value[:len(value)-len(unit.suffix)])
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q, expected a whole number with an optional unit such as 4TiB or 100GiB", size)
+	}
+
+	return number * multiplier, nil
+}
+
+// ParseClock parses a HH:MM time of day into hour and minute
+func ParseClock(value string) (int32, int32, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, 0, fmt.Errorf("invalid time %q, hour must be between 00 and 23", value)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, 0, fmt.Errorf("invalid time %q, minute must be between 00 and 59", value)
+	}
+
+	return int32(hour), int32(minute), nil
+}
+
+// FormatSize converts a value in bytes into the largest binary unit that represents it
+func FormatSize(sizeBytes int64) string {
+	units := []string{"TiB", "GiB", "MiB", "KiB"}
+	for i, unit := range units {
+		multiplier := int64(1) << (10 * (len(units) - i))
+		if sizeBytes >= multiplier {
+			if sizeBytes%multiplier == 0 {
+				return fmt.Sprintf("%d%v", sizeBytes/multiplier, unit)
+			}
+			return fmt.Sprintf("%.2f%v", float64(sizeBytes)/float64(multiplier), unit)
+		}
+	}
+	return fmt.Sprintf("%dB", sizeBytes)
+}
+
 // ReadAzureBasicInfoJSON reads the Azure Authentication json file json file and unmarshals it.
 func ReadAzureBasicInfoJSON(path string) (*models.AzureBasicInfo, error) {
 	infoJSON, err := ioutil.ReadFile(path)